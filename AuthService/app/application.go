@@ -1,18 +1,31 @@
 package app
 
 import (
+	"AuthService/assertion"
+	"AuthService/cache"
 	dbConfig "AuthService/config/db"
 	config "AuthService/config/env"
 	"AuthService/controllers"
 	repo "AuthService/db/repositories"
+	"AuthService/health"
+	"AuthService/metrics"
+	"AuthService/policy"
+	"AuthService/ratelimit"
 	"AuthService/router"
 	"AuthService/services"
+	"AuthService/tracing"
+	"AuthService/utils"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 )
 
@@ -75,6 +88,49 @@ func (a *Application) Run() error {
 	// Load the env variables
 	config.Load()
 
+	// Spans are exported from here on, whatever is buffered is flushed when the server stops
+	shutdown_tracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "tracing_error",
+		}).Error("Tracing Error")
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown_tracing(ctx)
+	}()
+
+	// Probes are answered from the start and report not ready until everything below is set up,
+	// every other request is turned away until the router takes over
+	health_checker := health.NewChecker(2 * time.Second)
+	health_controller := controllers.NewHealthController(health_checker)
+	health_router := router.NewHealthRouter(*health_controller)
+
+	startup_router := chi.NewRouter()
+	startup_router.Get("/healthz", health_controller.Live)
+	startup_router.Get("/readyz", health_controller.Ready)
+	startup_router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteErrorResponse(w, http.StatusServiceUnavailable, "Server is starting", nil)
+	})
+	var server_handler atomic.Pointer[chi.Mux]
+	server_handler.Store(startup_router)
+
+	server := &http.Server{
+		Addr: a.Config.Addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			server_handler.Load().ServeHTTP(w, r)
+		}),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	serve_errors := make(chan error, 1)
+	go func() {
+		serve_errors <- server.ListenAndServe()
+	}()
+
 	// Initialize DB
 	dbConn, err := dbConfig.SetupDBConn()
 	if err != nil {
@@ -84,31 +140,193 @@ func (a *Application) Run() error {
 		}).Error("DB Error")
 		os.Exit(1)
 	}
+	metrics.RegisterDB(dbConn, "auth")
+	health_checker.Add("mysql", true, dbConn.PingContext)
+
+	// Bring the schema up to this build before anything queries it, the probes report the migrating phase meanwhile
+	if config.GetBool("DB_MIGRATE_ON_START", true) {
+		health_checker.SetPhase(health.PhaseMigrating)
+		if err := dbConfig.Migrate(context.Background(), dbConn); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"err":  err,
+				"type": "db_migration_error",
+			}).Error("DB Migration Error")
+			os.Exit(1)
+		}
+		health_checker.SetPhase(health.PhaseStarting)
+	}
+	// Applied here or by the goose CLI, a schema behind this build keeps the instance out of rotation
+	migration_check, err := dbConfig.MigrationCheck(dbConn)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "db_migration_error",
+		}).Error("DB Migration Error")
+		os.Exit(1)
+	}
+	health_checker.Add("migrations", true, migration_check)
+
+	// Load the gateway policy, its tests must pass before we serve traffic
+	policyDoc, err := policy.LoadFile(config.GetString("POLICY_FILE", "config/policy/gateway.yaml"))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "policy_error",
+		}).Error("Policy Error")
+		os.Exit(1)
+	}
+	policy_engine, err := policy.NewEngine(*policyDoc)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "policy_error",
+		}).Error("Policy Error")
+		os.Exit(1)
+	}
+
+	// One balanced, health checked pool per upstream, the checks run for the life of the process
+	upstream_pools, err := policy_engine.UpstreamPools()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "policy_error",
+		}).Error("Policy Error")
+		os.Exit(1)
+	}
+	upstream_pools.Start(context.Background())
+	// A missing upstream fails only the routes it serves, not the gateway
+	for _, pool := range upstream_pools.Pools() {
+		health_checker.Add("upstream:"+pool.Name(), false, func(context.Context) error {
+			return pool.Check()
+		})
+	}
+	upstream_controller := controllers.NewUpstreamController(upstream_pools)
+	upstream_router := router.NewUpstreamRouter(*upstream_controller)
+
+	audit_log_repo := repo.NewAuditLogRepository(dbConn)
+	audit_service := services.NewAuditService(audit_log_repo)
+	audit_controller := controllers.NewAuditController(audit_service)
+	audit_router := router.NewAuditRouter(*audit_controller)
 
 	role_permission_repo := repo.NewRolePermissionRepository(dbConn)
 	user_role_repo := repo.NewUserRoleRepository(dbConn)
 
 	role_repo := repo.NewRoleRepository(dbConn)
-	role_service := services.NewRoleService(role_repo, role_permission_repo, user_role_repo)
+	role_service := services.NewRoleService(role_repo, role_permission_repo, user_role_repo, audit_service)
 	role_controller := controllers.NewRoleController(role_service)
 	role_router := router.NewRoleRouter(*role_controller)
 
 	user_repo := repo.NewUserRepository(dbConn)
-	user_service := services.NewUserService(user_repo)
-	user_controller := controllers.NewUserController(user_service, role_service)
+	organization_repo := repo.NewOrganizationRepository(dbConn)
+	session_repo := repo.NewSessionRepository(dbConn)
+	mailer := services.NewMailer()
+	login_attempt_repo := repo.NewLoginAttemptRepository(dbConn)
+	login_history_service := services.NewLoginHistoryService(login_attempt_repo, mailer)
+	user_service := services.NewUserService(user_repo, user_role_repo, organization_repo, session_repo, login_history_service, audit_service)
+	user_import_repo := repo.NewUserImportRepository(dbConn)
+	user_import_service := services.NewUserImportService(user_import_repo, role_repo, mailer, audit_service)
+	preferences_repo := repo.NewPreferencesRepository(dbConn)
+	preferences_service := services.NewPreferencesService(preferences_repo)
+	preferences_controller := controllers.NewPreferencesController(preferences_service)
+	preferences_router := router.NewPreferencesRouter(*preferences_controller)
+	user_controller := controllers.NewUserController(user_service, role_service, user_import_service, login_history_service, preferences_service)
 	user_router := router.NewUserRouter(*user_controller)
 
-	server := &http.Server{
-		Addr:         a.Config.Addr,
-		Handler:      router.SetupRouter(user_router, role_router),
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+	policy_controller := controllers.NewPolicyController(policy_engine, role_service)
+	policy_router := router.NewPolicyRouter(*policy_controller)
+
+	organization_service := services.NewOrganizationService(organization_repo, mailer)
+	organization_controller := controllers.NewOrganizationController(organization_service, user_service)
+	organization_router := router.NewOrganizationRouter(*organization_controller)
+
+	impersonation_repo := repo.NewImpersonationRepository(dbConn)
+	impersonation_service := services.NewImpersonationService(user_service, user_role_repo, impersonation_repo, audit_service)
+	impersonation_controller := controllers.NewImpersonationController(impersonation_service)
+	impersonation_router := router.NewImpersonationRouter(*impersonation_controller)
+
+	magic_link_repo := repo.NewMagicLinkRepository(dbConn)
+	magic_link_service := services.NewMagicLinkService(user_service, user_repo, magic_link_repo, mailer)
+	magic_link_controller := controllers.NewMagicLinkController(magic_link_service, role_service)
+	magic_link_router := router.NewMagicLinkRouter(*magic_link_controller)
+
+	guest_repo := repo.NewGuestRepository(dbConn)
+	guest_service := services.NewGuestService(user_service, guest_repo)
+	guest_controller := controllers.NewGuestController(guest_service, role_service)
+	guest_router := router.NewGuestRouter(*guest_controller)
+
+	suspension_repo := repo.NewSuspensionRepository(dbConn)
+	suspension_service := services.NewSuspensionService(user_repo, user_role_repo, suspension_repo, audit_service)
+	suspension_controller := controllers.NewSuspensionController(suspension_service)
+	suspension_router := router.NewSuspensionRouter(*suspension_controller)
+
+	profile_repo := repo.NewProfileRepository(dbConn)
+	submission_pool, _ := upstream_pools.Get("submission")
+	submission_stats_client := services.NewSubmissionStatsClient(submission_pool)
+	profile_service := services.NewProfileService(profile_repo, submission_stats_client)
+	blob_store, err := services.NewBlobStore(context.Background())
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "blob_error",
+		}).Error("Blob Store Error")
+		os.Exit(1)
+	}
+	avatar_service := services.NewAvatarService(profile_repo, profile_service, blob_store)
+	profile_controller := controllers.NewProfileController(profile_service, avatar_service)
+	profile_router := router.NewProfileRouter(*profile_controller)
+
+	session_service := services.NewSessionService(session_repo)
+	session_controller := controllers.NewSessionController(session_service)
+	session_router := router.NewSessionRouter(*session_controller)
+
+	// Upstreams verify proxied identities with the same secret, each assertion is valid for 30 seconds.
+	// There is no default, a secret anyone could read would let them forge any identity
+	assertion_secret := config.GetString("GATEWAY_ASSERTION_SECRET", "")
+	if assertion_secret == "" {
+		logrus.WithFields(logrus.Fields{
+			"err":  "GATEWAY_ASSERTION_SECRET is not set",
+			"type": "config_error",
+		}).Error("Identity Assertion Error")
+		os.Exit(1)
 	}
+	assertion_signer := assertion.NewSigner([]byte(assertion_secret), 30*time.Second)
+
+	// Rate limit buckets and cached answers live in Redis so every gateway replica shares them
+	redis_client := services.RedisConn()
+	rate_limiter := ratelimit.NewLimiter(redis_client, policy_engine.RateLimits())
+	cache_store := cache.NewStore(redis_client)
+	// Without Redis the limiter fails open and the cache is bypassed, the gateway degrades but still serves
+	health_checker.Add("redis", false, func(ctx context.Context) error {
+		return redis_client.Ping(ctx).Err()
+	})
+	health_checker.Add("evaluated_subscription", false, services.CheckEvaluationWorker)
+
+	server_handler.Store(router.SetupRouter(user_router, role_router, policy_router, organization_router, impersonation_router, session_router, magic_link_router, guest_router, suspension_router, audit_router, profile_router, preferences_router, upstream_router, health_router, policy_engine, upstream_pools, assertion_signer, rate_limiter, cache_store))
+	health_checker.SetPhase(health.PhaseServing)
 
 	logrus.WithFields(logrus.Fields{
 		"msg":  fmt.Sprint("Starting server on port ", a.Config.Addr),
 		"type": "info",
 	}).Info("Server started successfully")
 
-	return server.ListenAndServe()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serve_errors:
+		return err
+	case <-signals:
+	}
+
+	// Load balancers see the instance go unready and stop sending to it before the listener closes
+	health_checker.SetPhase(health.PhaseStopping)
+	drain := time.Duration(config.GetInt("SHUTDOWN_DRAIN_SECONDS", 5)) * time.Second
+	logrus.WithFields(logrus.Fields{
+		"msg":  fmt.Sprint("Draining for ", drain),
+		"type": "info",
+	}).Info("Server shutting down")
+	time.Sleep(drain)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	return server.Shutdown(ctx)
 }
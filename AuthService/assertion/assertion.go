@@ -0,0 +1,212 @@
+// Package assertion signs and verifies the identity assertion the gateway attaches to proxied requests.
+//
+// An assertion is a compact JWS (HS256) in the X-Identity-Assertion header. It is bound to one upstream
+// through its audience and lives for a few seconds, so a captured header is of little use elsewhere.
+// The package depends only on the standard library, so other Go services can import it as is.
+// testdata/vectors.json holds tokens with their expected outcome for verifiers written in other languages,
+// expect is either valid or the check that fails: malformed, algorithm, signature, issuer, audience, expired or not_yet_valid.
+package assertion
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderName = "X-Identity-Assertion"
+	Issuer     = "problem-battles-gateway"
+	// Allowed clock difference between the gateway and an upstream
+	DefaultLeeway = 5 * time.Second
+)
+
+var (
+	ErrMissing   = errors.New("identity assertion missing")
+	ErrMalformed = errors.New("identity assertion malformed")
+	ErrAlgorithm = errors.New("identity assertion algorithm not allowed")
+	ErrSignature = errors.New("identity assertion signature invalid")
+	ErrExpired   = errors.New("identity assertion expired")
+	ErrNotYet    = errors.New("identity assertion issued in the future")
+	ErrIssuer    = errors.New("identity assertion issuer invalid")
+	ErrAudience  = errors.New("identity assertion audience invalid")
+)
+
+// Who the gateway authenticated. During impersonation Subject is the impersonated user.
+type Claims struct {
+	Issuer         string   `json:"iss"`
+	Audience       string   `json:"aud"`
+	Subject        string   `json:"sub"`
+	Email          string   `json:"email,omitempty"`
+	Roles          []string `json:"roles"`
+	Permissions    []string `json:"perms,omitempty"`
+	OrgId          int64    `json:"org_id,omitempty"`
+	OrgRole        string   `json:"org_role,omitempty"`
+	ImpersonatorId int      `json:"imp,omitempty"`
+	RequestId      string   `json:"rid,omitempty"`
+	IssuedAt       int64    `json:"iat"`
+	ExpiresAt      int64    `json:"exp"`
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+}
+
+var encodedHeader = encode(mustMarshal(header{Algorithm: "HS256", Type: "JWT"}))
+
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewSigner(secret []byte, ttl time.Duration) *Signer {
+	return &Signer{
+		secret: secret,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Signs the claims for one upstream, issuer and lifetime are always set here
+func (s *Signer) Sign(claims Claims) (string, error) {
+	now := s.now()
+	claims.Issuer = Issuer
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(s.ttl).Unix()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodedHeader + "." + encode(payload)
+	return signingInput + "." + encode(sign(s.secret, signingInput)), nil
+}
+
+type Verifier struct {
+	audience string
+	secrets  [][]byte
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// Accepts assertions for audience signed with any of the secrets, which allows rotating the secret without downtime
+func NewVerifier(audience string, secrets ...[]byte) *Verifier {
+	return &Verifier{
+		audience: audience,
+		secrets:  secrets,
+		leeway:   DefaultLeeway,
+		now:      time.Now,
+	}
+}
+
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	if token == "" {
+		return nil, ErrMissing
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	headerJson, err := decode(parts[0])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var h header
+	if err := json.Unmarshal(headerJson, &h); err != nil {
+		return nil, ErrMalformed
+	}
+	// Only the algorithm we sign with, never "none" or whatever the token asks for
+	if h.Algorithm != "HS256" {
+		return nil, ErrAlgorithm
+	}
+
+	signature, err := decode(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	signingInput := parts[0] + "." + parts[1]
+	valid := false
+	for _, secret := range v.secrets {
+		if hmac.Equal(signature, sign(secret, signingInput)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrSignature
+	}
+
+	payload, err := decode(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, ErrMalformed
+	}
+
+	now := v.now()
+	if claims.Issuer != Issuer {
+		return nil, ErrIssuer
+	}
+	if claims.Audience != v.audience {
+		return nil, ErrAudience
+	}
+	if now.Add(-v.leeway).Unix() >= claims.ExpiresAt {
+		return nil, ErrExpired
+	}
+	if now.Add(v.leeway).Unix() < claims.IssuedAt {
+		return nil, ErrNotYet
+	}
+	return claims, nil
+}
+
+type contextKey struct{}
+
+// Rejects requests without a valid assertion with 401 and hands the claims to next
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := v.Verify(r.Header.Get(HeaderName))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, claims)))
+	})
+}
+
+// Claims verified by Middleware for this request
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
+
+func sign(secret []byte, signingInput string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(part string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(part)
+}
+
+func mustMarshal(value any) []byte {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	return encoded
+}
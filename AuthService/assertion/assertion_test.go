@@ -0,0 +1,225 @@
+package assertion
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type vectorCase struct {
+	Name     string          `json:"name"`
+	Token    string          `json:"token"`
+	Audience string          `json:"audience"`
+	Secrets  []string        `json:"secrets"`
+	Now      int64           `json:"now"`
+	Expect   string          `json:"expect"`
+	Claims   json.RawMessage `json:"claims"`
+}
+
+type vectorFile struct {
+	Algorithm     string       `json:"algorithm"`
+	LeewaySeconds int          `json:"leeway_seconds"`
+	TtlSeconds    int          `json:"ttl_seconds"`
+	Cases         []vectorCase `json:"cases"`
+}
+
+// The error Verify returns for each expect value of the vectors
+var expectedErrors = map[string]error{
+	"malformed":     ErrMalformed,
+	"algorithm":     ErrAlgorithm,
+	"signature":     ErrSignature,
+	"issuer":        ErrIssuer,
+	"audience":      ErrAudience,
+	"expired":       ErrExpired,
+	"not_yet_valid": ErrNotYet,
+}
+
+func loadVectors(t *testing.T) vectorFile {
+	t.Helper()
+	data, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatalf("reading vectors: %v", err)
+	}
+	var vectors vectorFile
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatalf("parsing vectors: %v", err)
+	}
+	return vectors
+}
+
+func newTestVerifier(audience string, now time.Time, secrets ...string) *Verifier {
+	keys := make([][]byte, len(secrets))
+	for i, secret := range secrets {
+		keys[i] = []byte(secret)
+	}
+	verifier := NewVerifier(audience, keys...)
+	verifier.now = func() time.Time { return now }
+	return verifier
+}
+
+func TestVectors(t *testing.T) {
+	vectors := loadVectors(t)
+	if vectors.Algorithm != "HS256" {
+		t.Fatalf("vectors are for %s, want HS256", vectors.Algorithm)
+	}
+	if time.Duration(vectors.LeewaySeconds)*time.Second != DefaultLeeway {
+		t.Fatalf("vectors assume a leeway of %ds, DefaultLeeway is %s", vectors.LeewaySeconds, DefaultLeeway)
+	}
+
+	// The cases other verifiers must get right, the file has to keep them
+	for _, expect := range []string{"valid", "algorithm", "expired", "audience"} {
+		found := false
+		for _, vector := range vectors.Cases {
+			found = found || vector.Expect == expect
+		}
+		if !found {
+			t.Errorf("no vector expects %s", expect)
+		}
+	}
+
+	for _, vector := range vectors.Cases {
+		t.Run(vector.Name, func(t *testing.T) {
+			verifier := newTestVerifier(vector.Audience, time.Unix(vector.Now, 0), vector.Secrets...)
+			claims, err := verifier.Verify(vector.Token)
+
+			if vector.Expect != "valid" {
+				want, ok := expectedErrors[vector.Expect]
+				if !ok {
+					t.Fatalf("unknown expect %q", vector.Expect)
+				}
+				if !errors.Is(err, want) {
+					t.Fatalf("Verify() error = %v, want %v", err, want)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			var want Claims
+			if err := json.Unmarshal(vector.Claims, &want); err != nil {
+				t.Fatalf("parsing expected claims: %v", err)
+			}
+			if !reflect.DeepEqual(*claims, want) {
+				t.Errorf("Verify() claims = %+v, want %+v", *claims, want)
+			}
+		})
+	}
+}
+
+// A token whose header names another algorithm but is signed with the shared secret
+func tokenWithAlgorithm(t *testing.T, algorithm string, secret []byte, claims Claims) string {
+	t.Helper()
+	signingInput := encode(mustMarshal(header{Algorithm: algorithm, Type: "JWT"})) + "." + encode(mustMarshal(claims))
+	return signingInput + "." + encode(sign(secret, signingInput))
+}
+
+func TestVerifyRejectsOtherAlgorithms(t *testing.T) {
+	now := time.Unix(1760000010, 0)
+	secret := []byte("gateway-test-secret")
+	claims := Claims{Issuer: Issuer, Audience: "submission", Subject: "42", IssuedAt: now.Unix(), ExpiresAt: now.Add(30 * time.Second).Unix()}
+	verifier := newTestVerifier("submission", now, string(secret))
+
+	for _, algorithm := range []string{"none", "None", "hs256", "HS384", "HS512", "RS256", "ES256", ""} {
+		t.Run(algorithm, func(t *testing.T) {
+			if _, err := verifier.Verify(tokenWithAlgorithm(t, algorithm, secret, claims)); !errors.Is(err, ErrAlgorithm) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrAlgorithm)
+			}
+		})
+	}
+
+	// Unsigned, the way "none" tokens are usually sent
+	unsigned := tokenWithAlgorithm(t, "none", secret, claims)
+	unsigned = unsigned[:strings.LastIndex(unsigned, ".")+1]
+	if _, err := verifier.Verify(unsigned); !errors.Is(err, ErrAlgorithm) {
+		t.Errorf("Verify(unsigned) error = %v, want %v", err, ErrAlgorithm)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	issued := time.Unix(1760000000, 0)
+	signer := NewSigner([]byte("gateway-test-secret"), 30*time.Second)
+	signer.now = func() time.Time { return issued }
+
+	token, err := signer.Sign(Claims{Audience: "submission", Subject: "42", Roles: []string{"user"}, Issuer: "someone-else"})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		audience string
+		at       time.Duration
+		want     error
+	}{
+		{name: "fresh", audience: "submission", at: time.Second},
+		{name: "at expiry within leeway", audience: "submission", at: 30*time.Second + DefaultLeeway - time.Second},
+		{name: "expired past leeway", audience: "submission", at: 30*time.Second + DefaultLeeway, want: ErrExpired},
+		{name: "before issue within leeway", audience: "submission", at: -DefaultLeeway},
+		{name: "before issue past leeway", audience: "submission", at: -DefaultLeeway - time.Second, want: ErrNotYet},
+		{name: "other upstream", audience: "problem", at: time.Second, want: ErrAudience},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := newTestVerifier(tt.audience, issued.Add(tt.at), "gateway-test-secret")
+			claims, err := verifier.Verify(token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if claims.Issuer != Issuer || claims.Subject != "42" || claims.ExpiresAt != issued.Add(30*time.Second).Unix() {
+				t.Errorf("Verify() claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	signer := NewSigner([]byte("gateway-test-secret"), 30*time.Second)
+	verifier := NewVerifier("submission", []byte("gateway-test-secret"))
+	handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := FromContext(r.Context())
+		if !ok {
+			t.Error("no claims in the request context")
+			return
+		}
+		w.Write([]byte(claims.Subject))
+	}))
+
+	token, err := signer.Sign(Claims{Audience: "submission", Subject: "42"})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	for _, tt := range []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "valid", token: token, status: http.StatusOK},
+		{name: "missing", status: http.StatusUnauthorized},
+		{name: "tampered", token: token + "x", status: http.StatusUnauthorized},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				request.Header.Set(HeaderName, tt.token)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.status {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && recorder.Body.String() != "42" {
+				t.Errorf("body = %q, want the subject", recorder.Body.String())
+			}
+		})
+	}
+}
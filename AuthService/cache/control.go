@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache-Control directives by lowercase name, a directive without a value maps to ""
+type Directives map[string]string
+
+func ParseCacheControl(header http.Header) Directives {
+	directives := Directives{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			name, argument, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(name)] = strings.Trim(argument, `"`)
+		}
+	}
+	return directives
+}
+
+func (d Directives) Has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// How long a shared cache may keep an answer: s-maxage, then max-age, then fallback.
+// False when the upstream asked not to keep it at all.
+func Lifetime(header http.Header, fallback time.Duration) (time.Duration, bool) {
+	directives := ParseCacheControl(header)
+	if directives.Has("no-store") || directives.Has("no-cache") || directives.Has("private") {
+		return 0, false
+	}
+	if header.Get("Set-Cookie") != "" || header.Get("Vary") == "*" {
+		return 0, false
+	}
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[name]; ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds <= 0 {
+				return 0, false
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return fallback, fallback > 0
+}
+
+// A strong ETag from the body, for answers the upstream sent without one
+func GenerateETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Whether an If-None-Match header lists etag, weak comparison as RFC 9110 asks for GET
+func NoneMatch(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// The request headers an answer varies on, with the request's values
+func VaryValues(r *http.Request, header http.Header) map[string]string {
+	values := map[string]string{}
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				values[name] = r.Header.Get(name)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "cache:"
+
+	// The in-memory layer only saves the trip to Redis, it is small and bounded by size
+	l1MaxBytes = 64 << 20
+)
+
+// A stored answer. Vary holds the request headers the upstream said it varies on, with the values it was stored for.
+type Entry struct {
+	Status    int               `json:"status"`
+	Header    http.Header       `json:"header"`
+	Body      []byte            `json:"body"`
+	ETag      string            `json:"etag"`
+	Vary      map[string]string `json:"vary,omitempty"`
+	StoredAt  time.Time         `json:"stored_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Whether the entry was stored for a request with the same values in the headers the upstream varies on
+func (e *Entry) Matches(r *http.Request) bool {
+	for name, value := range e.Vary {
+		if r.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Entry) size() int {
+	return len(e.Body) + 512
+}
+
+// Answers kept in Redis, shared by every gateway replica, with an in-memory layer in front.
+// Keys carry the generations of the paths they depend on, so an invalidation in one replica
+// makes the old entries unreachable in all of them without purging anything.
+type Store struct {
+	client *redis.Client
+
+	mu      sync.Mutex
+	l1      map[string]*Entry
+	l1Bytes int
+}
+
+func NewStore(client *redis.Client) *Store {
+	return &Store{
+		client: client,
+		l1:     make(map[string]*Entry),
+	}
+}
+
+// The key of the answer to a request for path, under the route prefix. Reads the generations
+// of every path from the prefix down to path, so it changes whenever one of them is invalidated.
+func (s *Store) Key(ctx context.Context, prefix string, path string, rawQuery string) (string, error) {
+	nodes := pathNodes(prefix, path)
+	keys := make([]string, 0, len(nodes)+1)
+	for _, node := range nodes {
+		keys = append(keys, treeGenerationKey(node))
+	}
+	keys = append(keys, selfGenerationKey(nodes[len(nodes)-1]))
+
+	generations, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return "", err
+	}
+
+	var key strings.Builder
+	key.WriteString(path)
+	key.WriteString("?")
+	key.WriteString(rawQuery)
+	for _, generation := range generations {
+		key.WriteString("|")
+		if generation != nil {
+			key.WriteString(generation.(string))
+		}
+	}
+	sum := sha256.Sum256([]byte(key.String()))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *Store) Get(ctx context.Context, key string) (*Entry, bool, error) {
+	now := time.Now()
+	if entry, ok := s.getL1(key, now); ok {
+		return entry, true, nil
+	}
+
+	data, err := s.client.Get(ctx, keyPrefix+"entry:"+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	entry := &Entry{}
+	if err := json.Unmarshal(data, entry); err != nil || !now.Before(entry.ExpiresAt) {
+		return nil, false, nil
+	}
+	s.setL1(key, entry, now)
+	return entry, true, nil
+}
+
+func (s *Store) Set(ctx context.Context, key string, entry *Entry) error {
+	ttl := time.Until(entry.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, keyPrefix+"entry:"+key, data, ttl).Err(); err != nil {
+		return err
+	}
+	s.setL1(key, entry, time.Now())
+	return nil
+}
+
+// Drops what a write to path changed. A POST adds below path, so only path and the listings above it
+// are dropped. Any other write changes path itself, so everything below it is dropped as well.
+func (s *Store) Invalidate(ctx context.Context, prefix string, path string, method string) error {
+	nodes := pathNodes(prefix, path)
+	pipe := s.client.TxPipeline()
+	if method == http.MethodPost {
+		for _, node := range nodes {
+			pipe.Incr(ctx, selfGenerationKey(node))
+		}
+	} else {
+		for _, node := range nodes[:len(nodes)-1] {
+			pipe.Incr(ctx, selfGenerationKey(node))
+		}
+		pipe.Incr(ctx, treeGenerationKey(nodes[len(nodes)-1]))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *Store) getL1(key string, now time.Time) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.l1[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(entry.ExpiresAt) {
+		s.deleteL1(key, entry)
+		return nil, false
+	}
+	return entry, true
+}
+
+func (s *Store) setL1(key string, entry *Entry, now time.Time) {
+	if entry.size() > l1MaxBytes/16 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous, ok := s.l1[key]; ok {
+		s.deleteL1(key, previous)
+	}
+	// Expired entries go first, then arbitrary ones until the new entry fits
+	for k, e := range s.l1 {
+		if s.l1Bytes+entry.size() <= l1MaxBytes {
+			break
+		}
+		if !now.Before(e.ExpiresAt) {
+			s.deleteL1(k, e)
+		}
+	}
+	for k, e := range s.l1 {
+		if s.l1Bytes+entry.size() <= l1MaxBytes {
+			break
+		}
+		s.deleteL1(k, e)
+	}
+	s.l1[key] = entry
+	s.l1Bytes += entry.size()
+}
+
+func (s *Store) deleteL1(key string, entry *Entry) {
+	delete(s.l1, key)
+	s.l1Bytes -= entry.size()
+}
+
+// The prefix and every path below it down to path, such as /api/v1/problem, /api/v1/problem/42
+func pathNodes(prefix string, path string) []string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	nodes := []string{prefix}
+	rest := strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	if rest == "" {
+		return nodes
+	}
+	node := prefix
+	for _, segment := range strings.Split(rest, "/") {
+		node += "/" + segment
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// Invalidates a path and everything below it
+func treeGenerationKey(node string) string {
+	return keyPrefix + "gen:tree:" + node
+}
+
+// Invalidates only the path itself
+func selfGenerationKey(node string) string {
+	return keyPrefix + "gen:self:" + node
+}
@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewStore(client), server
+}
+
+func TestPathNodes(t *testing.T) {
+	tests := []struct {
+		prefix string
+		path   string
+		want   []string
+	}{
+		{prefix: "/api/v1/problem", path: "/api/v1/problem", want: []string{"/api/v1/problem"}},
+		{prefix: "/api/v1/problem/", path: "/api/v1/problem/", want: []string{"/api/v1/problem"}},
+		{prefix: "/api/v1/problem", path: "/api/v1/problem/42", want: []string{"/api/v1/problem", "/api/v1/problem/42"}},
+		{prefix: "/api/v1/problem", path: "/api/v1/problem/42/tests/", want: []string{"/api/v1/problem", "/api/v1/problem/42", "/api/v1/problem/42/tests"}},
+	}
+	for _, tt := range tests {
+		if got := pathNodes(tt.prefix, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pathNodes(%q, %q) = %v, want %v", tt.prefix, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	const prefix = "/api/v1/problem"
+	paths := []string{
+		"/api/v1/problem",
+		"/api/v1/problem/42",
+		"/api/v1/problem/42/tests",
+		"/api/v1/problem/43",
+	}
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		changed []string
+	}{
+		{
+			name:    "POST to the listing drops only the listing",
+			method:  http.MethodPost,
+			path:    "/api/v1/problem",
+			changed: []string{"/api/v1/problem"},
+		},
+		{
+			name:    "POST below drops the path and the listings above it",
+			method:  http.MethodPost,
+			path:    "/api/v1/problem/42/tests",
+			changed: []string{"/api/v1/problem", "/api/v1/problem/42", "/api/v1/problem/42/tests"},
+		},
+		{
+			name:    "PUT drops the path, everything below it and the listings above it",
+			method:  http.MethodPut,
+			path:    "/api/v1/problem/42",
+			changed: []string{"/api/v1/problem", "/api/v1/problem/42", "/api/v1/problem/42/tests"},
+		},
+		{
+			name:    "DELETE of a leaf drops it and the listings above it",
+			method:  http.MethodDelete,
+			path:    "/api/v1/problem/43",
+			changed: []string{"/api/v1/problem", "/api/v1/problem/43"},
+		},
+		{
+			name:    "DELETE at the prefix drops everything",
+			method:  http.MethodDelete,
+			path:    "/api/v1/problem",
+			changed: paths,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := newTestStore(t)
+			ctx := context.Background()
+			keys := func() map[string]string {
+				keys := map[string]string{}
+				for _, path := range paths {
+					key, err := store.Key(ctx, prefix, path, "page=1")
+					if err != nil {
+						t.Fatalf("Key(%s) error = %v", path, err)
+					}
+					keys[path] = key
+				}
+				return keys
+			}
+
+			before := keys()
+			if err := store.Invalidate(ctx, prefix, tt.path, tt.method); err != nil {
+				t.Fatalf("Invalidate() error = %v", err)
+			}
+			after := keys()
+
+			for _, path := range paths {
+				changed := before[path] != after[path]
+				want := false
+				for _, p := range tt.changed {
+					want = want || p == path
+				}
+				if changed != want {
+					t.Errorf("key of %s changed = %v, want %v", path, changed, want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyDependsOnQuery(t *testing.T) {
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+
+	first, _ := store.Key(ctx, "/api/v1/problem", "/api/v1/problem", "page=1")
+	second, _ := store.Key(ctx, "/api/v1/problem", "/api/v1/problem", "page=2")
+	again, _ := store.Key(ctx, "/api/v1/problem", "/api/v1/problem", "page=1")
+	if first == second || first != again {
+		t.Errorf("keys = %s, %s, %s, want one per query", first, second, again)
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	store, server := newTestStore(t)
+	ctx := context.Background()
+	entry := &Entry{
+		Status:    http.StatusOK,
+		Header:    http.Header{"Content-Type": {"application/json"}},
+		Body:      []byte(`{"id":42}`),
+		ETag:      `"abc"`,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	if err := store.Set(ctx, "key-1", entry); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// Another replica reads it from Redis
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	other := NewStore(client)
+	got, ok, err := other.Get(ctx, "key-1")
+	if err != nil || !ok || string(got.Body) != `{"id":42}` || got.ETag != `"abc"` {
+		t.Fatalf("Get() from Redis = %+v, %v, %v", got, ok, err)
+	}
+
+	// This one answers from memory
+	server.FlushAll()
+	if got, ok, _ := store.Get(ctx, "key-1"); !ok || got != entry {
+		t.Errorf("Get() from memory = %+v, %v", got, ok)
+	}
+
+	if _, ok, err := store.Get(ctx, "missing"); ok || err != nil {
+		t.Errorf("Get(missing) = %v, %v", ok, err)
+	}
+	if err := store.Set(ctx, "expired", &Entry{ExpiresAt: time.Now().Add(-time.Second)}); err != nil || server.Exists(keyPrefix+"entry:expired") {
+		t.Errorf("an expired entry was stored, err = %v", err)
+	}
+}
+
+// An entry taking exactly a sixteenth of the in-memory layer, the largest it keeps
+func largestEntry(body []byte, expiresAt time.Time) *Entry {
+	return &Entry{Body: body[:l1MaxBytes/16-512], ExpiresAt: expiresAt}
+}
+
+func TestSetL1Eviction(t *testing.T) {
+	now := time.Now()
+	body := make([]byte, l1MaxBytes/16)
+
+	t.Run("too large", func(t *testing.T) {
+		store := NewStore(nil)
+		store.setL1("large", &Entry{Body: body[:l1MaxBytes/16-511], ExpiresAt: now.Add(time.Minute)}, now)
+		if len(store.l1) != 0 || store.l1Bytes != 0 {
+			t.Errorf("an entry over the size limit was kept")
+		}
+	})
+
+	t.Run("replacing keeps the size", func(t *testing.T) {
+		store := NewStore(nil)
+		store.setL1("key", &Entry{Body: []byte("first"), ExpiresAt: now.Add(time.Minute)}, now)
+		store.setL1("key", &Entry{Body: []byte("second"), ExpiresAt: now.Add(time.Minute)}, now)
+		if len(store.l1) != 1 || store.l1Bytes != len("second")+512 {
+			t.Errorf("l1 holds %d entries in %d bytes", len(store.l1), store.l1Bytes)
+		}
+	})
+
+	t.Run("expired entries go first", func(t *testing.T) {
+		store := NewStore(nil)
+		for i := 0; i < 16; i++ {
+			expiresAt := now.Add(time.Minute)
+			if i == 7 {
+				expiresAt = now.Add(-time.Second)
+			}
+			store.setL1(string(rune('a'+i)), largestEntry(body, expiresAt), now)
+		}
+		if store.l1Bytes != l1MaxBytes {
+			t.Fatalf("l1Bytes = %d, want it full at %d", store.l1Bytes, l1MaxBytes)
+		}
+
+		store.setL1("new", largestEntry(body, now.Add(time.Minute)), now)
+		if _, ok := store.l1["h"]; ok {
+			t.Error("the expired entry was kept")
+		}
+		if len(store.l1) != 16 || store.l1Bytes != l1MaxBytes {
+			t.Errorf("l1 holds %d entries in %d bytes, want 16 in %d", len(store.l1), store.l1Bytes, l1MaxBytes)
+		}
+	})
+
+	t.Run("then any entry", func(t *testing.T) {
+		store := NewStore(nil)
+		for i := 0; i < 16; i++ {
+			store.setL1(string(rune('a'+i)), largestEntry(body, now.Add(time.Minute)), now)
+		}
+		store.setL1("new", &Entry{Body: []byte("small"), ExpiresAt: now.Add(time.Minute)}, now)
+		if _, ok := store.l1["new"]; !ok {
+			t.Error("the new entry was not kept")
+		}
+		if len(store.l1) != 16 || store.l1Bytes > l1MaxBytes {
+			t.Errorf("l1 holds %d entries in %d bytes, want 16 within %d", len(store.l1), store.l1Bytes, l1MaxBytes)
+		}
+	})
+
+	t.Run("expired entries are not served", func(t *testing.T) {
+		store := NewStore(nil)
+		store.setL1("key", &Entry{Body: []byte("body"), ExpiresAt: now.Add(time.Second)}, now)
+		if _, ok := store.getL1("key", now.Add(time.Second)); ok {
+			t.Error("an expired entry was served")
+		}
+		if len(store.l1) != 0 || store.l1Bytes != 0 {
+			t.Errorf("the expired entry was not dropped")
+		}
+	})
+}
@@ -5,8 +5,10 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/XSAM/otelsql"
 	"github.com/go-sql-driver/mysql"
 	"github.com/sirupsen/logrus"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 var DB *sql.DB
@@ -21,7 +23,7 @@ func SetupDBConn() (*sql.DB, error) {
 	DB, err = setupDB()
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"err":  fmt.Sprintf("Error initializing db: ", err),
+			"err":  fmt.Sprintf("Error initializing db: %s", err),
 			"type": "db_init_error",
 		}).Info("Error initializing db")
 		return nil, err
@@ -48,7 +50,8 @@ func setupDB() (*sql.DB, error) {
 		"type": "db_init_info",
 	}).Info("Connecting to db")
 
-	db, err := sql.Open("mysql", cfg.FormatDSN())
+	// Every query gets a span under the request that ran it
+	db, err := otelsql.Open("mysql", cfg.FormatDSN(), otelsql.WithAttributes(semconv.DBSystemNameMySQL))
 	if err != nil || db == nil {
 		logrus.WithFields(logrus.Fields{
 			"msg":  fmt.Sprintf("Error connecting to db: %s", err),
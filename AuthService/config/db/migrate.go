@@ -0,0 +1,54 @@
+package config
+
+import (
+	"AuthService/db/migrations"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+	"github.com/sirupsen/logrus"
+)
+
+func newMigrationProvider(db *sql.DB) (*goose.Provider, error) {
+	return goose.NewProvider(goose.DialectMySQL, db, migrations.FS)
+}
+
+// Applies the migrations the database does not have yet, in the goose_db_version table the goose CLI uses
+func Migrate(ctx context.Context, db *sql.DB) error {
+	provider, err := newMigrationProvider(db)
+	if err != nil {
+		return err
+	}
+	results, err := provider.Up(ctx)
+	for _, result := range results {
+		logrus.WithFields(logrus.Fields{
+			"msg":  result.String(),
+			"type": "db_migration_info",
+		}).Info("Migration applied")
+	}
+	if err != nil {
+		return fmt.Errorf("applying migrations: %w", err)
+	}
+	return nil
+}
+
+// A check that is nil while the database has every migration this build ships with. Serving on an
+// older schema fails the queries that need the newer one, so the instance should not take traffic.
+func MigrationCheck(db *sql.DB) (func(context.Context) error, error) {
+	provider, err := newMigrationProvider(db)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		pending, err := provider.HasPending(ctx)
+		if err != nil || !pending {
+			return err
+		}
+		current, target, err := provider.GetVersions(ctx)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("migrations pending, the database is at version %d of %d", current, target)
+	}, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"AuthService/db/migrations"
+	"database/sql"
+	"io/fs"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func TestMigrationProviderFindsEveryMigration(t *testing.T) {
+	// Opening does not connect, the provider only reads the embedded files here
+	db, err := sql.Open("mysql", "root:password@tcp(127.0.0.1:1)/auth")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	provider, err := newMigrationProvider(db)
+	if err != nil {
+		t.Fatalf("newMigrationProvider() error = %v", err)
+	}
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("fs.Glob() error = %v", err)
+	}
+	sources := provider.ListSources()
+	if len(files) == 0 || len(sources) != len(files) {
+		t.Fatalf("provider has %d migrations, the directory %d", len(sources), len(files))
+	}
+	for i := 1; i < len(sources); i++ {
+		if sources[i].Version <= sources[i-1].Version {
+			t.Errorf("migration %s does not come after %s", sources[i].Path, sources[i-1].Path)
+		}
+	}
+}
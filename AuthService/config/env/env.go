@@ -14,7 +14,7 @@ func Load() {
 	err := godotenv.Load(".env")
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"err":  fmt.Sprintf("Error loading .env file, exiting the program:", err),
+			"err":  fmt.Sprintf("Error loading .env file, exiting the program: %s", err),
 			"type": "env_init_err",
 		}).Error("Error loading .env file")
 		os.Exit(1)
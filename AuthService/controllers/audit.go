@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/models"
+	"AuthService/services"
+	"AuthService/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidAuditFilter = errors.New("actorId must be a number and from/to must be dates (2006-01-02) or RFC3339 times")
+)
+
+type AuditController struct {
+	AuditService services.AuditService
+}
+
+func NewAuditController(_auditService services.AuditService) *AuditController {
+	return &AuditController{
+		AuditService: _auditService,
+	}
+}
+
+func (c *AuditController) GetLogs(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+	filter, err := parseAuditLogFilter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+
+	logs, err := c.AuditService.GetLogs(r.Context(), filter, page, limit)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Audit logs fetched successfully", logs)
+}
+
+// Downloads the entries matching the same filters as GetLogs as a CSV file
+func (c *AuditController) ExportCsv(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditLogFilter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-logs.csv"`)
+	// The status is already sent once rows stream out, a failure can only cut the file short
+	if err := c.AuditService.ExportCsv(r.Context(), filter, w); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "audit_export_error",
+		}).Error("Audit log export failed")
+	}
+}
+
+// Reads the actorId, action, targetType, targetId, from and to query parameters
+func parseAuditLogFilter(r *http.Request) (models.AuditLogFilter, error) {
+	query := r.URL.Query()
+	filter := models.AuditLogFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("targetType"),
+		TargetId:   query.Get("targetId"),
+	}
+	if value := query.Get("actorId"); value != "" {
+		actorId, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, ErrInvalidAuditFilter
+		}
+		filter.ActorId = actorId
+	}
+
+	var ok bool
+	if filter.From, ok = parseFilterTime(query.Get("from")); !ok {
+		return filter, ErrInvalidAuditFilter
+	}
+	if filter.To, ok = parseFilterTime(query.Get("to")); !ok {
+		return filter, ErrInvalidAuditFilter
+	}
+	return filter, nil
+}
+
+// Accepts a date or an RFC3339 time and formats it the way the database compares timestamps, an empty value stays empty
+func parseFilterTime(value string) (string, bool) {
+	if value == "" {
+		return "", true
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.Format(time.DateTime), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/services"
+	"AuthService/utils"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type GuestController struct {
+	GuestService services.GuestService
+	RoleService  services.RoleService
+}
+
+func NewGuestController(_guestService services.GuestService, _roleService services.RoleService) *GuestController {
+	return &GuestController{
+		GuestService: _guestService,
+		RoleService:  _roleService,
+	}
+}
+
+// Signs the visitor in as a new guest, with the same cookie and JWT a password login returns
+func (c *GuestController) CreateGuest(w http.ResponseWriter, r *http.Request) {
+	token, user, err := c.GuestService.CreateGuest(r.Context(), r.UserAgent(), utils.ClientIP(r))
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyGuestAccounts) {
+			utils.WriteErrorResponse(w, http.StatusTooManyRequests, "", services.ErrTooManyGuestAccounts.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	writeLoginResponse(w, r, c.RoleService, user, token)
+}
+
+func (c *GuestController) UpgradeGuest(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.UpgradeGuestDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	tokenClaims, ok := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	token, user, err := c.GuestService.UpgradeGuest(r.Context(), tokenClaims, payloadValue.Username, payloadValue.Email, payloadValue.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrEmailTaken):
+			utils.WriteErrorResponse(w, http.StatusConflict, "", db.ErrEmailTaken.Error())
+		case errors.Is(err, db.ErrUsernameTaken):
+			utils.WriteErrorResponse(w, http.StatusConflict, "", db.ErrUsernameTaken.Error())
+		case errors.Is(err, db.ErrNotGuest):
+			utils.WriteErrorResponse(w, http.StatusConflict, "", db.ErrNotGuest.Error())
+		case errors.Is(err, db.ErrUserNotFound):
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrUserNotFound.Error())
+		default:
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		}
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     "access_token",
+		Value:    token,
+		HttpOnly: true,
+		// Secure: true,
+		Path:    "/",
+		Expires: time.Now().Add(24 * time.Hour),
+		// SameSite: http.SameSiteLax,
+	}
+	http.SetCookie(w, cookie)
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Guest account upgraded successfully", map[string]any{
+		"user":  user,
+		"token": token,
+	})
+}
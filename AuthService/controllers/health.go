@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"AuthService/health"
+	"AuthService/utils"
+	"net/http"
+)
+
+type HealthController struct {
+	Checker *health.Checker
+}
+
+func NewHealthController(_checker *health.Checker) *HealthController {
+	return &HealthController{
+		Checker: _checker,
+	}
+}
+
+// Liveness only says the process answers, a dependency being down is no reason to restart it
+func (c *HealthController) Live(w http.ResponseWriter, r *http.Request) {
+	utils.WriteSuccessResponse(w, http.StatusOK, "Alive", map[string]string{"phase": c.Checker.Phase()})
+}
+
+// Readiness for load balancers and orchestrators, which checks failed is only shown to admins
+func (c *HealthController) Ready(w http.ResponseWriter, r *http.Request) {
+	// Starting or stopping, the dependencies do not matter
+	if phase := c.Checker.Phase(); phase != health.PhaseServing {
+		utils.WriteErrorResponse(w, http.StatusServiceUnavailable, "Not ready", map[string]string{"phase": phase})
+		return
+	}
+
+	report := c.Checker.Run(r.Context())
+	summary := map[string]string{"phase": report.Phase, "status": report.Status}
+	if !report.Ready {
+		utils.WriteErrorResponse(w, http.StatusServiceUnavailable, "Not ready", summary)
+		return
+	}
+	utils.WriteSuccessResponse(w, http.StatusOK, "Ready", summary)
+}
+
+// Every check with its latency and error
+func (c *HealthController) GetHealth(w http.ResponseWriter, r *http.Request) {
+	utils.WriteSuccessResponse(w, http.StatusOK, "Health fetched successfully", c.Checker.Run(r.Context()))
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/services"
+	"AuthService/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ImpersonationController struct {
+	ImpersonationService services.ImpersonationService
+}
+
+func NewImpersonationController(_impersonationService services.ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{
+		ImpersonationService: _impersonationService,
+	}
+}
+
+// Returns a short-lived token acting as the user, it is not set as a cookie so the admin keeps their own session
+func (c *ImpersonationController) Impersonate(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.ImpersonateUserDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	adminIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid user id")
+		return
+	}
+
+	token, expiresAt, err := c.ImpersonationService.Impersonate(r.Context(), int64(adminIdDto.UserId), userId, payloadValue.Reason, utils.ClientIP(r))
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrUserNotFound.Error())
+			return
+		}
+		if errors.Is(err, services.ErrCannotImpersonateAdmin) {
+			utils.WriteErrorResponse(w, http.StatusForbidden, "", services.ErrCannotImpersonateAdmin.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Impersonation started successfully", map[string]any{
+		"token":      token,
+		"user_id":    userId,
+		"expires_at": expiresAt.Format(time.RFC3339),
+	})
+}
+
+func (c *ImpersonationController) GetLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var impersonatorId, userId int64
+	var limit int
+	var err error
+	if value := query.Get("impersonatorId"); value != "" {
+		if impersonatorId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid impersonator id")
+			return
+		}
+	}
+	if value := query.Get("userId"); value != "" {
+		if userId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid user id")
+			return
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid limit")
+			return
+		}
+	}
+
+	logs, err := c.ImpersonationService.GetLogs(r.Context(), impersonatorId, userId, limit)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Impersonation logs fetched successfully", logs)
+}
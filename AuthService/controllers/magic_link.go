@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/services"
+	"AuthService/utils"
+	"errors"
+	"net/http"
+)
+
+type MagicLinkController struct {
+	MagicLinkService services.MagicLinkService
+	RoleService      services.RoleService
+}
+
+func NewMagicLinkController(_magicLinkService services.MagicLinkService, _roleService services.RoleService) *MagicLinkController {
+	return &MagicLinkController{
+		MagicLinkService: _magicLinkService,
+		RoleService:      _roleService,
+	}
+}
+
+func (c *MagicLinkController) RequestLink(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.MagicLinkRequestDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+
+	if err := c.MagicLinkService.RequestLink(r.Context(), payloadValue.Email, utils.ClientIP(r)); err != nil {
+		if errors.Is(err, services.ErrTooManyLoginLinkRequests) {
+			utils.WriteErrorResponse(w, http.StatusTooManyRequests, "", services.ErrTooManyLoginLinkRequests.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "If an account exists for this email, a login link has been sent", nil)
+}
+
+// Exchanges the token from the emailed link for the same cookie and JWT a password login returns
+func (c *MagicLinkController) Callback(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.MagicLinkLoginDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+
+	token, user, err := c.MagicLinkService.Login(r.Context(), payloadValue.Token, r.UserAgent(), utils.ClientIP(r))
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidMagicLinkToken) || errors.Is(err, db.ErrMagicLinkUsed) || errors.Is(err, db.ErrUserNotFound) {
+			utils.WriteErrorResponse(w, http.StatusUnauthorized, "", utils.ErrInvalidMagicLinkToken.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	writeLoginResponse(w, r, c.RoleService, user, token)
+}
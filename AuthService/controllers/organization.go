@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/services"
+	"AuthService/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type OrganizationController struct {
+	OrganizationService services.OrganizationService
+	UserService         services.UserService
+}
+
+func NewOrganizationController(_organizationService services.OrganizationService, _userService services.UserService) *OrganizationController {
+	return &OrganizationController{
+		OrganizationService: _organizationService,
+		UserService:         _userService,
+	}
+}
+
+func (c *OrganizationController) Create(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.CreateOrganizationDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	org, err := c.OrganizationService.CreateOrganization(r.Context(), int64(userIdDto.UserId), payloadValue.Name, payloadValue.Slug)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization created successfully", org)
+}
+
+func (c *OrganizationController) GetUserOrganizations(w http.ResponseWriter, r *http.Request) {
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	orgs, err := c.OrganizationService.GetUserOrganizations(r.Context(), int64(userIdDto.UserId))
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organizations fetched successfully", orgs)
+}
+
+func (c *OrganizationController) GetById(w http.ResponseWriter, r *http.Request) {
+	actorId, orgId, ok := organizationRequestIds(w, r)
+	if !ok {
+		return
+	}
+
+	org, err := c.OrganizationService.GetOrganization(r.Context(), actorId, orgId)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization fetched successfully", org)
+}
+
+func (c *OrganizationController) GetMembers(w http.ResponseWriter, r *http.Request) {
+	actorId, orgId, ok := organizationRequestIds(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := c.OrganizationService.GetMembers(r.Context(), actorId, orgId)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization members fetched successfully", members)
+}
+
+func (c *OrganizationController) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.UpdateOrgMemberDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	actorId, orgId, ok := organizationRequestIds(w, r)
+	if !ok {
+		return
+	}
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid user id")
+		return
+	}
+
+	if _, err := c.OrganizationService.UpdateMemberRole(r.Context(), actorId, orgId, userId, payloadValue.Role); err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization member updated successfully", nil)
+}
+
+func (c *OrganizationController) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	actorId, orgId, ok := organizationRequestIds(w, r)
+	if !ok {
+		return
+	}
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid user id")
+		return
+	}
+
+	if _, err := c.OrganizationService.RemoveMember(r.Context(), actorId, orgId, userId); err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization member removed successfully", nil)
+}
+
+func (c *OrganizationController) InviteMember(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.CreateOrgInvitationDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	actorId, orgId, ok := organizationRequestIds(w, r)
+	if !ok {
+		return
+	}
+
+	invitation, err := c.OrganizationService.InviteMember(r.Context(), actorId, orgId, payloadValue.Email, payloadValue.Role)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Invitation sent successfully", invitation)
+}
+
+func (c *OrganizationController) GetPendingInvitations(w http.ResponseWriter, r *http.Request) {
+	actorId, orgId, ok := organizationRequestIds(w, r)
+	if !ok {
+		return
+	}
+
+	invitations, err := c.OrganizationService.GetPendingInvitations(r.Context(), actorId, orgId)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Invitations fetched successfully", invitations)
+}
+
+func (c *OrganizationController) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.AcceptOrgInvitationDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	userIdDto, okId := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	email, okEmail := r.Context().Value(utils.EmailKey).(string)
+	if !okId || !okEmail {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	invitation, err := c.OrganizationService.AcceptInvitation(r.Context(), int64(userIdDto.UserId), email, payloadValue.Token)
+	if err != nil {
+		writeOrganizationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Invitation accepted successfully", invitation)
+}
+
+// Re-issues the access token with the organization as the active one
+func (c *OrganizationController) SwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	_, orgId, ok := organizationRequestIds(w, r)
+	if !ok {
+		return
+	}
+	c.issueScopedToken(w, r, orgId, "Active organization switched successfully")
+}
+
+// Re-issues the access token without an active organization
+func (c *OrganizationController) ClearActiveOrganization(w http.ResponseWriter, r *http.Request) {
+	c.issueScopedToken(w, r, 0, "Active organization cleared successfully")
+}
+
+func (c *OrganizationController) issueScopedToken(w http.ResponseWriter, r *http.Request, orgId int64, message string) {
+	tokenClaims, ok := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	token, err := c.UserService.SwitchOrganization(r.Context(), tokenClaims, orgId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotOrgMember) {
+			utils.WriteErrorResponse(w, http.StatusForbidden, "", db.ErrNotOrgMember.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     "access_token",
+		Value:    token,
+		HttpOnly: true,
+		// Secure: true,
+		Path:    "/",
+		Expires: time.Now().Add(24 * time.Hour),
+		// SameSite: http.SameSiteLax,
+	}
+	http.SetCookie(w, cookie)
+
+	utils.WriteSuccessResponse(w, http.StatusOK, message, map[string]any{
+		"org_id": orgId,
+		"token":  token,
+	})
+}
+
+func organizationRequestIds(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return 0, 0, false
+	}
+	orgId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid organization id")
+		return 0, 0, false
+	}
+	return int64(userIdDto.UserId), orgId, true
+}
+
+func writeOrganizationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidOrgSlug):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+	case errors.Is(err, services.ErrOrgForbidden):
+		utils.WriteErrorResponse(w, http.StatusForbidden, "", err.Error())
+	case errors.Is(err, db.ErrOrgNotFound), errors.Is(err, db.ErrNotOrgMember), errors.Is(err, db.ErrInvitationNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, "", err.Error())
+	case errors.Is(err, db.ErrOrgSlugTaken), errors.Is(err, db.ErrLastOrgOwner):
+		utils.WriteErrorResponse(w, http.StatusConflict, "", err.Error())
+	default:
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+	}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/policy"
+	"AuthService/services"
+	"AuthService/utils"
+	"net/http"
+	"strings"
+)
+
+type PolicyController struct {
+	Engine      *policy.Engine
+	RoleService services.RoleService
+}
+
+func NewPolicyController(_engine *policy.Engine, _roleService services.RoleService) *PolicyController {
+	return &PolicyController{
+		Engine:      _engine,
+		RoleService: _roleService,
+	}
+}
+
+func (c *PolicyController) GetRoutes(w http.ResponseWriter, r *http.Request) {
+	utils.WriteSuccessResponse(w, http.StatusOK, "Policy routes fetched successfully", c.Engine.Routes())
+}
+
+// Dry run of the gateway policy for a stored user or a hypothetical set of roles
+func (c *PolicyController) Explain(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.PolicyExplainDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+
+	subject := policy.Subject{
+		UserId:      payloadValue.UserId,
+		Roles:       payloadValue.Roles,
+		Permissions: payloadValue.Permissions,
+	}
+
+	if payloadValue.UserId != 0 {
+		roles, err := c.RoleService.GetUserRoles(r.Context(), int64(payloadValue.UserId))
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+			return
+		}
+		permissions, err := c.RoleService.GetUserPermissions(r.Context(), int64(payloadValue.UserId))
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+			return
+		}
+
+		subject.Roles = []string{}
+		for _, role := range roles {
+			subject.Roles = append(subject.Roles, role.Name)
+		}
+		subject.Permissions = []string{}
+		for _, permission := range permissions {
+			subject.Permissions = append(subject.Permissions, permission.Name)
+		}
+	}
+
+	decision := c.Engine.Evaluate(subject, strings.ToUpper(payloadValue.Method), payloadValue.Path)
+
+	response := map[string]any{
+		"subject":  subject,
+		"decision": decision,
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Policy evaluated successfully", response)
+}
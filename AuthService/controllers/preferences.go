@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/models"
+	"AuthService/services"
+	"AuthService/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidIfMatch = errors.New("If-Match must be the version of the preferences")
+)
+
+type PreferencesController struct {
+	PreferencesService services.PreferencesService
+}
+
+func NewPreferencesController(_preferencesService services.PreferencesService) *PreferencesController {
+	return &PreferencesController{
+		PreferencesService: _preferencesService,
+	}
+}
+
+// The version doubles as the ETag, sending it back in If-Match makes a write fail if another session changed them first
+func (c *PreferencesController) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	preferences, err := c.PreferencesService.Get(r.Context(), int64(userIdDto.UserId))
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	w.Header().Set("ETag", preferencesETag(preferences))
+	utils.WriteSuccessResponse(w, http.StatusOK, "Preferences fetched successfully", preferences)
+}
+
+func (c *PreferencesController) ReplacePreferences(w http.ResponseWriter, r *http.Request) {
+	payloadValue, ok := r.Context().Value("payload").(dto.PreferencesDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	userIdDto, tokenClaims, expectedVersion, ok := preferencesRequest(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := c.PreferencesService.Replace(r.Context(), int64(userIdDto.UserId), tokenClaims.SessionId, payloadValue, expectedVersion)
+	writePreferencesResponse(w, preferences, err, expectedVersion != nil)
+}
+
+func (c *PreferencesController) PatchPreferences(w http.ResponseWriter, r *http.Request) {
+	payloadValue, ok := r.Context().Value("payload").(dto.PatchPreferencesDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	userIdDto, tokenClaims, expectedVersion, ok := preferencesRequest(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := c.PreferencesService.Patch(r.Context(), int64(userIdDto.UserId), tokenClaims.SessionId, payloadValue, expectedVersion)
+	writePreferencesResponse(w, preferences, err, expectedVersion != nil)
+}
+
+// Reads the caller and the optional If-Match version of a write, answering the request itself when they are missing or invalid
+func preferencesRequest(w http.ResponseWriter, r *http.Request) (dto.UserIdDTO, dto.TokenClaimsDTO, *int, bool) {
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return userIdDto, dto.TokenClaimsDTO{}, nil, false
+	}
+	tokenClaims, _ := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return userIdDto, tokenClaims, nil, true
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`))
+	if err != nil || version < 0 {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", ErrInvalidIfMatch.Error())
+		return userIdDto, tokenClaims, nil, false
+	}
+	return userIdDto, tokenClaims, &version, true
+}
+
+func writePreferencesResponse(w http.ResponseWriter, preferences *models.UserPreferences, err error, conditional bool) {
+	if err != nil {
+		if errors.Is(err, db.ErrPreferencesVersionConflict) {
+			status := http.StatusConflict
+			if conditional {
+				status = http.StatusPreconditionFailed
+			}
+			utils.WriteErrorResponse(w, status, "", db.ErrPreferencesVersionConflict.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	w.Header().Set("ETag", preferencesETag(preferences))
+	utils.WriteSuccessResponse(w, http.StatusOK, "Preferences updated successfully", preferences)
+}
+
+func preferencesETag(preferences *models.UserPreferences) string {
+	return `"` + strconv.Itoa(preferences.Version) + `"`
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/services"
+	"AuthService/utils"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ProfileController struct {
+	ProfileService services.ProfileService
+	AvatarService  services.AvatarService
+}
+
+func NewProfileController(_profileService services.ProfileService, _avatarService services.AvatarService) *ProfileController {
+	return &ProfileController{
+		ProfileService: _profileService,
+		AvatarService:  _avatarService,
+	}
+}
+
+// Anyone may look a profile up by username, only the fields its owner made public are returned
+func (c *ProfileController) GetPublicProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := c.ProfileService.GetPublicProfile(r.Context(), chi.URLParam(r, "username"))
+	if err != nil {
+		if errors.Is(err, db.ErrProfileNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrProfileNotFound.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Profile fetched successfully", profile)
+}
+
+func (c *ProfileController) GetOwnProfile(w http.ResponseWriter, r *http.Request) {
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	profile, err := c.ProfileService.GetOwnProfile(r.Context(), int64(userIdDto.UserId))
+	if err != nil {
+		if errors.Is(err, db.ErrProfileNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrProfileNotFound.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Profile fetched successfully", profile)
+}
+
+func (c *ProfileController) UpdateOwnProfile(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.UpdateProfileDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	profile, err := c.ProfileService.UpdateOwnProfile(r.Context(), int64(userIdDto.UserId), payloadValue)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Profile updated successfully", profile)
+}
+
+// Takes the image from the multipart field avatar
+func (c *ProfileController) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	// Leaves room for the multipart framing around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, services.AvatarMaxBytes+64<<10)
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, "", services.ErrAvatarTooLarge.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "An image is required in the avatar field")
+		return
+	}
+	defer file.Close()
+
+	profile, err := c.AvatarService.Upload(r.Context(), int64(userIdDto.UserId), file)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAvatarTooLarge):
+			utils.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, "", err.Error())
+		case errors.Is(err, services.ErrUnsupportedAvatar):
+			utils.WriteErrorResponse(w, http.StatusUnsupportedMediaType, "", err.Error())
+		case errors.Is(err, services.ErrInvalidAvatarDimension):
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+		case errors.Is(err, services.ErrTooManyAvatarUploads):
+			utils.WriteErrorResponse(w, http.StatusTooManyRequests, "", err.Error())
+		default:
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Avatar uploaded successfully", profile)
+}
+
+func (c *ProfileController) RemoveAvatar(w http.ResponseWriter, r *http.Request) {
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	profile, err := c.AvatarService.Remove(r.Context(), int64(userIdDto.UserId))
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Avatar removed successfully", profile)
+}
+
+// Serves a thumbnail. A key is never reused for other content, so browsers and proxies may keep it for good.
+func (c *ProfileController) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.Atoi(strings.TrimSuffix(chi.URLParam(r, "file"), ".jpg"))
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusNotFound, "", services.ErrAvatarNotFound.Error())
+		return
+	}
+	avatarKey := chi.URLParam(r, "key")
+	etag := `"` + avatarKey + "-" + strconv.Itoa(size) + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	reader, info, err := c.AvatarService.Open(r.Context(), avatarKey, size)
+	if err != nil {
+		if errors.Is(err, services.ErrAvatarNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", services.ErrAvatarNotFound.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, reader)
+}
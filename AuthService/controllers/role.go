@@ -58,6 +58,14 @@ func (c *RoleController) UpdateRole(w http.ResponseWriter, r *http.Request) {
 	}
 	role, err := c.RoleService.UpdateRole(r.Context(), roleIdInt, payloadValue.Name, payloadValue.Description)
 	if err != nil {
+		if errors.Is(err, db.ErrRoleNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrRoleNotFound.Error())
+			return
+		}
+		if errors.Is(err, db.ErrSystemRole) {
+			utils.WriteErrorResponse(w, http.StatusConflict, "", db.ErrSystemRole.Error())
+			return
+		}
 		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
 		return
 	}
@@ -190,9 +198,105 @@ func (c *RoleController) RemoveRole(w http.ResponseWriter, r *http.Request) {
 			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrRoleNotFound.Error())
 			return
 		}
+		if errors.Is(err, db.ErrLastAdmin) {
+			utils.WriteErrorResponse(w, http.StatusConflict, "", db.ErrLastAdmin.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Role removed successfully", nil)
+}
+
+func (c *RoleController) RemoveUserRole(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "userId")
+	userIdInt, err := strconv.Atoi(userId)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid user id")
+		return
+	}
+
+	roleId := chi.URLParam(r, "roleId")
+	roleIdInt, err := strconv.Atoi(roleId)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid role id")
+		return
+	}
+
+	_, err = c.RoleService.RemoveUserRole(r.Context(), userIdInt, roleIdInt)
+	if err != nil {
+		if errors.Is(err, db.ErrRoleNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrRoleNotFound.Error())
+			return
+		}
+		if errors.Is(err, db.ErrLastAdmin) {
+			utils.WriteErrorResponse(w, http.StatusConflict, "", db.ErrLastAdmin.Error())
+			return
+		}
 		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
 		return
 	}
 
 	utils.WriteSuccessResponse(w, http.StatusOK, "Role removed successfully", nil)
 }
+
+func (c *RoleController) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	roleId := chi.URLParam(r, "id")
+	roleIdInt, err := strconv.Atoi(roleId)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid role id")
+		return
+	}
+
+	// Members keep access through another role when reassignTo is given
+	reassignToInt := 0
+	if reassignTo := r.URL.Query().Get("reassignTo"); reassignTo != "" {
+		reassignToInt, err = strconv.Atoi(reassignTo)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid reassign role id")
+			return
+		}
+	}
+
+	_, err = c.RoleService.DeleteRole(r.Context(), roleIdInt, reassignToInt)
+	if err != nil {
+		if errors.Is(err, db.ErrRoleNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrRoleNotFound.Error())
+			return
+		}
+		if errors.Is(err, db.ErrSystemRole) {
+			utils.WriteErrorResponse(w, http.StatusConflict, "", db.ErrSystemRole.Error())
+			return
+		}
+		if errors.Is(err, db.ErrInvalidReassign) {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", db.ErrInvalidReassign.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Role deleted successfully", nil)
+}
+
+func (c *RoleController) GetRoleMembers(w http.ResponseWriter, r *http.Request) {
+	roleId := chi.URLParam(r, "id")
+	roleIdInt, err := strconv.Atoi(roleId)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid role id")
+		return
+	}
+
+	members, err := c.RoleService.GetRoleMembers(r.Context(), roleIdInt)
+	if err != nil {
+		if errors.Is(err, db.ErrRoleNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrRoleNotFound.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Role members fetched successfully", members)
+}
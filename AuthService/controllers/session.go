@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/services"
+	"AuthService/utils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SessionController struct {
+	SessionService services.SessionService
+}
+
+func NewSessionController(_sessionService services.SessionService) *SessionController {
+	return &SessionController{
+		SessionService: _sessionService,
+	}
+}
+
+func (c *SessionController) GetSessions(w http.ResponseWriter, r *http.Request) {
+	tokenClaims, ok := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	sessions, err := c.SessionService.GetSessions(r.Context(), int64(tokenClaims.UserId), tokenClaims.SessionId)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Sessions fetched successfully", sessions)
+}
+
+func (c *SessionController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	tokenClaims, ok := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+	sessionId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid session id")
+		return
+	}
+
+	if _, err := c.SessionService.RevokeSession(r.Context(), int64(tokenClaims.UserId), sessionId); err != nil {
+		if errors.Is(err, db.ErrSessionNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrSessionNotFound.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// Signs out every device except the one making the request
+func (c *SessionController) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	tokenClaims, ok := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	revoked, err := c.SessionService.RevokeOtherSessions(r.Context(), int64(tokenClaims.UserId), tokenClaims.SessionId)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Sessions revoked successfully", map[string]any{
+		"revoked": revoked,
+	})
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/services"
+	"AuthService/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SuspensionController struct {
+	SuspensionService services.SuspensionService
+}
+
+func NewSuspensionController(_suspensionService services.SuspensionService) *SuspensionController {
+	return &SuspensionController{
+		SuspensionService: _suspensionService,
+	}
+}
+
+// Lists suspensions newest first, optionally only those of one user or only the active ones
+func (c *SuspensionController) GetSuspensions(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+	query := r.URL.Query()
+	var userId int64
+	if value := query.Get("userId"); value != "" {
+		if userId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid user id")
+			return
+		}
+	}
+	activeOnly := query.Get("active") == "true"
+
+	suspensions, err := c.SuspensionService.GetSuspensions(r.Context(), userId, activeOnly, page, limit)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Suspensions fetched successfully", suspensions)
+}
+
+func (c *SuspensionController) Suspend(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.CreateSuspensionDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	adminIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	duration := time.Duration(payloadValue.DurationMinutes) * time.Minute
+	suspension, err := c.SuspensionService.Suspend(r.Context(), int64(adminIdDto.UserId), payloadValue.UserId, payloadValue.Scope, payloadValue.Reason, duration)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrUserNotFound.Error())
+			return
+		}
+		if errors.Is(err, services.ErrCannotSuspendAdmin) {
+			utils.WriteErrorResponse(w, http.StatusForbidden, "", services.ErrCannotSuspendAdmin.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User suspended successfully", suspension)
+}
+
+func (c *SuspensionController) Lift(w http.ResponseWriter, r *http.Request) {
+	adminIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid suspension id")
+		return
+	}
+
+	suspension, err := c.SuspensionService.Lift(r.Context(), int64(adminIdDto.UserId), id)
+	if err != nil {
+		if errors.Is(err, db.ErrSuspensionNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrSuspensionNotFound.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Suspension lifted successfully", suspension)
+}
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"AuthService/upstream"
+	"AuthService/utils"
+	"net/http"
+)
+
+type UpstreamController struct {
+	Pools *upstream.Registry
+}
+
+func NewUpstreamController(_pools *upstream.Registry) *UpstreamController {
+	return &UpstreamController{
+		Pools: _pools,
+	}
+}
+
+// Health, ejection and load of every replica behind the gateway
+func (c *UpstreamController) GetUpstreams(w http.ResponseWriter, r *http.Request) {
+	utils.WriteSuccessResponse(w, http.StatusOK, "Upstreams fetched successfully", c.Pools.Status())
+}
@@ -1,31 +1,41 @@
 package controllers
 
 import (
+	env "AuthService/config/env"
 	db "AuthService/db/repositories"
 	"AuthService/dto"
+	"AuthService/models"
 	"AuthService/services"
 	"AuthService/utils"
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
 var (
-	ErrInvalidUserId = errors.New("invalid user id")
+	ErrInvalidUserId         = errors.New("invalid user id")
+	ErrInvalidUserListFilter = errors.New("createdFrom and createdTo must be dates (2006-01-02) or RFC3339 times, deleted, suspended and verified must be true or false")
 )
 
 type UserController struct {
-	UserService services.UserService
-	RoleService services.RoleService
+	UserService         services.UserService
+	RoleService         services.RoleService
+	UserImportService   services.UserImportService
+	LoginHistoryService services.LoginHistoryService
+	PreferencesService  services.PreferencesService
 }
 
-func NewUserController(_userService services.UserService, _roleService services.RoleService) *UserController {
+func NewUserController(_userService services.UserService, _roleService services.RoleService, _userImportService services.UserImportService, _loginHistoryService services.LoginHistoryService, _preferencesService services.PreferencesService) *UserController {
 	return &UserController{
-		UserService: _userService,
-		RoleService: _roleService,
+		UserService:         _userService,
+		RoleService:         _roleService,
+		UserImportService:   _userImportService,
+		LoginHistoryService: _loginHistoryService,
+		PreferencesService:  _preferencesService,
 	}
 }
 
@@ -80,6 +90,10 @@ func (c *UserController) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	user, err := c.UserService.Create(r.Context(), payloadValue.Username, payloadValue.Email, payloadValue.Password)
 	if err != nil {
+		if errors.Is(err, db.ErrUsernameTaken) {
+			utils.WriteErrorResponse(w, http.StatusConflict, "", db.ErrUsernameTaken.Error())
+			return
+		}
 		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
 		return
 	}
@@ -87,9 +101,26 @@ func (c *UserController) Create(w http.ResponseWriter, r *http.Request) {
 	utils.WriteSuccessResponse(w, http.StatusOK, "User created successfully", user)
 }
 
+// Lists users a page at a time. Filters: role, createdFrom, createdTo, deleted, suspended, verified and q, a username or email prefix.
+// sort is id, created_at, username or email, prefixed with - for descending order, and defaults to -created_at.
 func (c *UserController) GetAll(w http.ResponseWriter, r *http.Request) {
-	users, err := c.UserService.GetAll(r.Context())
+	limit, err := utils.ParseLimit(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+	filter, err := parseUserListFilter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+
+	users, err := c.UserService.ListUsers(r.Context(), filter, r.URL.Query().Get("cursor"), limit)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidUserListCursor) || errors.Is(err, services.ErrInvalidUserListSort) {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+			return
+		}
 		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
 		return
 	}
@@ -97,6 +128,50 @@ func (c *UserController) GetAll(w http.ResponseWriter, r *http.Request) {
 	utils.WriteSuccessResponse(w, http.StatusOK, "Users fetched successfully", users)
 }
 
+func parseUserListFilter(r *http.Request) (models.UserListFilter, error) {
+	query := r.URL.Query()
+	filter := models.UserListFilter{
+		Role:       query.Get("role"),
+		Search:     strings.TrimSpace(query.Get("q")),
+		SortBy:     "created_at",
+		Descending: true,
+	}
+	if sort := query.Get("sort"); sort != "" {
+		filter.SortBy = strings.TrimPrefix(sort, "-")
+		filter.Descending = strings.HasPrefix(sort, "-")
+	}
+
+	var ok bool
+	if filter.CreatedFrom, ok = parseFilterTime(query.Get("createdFrom")); !ok {
+		return filter, ErrInvalidUserListFilter
+	}
+	if filter.CreatedTo, ok = parseFilterTime(query.Get("createdTo")); !ok {
+		return filter, ErrInvalidUserListFilter
+	}
+	if filter.Deleted, ok = parseOptionalBool(query.Get("deleted")); !ok {
+		return filter, ErrInvalidUserListFilter
+	}
+	if filter.Suspended, ok = parseOptionalBool(query.Get("suspended")); !ok {
+		return filter, ErrInvalidUserListFilter
+	}
+	if filter.Verified, ok = parseOptionalBool(query.Get("verified")); !ok {
+		return filter, ErrInvalidUserListFilter
+	}
+	return filter, nil
+}
+
+// An empty value means the flag does not filter
+func parseOptionalBool(value string) (*bool, bool) {
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, false
+	}
+	return &parsed, true
+}
+
 func (c *UserController) DeleteById(w http.ResponseWriter, r *http.Request) {
 	payload := r.Context().Value("payload")
 	payloadValue, ok := payload.(dto.UserIdDTO)
@@ -105,7 +180,7 @@ func (c *UserController) DeleteById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := c.UserService.DeleteById(r.Context(), string(payloadValue.UserId))
+	_, err := c.UserService.DeleteById(r.Context(), strconv.Itoa(payloadValue.UserId))
 	if err != nil {
 		if errors.Is(err, db.ErrUserNotFound) {
 			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrUserNotFound.Error())
@@ -126,7 +201,7 @@ func (c *UserController) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := c.UserService.LoginUser(r.Context(), payloadValue.Email, payloadValue.Password)
+	token, err := c.UserService.LoginUser(r.Context(), payloadValue.Email, payloadValue.Password, r.UserAgent(), utils.ClientIP(r))
 	if err != nil {
 		if errors.Is(err, db.ErrUserNotFound) {
 			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrUserNotFound.Error())
@@ -149,9 +224,15 @@ func (c *UserController) LoginUser(w http.ResponseWriter, r *http.Request) {
 		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
 		return
 	}
+
+	writeLoginResponse(w, r, c.RoleService, user, token)
+}
+
+// Responds to a successful login with the user, their roles and the token, which is also set as the access cookie
+func writeLoginResponse(w http.ResponseWriter, r *http.Request, roleService services.RoleService, user *models.User, token string) {
 	user.Password = ""
 
-	roles, err := c.RoleService.GetUserRoles(r.Context(), user.Id)
+	roles, err := roleService.GetUserRoles(r.Context(), user.Id)
 	if err != nil {
 		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
 		return
@@ -183,6 +264,13 @@ func (c *UserController) LoginUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *UserController) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	if tokenClaims, ok := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO); ok {
+		if err := c.UserService.LogoutUser(r.Context(), tokenClaims); err != nil {
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+			return
+		}
+	}
+
 	cookie := &http.Cookie{
 		Name:     "access_token",
 		Value:    "",
@@ -229,16 +317,26 @@ func (c *UserController) ValidateUserSession(w http.ResponseWriter, r *http.Requ
 		modifiedRoles = append(modifiedRoles, item.Name)
 	}
 
-	token, err := c.UserService.ValidateUserSession(r.Context(), user.Email, int(user.Id))
+	tokenClaims, _ := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+
+	token, err := c.UserService.ValidateUserSession(r.Context(), user.Email, int(user.Id), tokenClaims)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	// Clients refetch their preferences only when this differs from the hash they cached
+	preferences, err := c.PreferencesService.Get(r.Context(), user.Id)
 	if err != nil {
 		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
 		return
 	}
 
 	response := map[string]any{
-		"user":  user,
-		"roles": modifiedRoles,
-		"token": token,
+		"user":             user,
+		"roles":            modifiedRoles,
+		"token":            token,
+		"preferences_hash": preferences.Hash,
 	}
 
 	cookie := &http.Cookie{
@@ -254,3 +352,133 @@ func (c *UserController) ValidateUserSession(w http.ResponseWriter, r *http.Requ
 
 	utils.WriteSuccessResponse(w, http.StatusOK, "User session validated successfully", response)
 }
+
+// Imports users from a CSV or JSONL body, files above IMPORT_SYNC_LIMIT rows run as a background job
+func (c *UserController) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 5<<20)
+
+	rows, err := c.UserImportService.ParseRows(r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedImportFormat) {
+			utils.WriteErrorResponse(w, http.StatusUnsupportedMediaType, "", err.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid import file", err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid import file", "No rows to import")
+		return
+	}
+
+	if len(rows) > env.GetInt("IMPORT_SYNC_LIMIT", 20) {
+		job, err := c.UserImportService.StartImportJob(r.Context(), rows)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+			return
+		}
+		utils.WriteSuccessResponse(w, http.StatusAccepted, "User import started", job)
+		return
+	}
+
+	job := c.UserImportService.Import(r.Context(), rows)
+	utils.WriteSuccessResponse(w, http.StatusOK, "Users imported successfully", job)
+}
+
+func (c *UserController) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	job, err := c.UserImportService.GetImportJob(r.Context(), chi.URLParam(r, "jobId"))
+	if err != nil {
+		if errors.Is(err, services.ErrImportJobNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", services.ErrImportJobNotFound.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User import fetched successfully", job)
+}
+
+func (c *UserController) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.AcceptInviteDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+
+	if err := c.UserService.AcceptInvite(r.Context(), payloadValue.Token, payloadValue.Password); err != nil {
+		if errors.Is(err, utils.ErrInvalidInviteToken) {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", utils.ErrInvalidInviteToken.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Invite accepted successfully", nil)
+}
+
+func (c *UserController) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	payload := r.Context().Value("payload")
+	payloadValue, ok := payload.(dto.ChangePasswordDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid request payload")
+		return
+	}
+	tokenClaims, ok := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+
+	err := c.UserService.ChangePassword(r.Context(), tokenClaims, payloadValue.CurrentPassword, payloadValue.NewPassword)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "", db.ErrUserNotFound.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "", services.ErrInvalidCredentials.Error())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Password changed successfully", nil)
+}
+
+func (c *UserController) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userDTO, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", "You are not authorized to access this route")
+		return
+	}
+	c.writeLoginHistory(w, r, int64(userDTO.UserId))
+}
+
+func (c *UserController) GetUserLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", "Invalid user id")
+		return
+	}
+	c.writeLoginHistory(w, r, userId)
+}
+
+func (c *UserController) writeLoginHistory(w http.ResponseWriter, r *http.Request, userId int64) {
+	page, limit, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+
+	history, err := c.LoginHistoryService.GetHistory(r.Context(), userId, page, limit)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "", db.ErrInternalServerError.Error())
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Login history fetched successfully", history)
+}
@@ -47,6 +47,7 @@ func WsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	userId := userIdDto.UserId
+	tokenClaims, _ := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
 
 	// 3. Upgrade initial GET request to a websocket
 	ws, err := upgrader.Upgrade(w, r, nil)
@@ -63,7 +64,7 @@ func WsHandler(w http.ResponseWriter, r *http.Request) {
 	}).Info("Websocket Connected")
 
 	// 4. Register connection
-	services.RegisterConnection(userId, ws)
+	services.RegisterConnection(userId, tokenClaims.SessionId, ws)
 	defer services.UnregisterConnection(userId, ws)
 
 	// Message to send
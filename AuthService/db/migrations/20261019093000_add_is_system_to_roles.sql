@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE roles ADD COLUMN is_system BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE roles SET is_system = true WHERE name IN ('admin', 'user');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE roles DROP COLUMN is_system;
+-- +goose StatementEnd
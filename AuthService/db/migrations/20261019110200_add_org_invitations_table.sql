@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS org_invitations (
+    id SERIAL PRIMARY KEY,
+    org_id BIGINT UNSIGNED NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    role ENUM('admin', 'member') NOT NULL DEFAULT 'member',
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    invited_by BIGINT UNSIGNED NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    FOREIGN KEY (invited_by) REFERENCES users(id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS org_invitations;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS impersonation_logs (
+    id SERIAL PRIMARY KEY,
+    impersonator_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    action ENUM('start', 'request') NOT NULL,
+    method VARCHAR(10) NOT NULL DEFAULT '',
+    path VARCHAR(2048) NOT NULL DEFAULT '',
+    status_code INT NOT NULL DEFAULT 0,
+    reason VARCHAR(500) NOT NULL DEFAULT '',
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (impersonator_id) REFERENCES users(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    INDEX impersonation_logs_impersonator_idx (impersonator_id, created_at),
+    INDEX impersonation_logs_user_idx (user_id, created_at)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS impersonation_logs;
+-- +goose StatementEnd
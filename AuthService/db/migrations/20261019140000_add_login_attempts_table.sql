@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id SERIAL PRIMARY KEY,
+    user_id BIGINT UNSIGNED NULL,
+    email VARCHAR(255) NOT NULL,
+    outcome ENUM('success', 'invalid_password', 'unknown_user') NOT NULL,
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    ip_range VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    device_label VARCHAR(255) NOT NULL DEFAULT '',
+    new_device BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX login_attempts_user_idx (user_id, id),
+    INDEX login_attempts_known_device_idx (user_id, outcome, device_label, ip_range),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_attempts;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN is_guest BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO roles (name, description, is_system) VALUES ('guest', 'Anonymous visitor trying the platform before signing up', true);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM user_roles WHERE role_id = (SELECT id FROM roles WHERE name = 'guest');
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DELETE FROM roles WHERE name = 'guest';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN is_guest;
+-- +goose StatementEnd
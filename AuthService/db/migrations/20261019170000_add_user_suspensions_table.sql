@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_suspensions (
+    id SERIAL PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    scope ENUM('full', 'submissions') NOT NULL,
+    reason VARCHAR(500) NOT NULL,
+    created_by BIGINT UNSIGNED NOT NULL,
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    lifted_at TIMESTAMP NULL DEFAULT NULL,
+    lifted_by BIGINT UNSIGNED NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX user_suspensions_user_idx (user_id, lifted_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id),
+    FOREIGN KEY (lifted_by) REFERENCES users(id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_suspensions;
+-- +goose StatementEnd
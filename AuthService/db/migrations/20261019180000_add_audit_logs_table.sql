@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id SERIAL PRIMARY KEY,
+    actor_id BIGINT UNSIGNED NULL DEFAULT NULL,
+    impersonator_id BIGINT UNSIGNED NULL DEFAULT NULL,
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id VARCHAR(64) NOT NULL DEFAULT '',
+    changes JSON NULL,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX audit_logs_actor_idx (actor_id, created_at),
+    INDEX audit_logs_target_idx (target_type, target_id),
+    INDEX audit_logs_action_idx (action, created_at)
+);
+-- +goose StatementEnd
+
+-- Entries outlive the users they mention, so there are no foreign keys, and they can never be changed or removed
+-- +goose StatementBegin
+CREATE TRIGGER audit_logs_no_update BEFORE UPDATE ON audit_logs
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_logs_no_delete BEFORE DELETE ON audit_logs
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS audit_logs_no_delete;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS audit_logs_no_update;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_logs;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- InnoDB appends the primary key to secondary indexes, so these also serve the (column, id) keyset order of the users list
+-- +goose StatementBegin
+CREATE INDEX users_created_at_idx ON users (created_at);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX users_username_idx ON users (username);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX users_username_idx ON users;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX users_created_at_idx ON users;
+-- +goose StatementEnd
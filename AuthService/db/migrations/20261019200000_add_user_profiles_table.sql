@@ -0,0 +1,55 @@
+-- +goose Up
+-- Profiles are addressed by username, so it becomes unique among users that are not deleted.
+-- Existing duplicates keep the name on their oldest account, the others get their id appended.
+-- +goose StatementBegin
+UPDATE users u
+JOIN (
+    SELECT username, MIN(id) AS keep_id
+    FROM users
+    WHERE COALESCE(is_deleted, false) = false
+    GROUP BY username
+    HAVING COUNT(*) > 1
+) duplicates ON u.username = duplicates.username
+SET u.username = CONCAT(LEFT(u.username, 254 - CHAR_LENGTH(u.id)), '-', u.id)
+WHERE u.id <> duplicates.keep_id AND COALESCE(u.is_deleted, false) = false;
+-- +goose StatementEnd
+
+-- Deleted users hold no name, NULL is never a duplicate
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN active_username VARCHAR(255)
+    GENERATED ALWAYS AS (IF(COALESCE(is_deleted, false), NULL, username)) VIRTUAL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX users_username_unique ON users (active_username);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id BIGINT UNSIGNED PRIMARY KEY,
+    display_name VARCHAR(100) NOT NULL DEFAULT '',
+    bio VARCHAR(500) NOT NULL DEFAULT '',
+    display_name_public BOOLEAN NOT NULL DEFAULT true,
+    bio_public BOOLEAN NOT NULL DEFAULT true,
+    avatar_public BOOLEAN NOT NULL DEFAULT true,
+    join_date_public BOOLEAN NOT NULL DEFAULT true,
+    stats_public BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- Renamed duplicates keep their new names
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_profiles;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX users_username_unique ON users;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN active_username;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Avatars are uploaded, the profile keeps the key of their stored thumbnails
+-- +goose StatementBegin
+ALTER TABLE user_profiles ADD COLUMN avatar_key VARCHAR(64) NULL AFTER bio;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_profiles DROP COLUMN avatar_key;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- One JSON document per user, version goes up by one on every write
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id BIGINT UNSIGNED PRIMARY KEY,
+    document JSON NOT NULL,
+    version INT UNSIGNED NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_preferences;
+-- +goose StatementEnd
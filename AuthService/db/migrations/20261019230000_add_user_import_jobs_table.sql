@@ -0,0 +1,23 @@
+-- +goose Up
+-- Background imports report their progress here, so any replica can answer a poll and a restart loses nothing.
+-- results holds one object per processed row, appended batch by batch
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_import_jobs (
+    id VARCHAR(32) PRIMARY KEY,
+    status ENUM('queued', 'running', 'completed', 'failed') NOT NULL DEFAULT 'queued',
+    total INT UNSIGNED NOT NULL,
+    processed INT UNSIGNED NOT NULL DEFAULT 0,
+    created INT UNSIGNED NOT NULL DEFAULT 0,
+    failed INT UNSIGNED NOT NULL DEFAULT 0,
+    results JSON NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX user_import_jobs_finished_idx (finished_at)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_import_jobs;
+-- +goose StatementEnd
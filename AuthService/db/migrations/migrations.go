@@ -0,0 +1,8 @@
+// Package migrations holds the goose migrations of the auth database. They are embedded so the
+// gateway can apply them when it starts, the makefile targets run the same files through the goose CLI.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
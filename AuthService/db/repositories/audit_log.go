@@ -0,0 +1,133 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *models.AuditLog) error
+	GetAll(ctx context.Context, filter models.AuditLogFilter, page int, limit int) ([]*models.AuditLog, int, error)
+	Each(ctx context.Context, filter models.AuditLogFilter, maxRows int, fn func(*models.AuditLog) error) error
+}
+
+type AuditLogRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewAuditLogRepository(_db *sql.DB) AuditLogRepository {
+	return &AuditLogRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	createAuditLogQuery = `
+		INSERT INTO audit_logs (actor_id, impersonator_id, action, target_type, target_id, changes, request_id, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	// Empty filter values match every entry
+	auditLogFilterCondition = `
+		WHERE (? = 0 OR actor_id = ?)
+		AND (? = '' OR action = ?)
+		AND (? = '' OR target_type = ?)
+		AND (? = '' OR target_id = ?)
+		AND (? = '' OR created_at >= ?)
+		AND (? = '' OR created_at < ?)`
+	countAuditLogsQuery = "SELECT COUNT(*) FROM audit_logs" + auditLogFilterCondition
+	getAuditLogsQuery   = `
+		SELECT id, COALESCE(actor_id, 0), COALESCE(impersonator_id, 0), action, target_type, target_id, COALESCE(changes, 'null'), request_id, ip_address, created_at
+		FROM audit_logs` + auditLogFilterCondition + `
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`
+)
+
+func (r *AuditLogRepositoryImpl) Create(ctx context.Context, entry *models.AuditLog) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// A zero actor is the system itself
+	var actorId, impersonatorId sql.NullInt64
+	if entry.ActorId != 0 {
+		actorId = sql.NullInt64{Int64: entry.ActorId, Valid: true}
+	}
+	if entry.ImpersonatorId != 0 {
+		impersonatorId = sql.NullInt64{Int64: entry.ImpersonatorId, Valid: true}
+	}
+	var changes any
+	if len(entry.Changes) > 0 {
+		changes = string(entry.Changes)
+	}
+
+	_, err := r.db.ExecContext(ctx, createAuditLogQuery, actorId, impersonatorId, entry.Action, entry.TargetType, truncate(entry.TargetId, 64), changes, truncate(entry.RequestId, 64), entry.IpAddress)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (r *AuditLogRepositoryImpl) GetAll(ctx context.Context, filter models.AuditLogFilter, page int, limit int) ([]*models.AuditLog, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, countAuditLogsQuery, auditLogFilterArgs(filter)...).Scan(&total); err != nil {
+		return nil, 0, ErrInternalServerError
+	}
+
+	entries := []*models.AuditLog{}
+	err := r.query(ctx, filter, limit, (page-1)*limit, func(entry *models.AuditLog) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// Hands matching entries to fn one at a time, newest first, so exports never hold the whole log in memory
+func (r *AuditLogRepositoryImpl) Each(ctx context.Context, filter models.AuditLogFilter, maxRows int, fn func(*models.AuditLog) error) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return r.query(ctx, filter, maxRows, 0, fn)
+}
+
+func (r *AuditLogRepositoryImpl) query(ctx context.Context, filter models.AuditLogFilter, limit int, offset int, fn func(*models.AuditLog) error) error {
+	args := append(auditLogFilterArgs(filter), limit, offset)
+	rows, err := r.db.QueryContext(ctx, getAuditLogsQuery, args...)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := &models.AuditLog{}
+		var changes string
+		if err := rows.Scan(&entry.Id, &entry.ActorId, &entry.ImpersonatorId, &entry.Action, &entry.TargetType, &entry.TargetId, &changes, &entry.RequestId, &entry.IpAddress, &entry.CreatedAt); err != nil {
+			return ErrInternalServerError
+		}
+		entry.Changes = []byte(changes)
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func auditLogFilterArgs(filter models.AuditLogFilter) []any {
+	return []any{
+		filter.ActorId, filter.ActorId,
+		filter.Action, filter.Action,
+		filter.TargetType, filter.TargetType,
+		filter.TargetId, filter.TargetId,
+		filter.From, filter.From,
+		filter.To, filter.To,
+	}
+}
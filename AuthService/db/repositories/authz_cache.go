@@ -0,0 +1,67 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Bumps made through this process drop their entries at once, other replicas see them within the TTL
+	authzVersionTTL = 5 * time.Second
+
+	// Past this many entries the expired ones are swept on the next write
+	authzVersionSweepSize = 10000
+)
+
+type authzVersionEntry struct {
+	version   int
+	expiresAt time.Time
+}
+
+// Authz versions are read on every authenticated request, keeping them briefly saves most of those queries
+type authzVersionCache struct {
+	mu      sync.Mutex
+	entries map[int]authzVersionEntry
+}
+
+var authzVersions = &authzVersionCache{entries: make(map[int]authzVersionEntry)}
+
+func (c *authzVersionCache) get(userId int, now time.Time) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userId]
+	if !ok || !now.Before(entry.expiresAt) {
+		return 0, false
+	}
+	return entry.version, true
+}
+
+func (c *authzVersionCache) set(userId int, version int, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= authzVersionSweepSize {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[userId] = authzVersionEntry{version: version, expiresAt: now.Add(authzVersionTTL)}
+}
+
+func (c *authzVersionCache) forget(userId int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, userId)
+}
+
+// For bumps that reach every member of a role, whose ids are not at hand
+func (c *authzVersionCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[int]authzVersionEntry)
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var roleColumns = []string{"id", "name", "description", "created_at", "updated_at", "is_system"}
+
+func TestAuthzVersionCache(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "when set", at: now, want: true},
+		{name: "within the TTL", at: now.Add(authzVersionTTL - time.Millisecond), want: true},
+		{name: "at the TTL", at: now.Add(authzVersionTTL)},
+		{name: "past the TTL", at: now.Add(time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &authzVersionCache{entries: make(map[int]authzVersionEntry)}
+			cache.set(7, 3, now)
+
+			got, ok := cache.get(7, tt.at)
+			if ok != tt.want || (ok && got != 3) {
+				t.Errorf("get() = %d, %v, want cached %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthzVersionCacheSweepsExpiredEntries(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	cache := &authzVersionCache{entries: make(map[int]authzVersionEntry)}
+	for id := 1; id <= authzVersionSweepSize; id++ {
+		cache.set(id, 1, now)
+	}
+	cache.set(authzVersionSweepSize, 2, now.Add(authzVersionTTL-time.Second))
+
+	// The full cache is swept before the write, only the entry still within its TTL survives
+	cache.set(authzVersionSweepSize+1, 1, now.Add(authzVersionTTL))
+	if len(cache.entries) != 2 {
+		t.Errorf("entries = %d, want 2", len(cache.entries))
+	}
+	if version, ok := cache.get(authzVersionSweepSize, now.Add(authzVersionTTL)); !ok || version != 2 {
+		t.Errorf("get() = %d, %v, want the live entry kept", version, ok)
+	}
+}
+
+func TestGetAuthzVersionIsCached(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer conn.Close()
+	repo := NewUserRoleRepository(conn)
+	const userId = 21
+	authzVersions.forget(userId)
+
+	mock.ExpectQuery("SELECT authz_version FROM users").WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows([]string{"authz_version"}).AddRow(4))
+	for i := 0; i < 2; i++ {
+		version, err := repo.GetAuthzVersion(context.Background(), userId)
+		if err != nil || version != 4 {
+			t.Fatalf("GetAuthzVersion() = %d, %v, want 4", version, err)
+		}
+	}
+	// The second read was answered from the cache
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("queries: %v", err)
+	}
+}
+
+func TestAssignRoleForgetsAuthzVersion(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer conn.Close()
+	repo := NewUserRoleRepository(conn)
+	const userId = 22
+	now := time.Now()
+	authzVersions.set(userId, 4, now)
+	authzVersions.set(23, 1, now)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO user_roles").WithArgs(userId, 2).WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectExec("UPDATE users SET authz_version = authz_version \\+ 1").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if _, err := repo.AssignRole(context.Background(), userId, 2); err != nil {
+		t.Fatalf("AssignRole() error = %v", err)
+	}
+
+	if _, ok := authzVersions.get(userId, now); ok {
+		t.Errorf("authz version still cached after a role was assigned")
+	}
+	if _, ok := authzVersions.get(23, now); !ok {
+		t.Errorf("authz version of another user was forgotten")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("queries: %v", err)
+	}
+}
+
+func TestRoleChangesClearAuthzVersions(t *testing.T) {
+	tests := []struct {
+		name      string
+		expect    func(mock sqlmock.Sqlmock)
+		change    func(repo RoleRepository) error
+		wantClear bool
+	}{
+		{
+			name: "rename",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("FROM roles WHERE id = \\? FOR UPDATE").WithArgs(5).
+					WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(5, "judge", "", "2026-10-19 12:00:00", "2026-10-19 12:00:00", false))
+				mock.ExpectExec("UPDATE roles SET name").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE users SET authz_version = authz_version \\+ 1").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectQuery("FROM roles WHERE id = \\?$").WithArgs(5).
+					WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(5, "reviewer", "", "2026-10-19 12:00:00", "2026-10-19 12:00:00", false))
+				mock.ExpectCommit()
+			},
+			change: func(repo RoleRepository) error {
+				_, err := repo.UpdateRoleById(context.Background(), 5, "reviewer", "")
+				return err
+			},
+			wantClear: true,
+		},
+		{
+			// The members' tokens still carry the right name
+			name: "description only",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("FROM roles WHERE id = \\? FOR UPDATE").WithArgs(5).
+					WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(5, "judge", "", "2026-10-19 12:00:00", "2026-10-19 12:00:00", false))
+				mock.ExpectExec("UPDATE roles SET name").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("FROM roles WHERE id = \\?$").WithArgs(5).
+					WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(5, "judge", "Judges contests", "2026-10-19 12:00:00", "2026-10-19 12:00:00", false))
+				mock.ExpectCommit()
+			},
+			change: func(repo RoleRepository) error {
+				_, err := repo.UpdateRoleById(context.Background(), 5, "", "Judges contests")
+				return err
+			},
+		},
+		{
+			name: "delete",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id, is_system FROM roles").WithArgs(5).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "is_system"}).AddRow(5, false))
+				mock.ExpectExec("UPDATE users SET authz_version = authz_version \\+ 1").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec("DELETE FROM user_roles WHERE role_id").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec("DELETE FROM roles").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			change: func(repo RoleRepository) error {
+				_, err := repo.DeleteRoleById(context.Background(), 5, 0)
+				return err
+			},
+			wantClear: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer conn.Close()
+			now := time.Now()
+			authzVersions.set(24, 2, now)
+			authzVersions.set(25, 6, now)
+
+			tt.expect(mock)
+			if err := tt.change(NewRoleRepository(conn)); err != nil {
+				t.Fatalf("change error = %v", err)
+			}
+
+			// The members are not known here, every cached version is dropped
+			for _, userId := range []int{24, 25} {
+				if _, ok := authzVersions.get(userId, now); ok == tt.wantClear {
+					t.Errorf("user %d cached = %v, want cleared %v", userId, ok, tt.wantClear)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("queries: %v", err)
+			}
+		})
+	}
+}
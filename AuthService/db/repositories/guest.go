@@ -0,0 +1,141 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type GuestRepository interface {
+	Create(ctx context.Context, username string, email string, hashedPassword string) (*models.User, error)
+	Upgrade(ctx context.Context, userId int64, username string, email string, hashedPassword string) error
+}
+
+type GuestRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewGuestRepository(_db *sql.DB) GuestRepository {
+	return &GuestRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	ErrNotGuest = errors.New("account is not a guest account")
+)
+
+var (
+	createGuestQuery      = "INSERT INTO users (username, email, password, is_guest) VALUES (?, ?, ?, true)"
+	assignRoleByNameQuery = "INSERT INTO user_roles (user_id, role_id) SELECT ?, id FROM roles WHERE name = ?"
+	lockGuestQuery        = "SELECT is_guest FROM users WHERE id = ? AND is_deleted = false FOR UPDATE"
+	upgradeGuestQuery     = `
+		UPDATE users
+		SET username = ?, email = ?, password = ?, is_guest = false, authz_version = authz_version + 1, updated_at = NOW()
+		WHERE id = ?`
+	removeRoleByNameQuery = "DELETE FROM user_roles WHERE user_id = ? AND role_id = (SELECT id FROM roles WHERE name = ?)"
+)
+
+// Creates the user flagged as a guest together with its guest role
+func (r *GuestRepositoryImpl) Create(ctx context.Context, username string, email string, hashedPassword string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, createGuestQuery, username, email, hashedPassword)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	userId, err := result.LastInsertId()
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	if err := assignRoleByName(ctx, tx, userId, "guest"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return &models.User{
+		Id:       userId,
+		Username: username,
+		Email:    email,
+	}, nil
+}
+
+// Turns the guest into a regular user in place, so everything attached to the user id stays with the account.
+// The authz version is bumped because the roles change.
+func (r *GuestRepositoryImpl) Upgrade(ctx context.Context, userId int64, username string, email string, hashedPassword string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	var isGuest bool
+	if err := tx.QueryRowContext(ctx, lockGuestQuery, userId).Scan(&isGuest); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return ErrInternalServerError
+	}
+	if !isGuest {
+		return ErrNotGuest
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, emailExistsQuery, email).Scan(&exists); err != nil {
+		return ErrInternalServerError
+	}
+	if exists {
+		return ErrEmailTaken
+	}
+
+	if _, err := tx.ExecContext(ctx, upgradeGuestQuery, username, email, hashedPassword, userId); err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrUsernameTaken
+		}
+		return ErrInternalServerError
+	}
+	if _, err := tx.ExecContext(ctx, removeRoleByNameQuery, userId, "guest"); err != nil {
+		return ErrInternalServerError
+	}
+	if err := assignRoleByName(ctx, tx, userId, "user"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ErrInternalServerError
+	}
+	authzVersions.forget(int(userId))
+
+	return nil
+}
+
+func assignRoleByName(ctx context.Context, tx *sql.Tx, userId int64, roleName string) error {
+	result, err := tx.ExecContext(ctx, assignRoleByNameQuery, userId, roleName)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return ErrInternalServerError
+	}
+	// The role is created by a migration, a missing one is a broken deployment
+	if rowsAffected == 0 {
+		return ErrInternalServerError
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type ImpersonationRepository interface {
+	LogStart(ctx context.Context, impersonatorId int64, userId int64, reason string, ipAddress string) error
+	LogRequest(ctx context.Context, entry *models.ImpersonationLog) error
+	GetLogs(ctx context.Context, impersonatorId int64, userId int64, limit int) ([]*models.ImpersonationLog, error)
+}
+
+type ImpersonationRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewImpersonationRepository(_db *sql.DB) ImpersonationRepository {
+	return &ImpersonationRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	logImpersonationStartQuery   = "INSERT INTO impersonation_logs (impersonator_id, user_id, action, reason, ip_address) VALUES (?, ?, 'start', ?, ?)"
+	logImpersonationRequestQuery = "INSERT INTO impersonation_logs (impersonator_id, user_id, action, method, path, status_code, ip_address) VALUES (?, ?, 'request', ?, ?, ?, ?)"
+	// A zero id matches every impersonator or user
+	getImpersonationLogsQuery = `
+		SELECT id, impersonator_id, user_id, action, method, path, status_code, reason, ip_address, created_at
+		FROM impersonation_logs
+		WHERE (? = 0 OR impersonator_id = ?) AND (? = 0 OR user_id = ?)
+		ORDER BY id DESC
+		LIMIT ?`
+)
+
+func (r *ImpersonationRepositoryImpl) LogStart(ctx context.Context, impersonatorId int64, userId int64, reason string, ipAddress string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, logImpersonationStartQuery, impersonatorId, userId, reason, ipAddress); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (r *ImpersonationRepositoryImpl) LogRequest(ctx context.Context, entry *models.ImpersonationLog) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, logImpersonationRequestQuery, entry.ImpersonatorId, entry.UserId, entry.Method, entry.Path, entry.StatusCode, entry.IpAddress)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (r *ImpersonationRepositoryImpl) GetLogs(ctx context.Context, impersonatorId int64, userId int64, limit int) ([]*models.ImpersonationLog, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, getImpersonationLogsQuery, impersonatorId, impersonatorId, userId, userId, limit)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	logs := []*models.ImpersonationLog{}
+	for rows.Next() {
+		log := &models.ImpersonationLog{}
+		if err := rows.Scan(&log.Id, &log.ImpersonatorId, &log.UserId, &log.Action, &log.Method, &log.Path, &log.StatusCode, &log.Reason, &log.IpAddress, &log.CreatedAt); err != nil {
+			return nil, ErrInternalServerError
+		}
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return logs, nil
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *models.LoginAttempt) error
+	IsKnownDevice(ctx context.Context, userId int64, deviceLabel string, ipRange string) (bool, bool, error)
+	GetByUserId(ctx context.Context, userId int64, page int, limit int) ([]*models.LoginAttempt, int, error)
+}
+
+type LoginAttemptRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(_db *sql.DB) LoginAttemptRepository {
+	return &LoginAttemptRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	createLoginAttemptQuery = `
+		INSERT INTO login_attempts (user_id, email, outcome, ip_address, ip_range, user_agent, device_label, new_device)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	knownDeviceQuery = `
+		SELECT COUNT(*) > 0, COALESCE(SUM(device_label = ? AND ip_range = ?), 0) > 0
+		FROM login_attempts
+		WHERE user_id = ? AND outcome = 'success'`
+	countLoginAttemptsQuery = "SELECT COUNT(*) FROM login_attempts WHERE user_id = ?"
+	getLoginAttemptsQuery   = `
+		SELECT id, COALESCE(user_id, 0), email, outcome, ip_address, ip_range, user_agent, device_label, new_device, created_at
+		FROM login_attempts
+		WHERE user_id = ?
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`
+)
+
+func (r *LoginAttemptRepositoryImpl) Create(ctx context.Context, attempt *models.LoginAttempt) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// Attempts against unknown emails have no user
+	var userId sql.NullInt64
+	if attempt.UserId != 0 {
+		userId = sql.NullInt64{Int64: attempt.UserId, Valid: true}
+	}
+
+	_, err := r.db.ExecContext(ctx, createLoginAttemptQuery, userId, truncate(attempt.Email, 255), attempt.Outcome, attempt.IpAddress, attempt.IpRange, truncate(attempt.UserAgent, 512), attempt.DeviceLabel, attempt.NewDevice)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// Reports whether the user has logged in successfully before, and whether any of those logins came from this device and network
+func (r *LoginAttemptRepositoryImpl) IsKnownDevice(ctx context.Context, userId int64, deviceLabel string, ipRange string) (bool, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var hasLoggedIn, known bool
+	if err := r.db.QueryRowContext(ctx, knownDeviceQuery, deviceLabel, ipRange, userId).Scan(&hasLoggedIn, &known); err != nil {
+		return false, false, ErrInternalServerError
+	}
+	return hasLoggedIn, known, nil
+}
+
+func (r *LoginAttemptRepositoryImpl) GetByUserId(ctx context.Context, userId int64, page int, limit int) ([]*models.LoginAttempt, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, countLoginAttemptsQuery, userId).Scan(&total); err != nil {
+		return nil, 0, ErrInternalServerError
+	}
+
+	rows, err := r.db.QueryContext(ctx, getLoginAttemptsQuery, userId, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	attempts := []*models.LoginAttempt{}
+	for rows.Next() {
+		attempt := &models.LoginAttempt{}
+		if err := rows.Scan(&attempt.Id, &attempt.UserId, &attempt.Email, &attempt.Outcome, &attempt.IpAddress, &attempt.IpRange, &attempt.UserAgent, &attempt.DeviceLabel, &attempt.NewDevice, &attempt.CreatedAt); err != nil {
+			return nil, 0, ErrInternalServerError
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, ErrInternalServerError
+	}
+
+	return attempts, total, nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type MagicLinkRepository interface {
+	Create(ctx context.Context, tokenId string, userId int64, ipAddress string, ttl time.Duration) error
+	Consume(ctx context.Context, tokenId string, userId int64) error
+}
+
+type MagicLinkRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewMagicLinkRepository(_db *sql.DB) MagicLinkRepository {
+	return &MagicLinkRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	ErrMagicLinkUsed = errors.New("login link has already been used or has expired")
+)
+
+var (
+	createMagicLinkQuery  = "INSERT INTO magic_links (token_id, user_id, ip_address, expires_at) VALUES (?, ?, ?, DATE_ADD(NOW(), INTERVAL ? SECOND))"
+	consumeMagicLinkQuery = "UPDATE magic_links SET used_at = NOW() WHERE token_id = ? AND user_id = ? AND used_at IS NULL AND expires_at > NOW()"
+)
+
+func (r *MagicLinkRepositoryImpl) Create(ctx context.Context, tokenId string, userId int64, ipAddress string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, createMagicLinkQuery, tokenId, userId, ipAddress, int(ttl.Seconds())); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// Marks the link as used, a single UPDATE so two concurrent callbacks cannot both succeed
+func (r *MagicLinkRepositoryImpl) Consume(ctx context.Context, tokenId string, userId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, consumeMagicLinkQuery, tokenId, userId)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return ErrInternalServerError
+	}
+	if rowsAffected == 0 {
+		return ErrMagicLinkUsed
+	}
+
+	return nil
+}
@@ -0,0 +1,430 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, name string, slug string, ownerId int64) (*models.Organization, error)
+	GetOrganizationById(ctx context.Context, id int64) (*models.Organization, error)
+	GetUserOrganizations(ctx context.Context, userId int64) ([]*models.Organization, error)
+	GetMembershipRole(ctx context.Context, orgId int64, userId int64) (string, error)
+	GetMembers(ctx context.Context, orgId int64) ([]*models.OrgMember, error)
+	UpdateMemberRole(ctx context.Context, orgId int64, userId int64, role string) (bool, error)
+	RemoveMember(ctx context.Context, orgId int64, userId int64) (bool, error)
+	CreateInvitation(ctx context.Context, orgId int64, email string, role string, tokenHash string, invitedBy int64, ttl time.Duration) (*models.OrgInvitation, error)
+	GetPendingInvitations(ctx context.Context, orgId int64) ([]*models.OrgInvitation, error)
+	AcceptInvitation(ctx context.Context, tokenHash string, userId int64, email string) (*models.OrgInvitation, error)
+}
+
+type OrganizationRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewOrganizationRepository(_db *sql.DB) OrganizationRepository {
+	return &OrganizationRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	ErrOrgNotFound        = errors.New("organization not found")
+	ErrOrgSlugTaken       = errors.New("organization slug already taken")
+	ErrNotOrgMember       = errors.New("user is not a member of this organization")
+	ErrLastOrgOwner       = errors.New("the last owner cannot leave or be demoted")
+	ErrInvitationNotFound = errors.New("invitation not found or expired")
+)
+
+var (
+	createOrganizationQuery   = "INSERT INTO organizations (name, slug, created_by) VALUES (?, ?, ?)"
+	addOrgMemberQuery         = "INSERT INTO org_memberships (org_id, user_id, role) VALUES (?, ?, ?)"
+	getOrganizationByIdQuery  = "SELECT id, name, slug, created_by, created_at, updated_at FROM organizations WHERE id = ?"
+	getUserOrganizationsQuery = `
+		SELECT o.id, o.name, o.slug, o.created_by, o.created_at, o.updated_at, m.role
+		FROM org_memberships m
+		INNER JOIN organizations o ON m.org_id = o.id
+		WHERE m.user_id = ?
+		ORDER BY o.name`
+	getMembershipRoleQuery = "SELECT role FROM org_memberships WHERE org_id = ? AND user_id = ?"
+	getOrgMembersQuery     = `
+		SELECT m.org_id, u.id, u.username, u.email, m.role, m.created_at
+		FROM org_memberships m
+		INNER JOIN users u ON m.user_id = u.id
+		WHERE m.org_id = ?
+		ORDER BY m.created_at`
+	lockOrgOwnersQuery         = "SELECT user_id FROM org_memberships WHERE org_id = ? AND role = 'owner' FOR UPDATE"
+	updateOrgMemberRoleQuery   = "UPDATE org_memberships SET role = ? WHERE org_id = ? AND user_id = ?"
+	removeOrgMemberQuery       = "DELETE FROM org_memberships WHERE org_id = ? AND user_id = ?"
+	createOrgInvitationQuery   = "INSERT INTO org_invitations (org_id, email, role, token_hash, invited_by, expires_at) VALUES (?, ?, ?, ?, ?, DATE_ADD(NOW(), INTERVAL ? SECOND))"
+	getOrgInvitationByIdQuery  = "SELECT id, org_id, email, role, invited_by, expires_at, COALESCE(accepted_at, ''), created_at FROM org_invitations WHERE id = ?"
+	getPendingInvitationsQuery = `
+		SELECT id, org_id, email, role, invited_by, expires_at, COALESCE(accepted_at, ''), created_at
+		FROM org_invitations
+		WHERE org_id = ? AND accepted_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC`
+	lockPendingInvitationQuery = `
+		SELECT id, org_id, email, role, invited_by, expires_at, COALESCE(accepted_at, ''), created_at
+		FROM org_invitations
+		WHERE token_hash = ? AND accepted_at IS NULL AND expires_at > NOW()
+		FOR UPDATE`
+	acceptOrgInvitationQuery = "UPDATE org_invitations SET accepted_at = NOW() WHERE id = ?"
+	// Accepting an invitation never downgrades an existing membership
+	upsertOrgMemberQuery = `
+		INSERT INTO org_memberships (org_id, user_id, role) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = IF(role = 'member', VALUES(role), role)`
+)
+
+func (r *OrganizationRepositoryImpl) CreateOrganization(ctx context.Context, name string, slug string, ownerId int64) (*models.Organization, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, createOrganizationQuery, name, slug, ownerId)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrOrgSlugTaken
+		}
+		return nil, ErrInternalServerError
+	}
+	orgId, err := result.LastInsertId()
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	if _, err := tx.ExecContext(ctx, addOrgMemberQuery, orgId, ownerId, "owner"); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	org := &models.Organization{}
+	if err := tx.QueryRowContext(ctx, getOrganizationByIdQuery, orgId).Scan(&org.Id, &org.Name, &org.Slug, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt); err != nil {
+		return nil, ErrInternalServerError
+	}
+	org.Role = "owner"
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return org, nil
+}
+
+func (r *OrganizationRepositoryImpl) GetOrganizationById(ctx context.Context, id int64) (*models.Organization, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	org := &models.Organization{}
+	err := r.db.QueryRowContext(ctx, getOrganizationByIdQuery, id).Scan(&org.Id, &org.Name, &org.Slug, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrgNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+
+	return org, nil
+}
+
+func (r *OrganizationRepositoryImpl) GetUserOrganizations(ctx context.Context, userId int64) ([]*models.Organization, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, getUserOrganizationsQuery, userId)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	orgs := []*models.Organization{}
+	for rows.Next() {
+		org := &models.Organization{}
+		if err := rows.Scan(&org.Id, &org.Name, &org.Slug, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt, &org.Role); err != nil {
+			return nil, ErrInternalServerError
+		}
+		orgs = append(orgs, org)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return orgs, nil
+}
+
+func (r *OrganizationRepositoryImpl) GetMembershipRole(ctx context.Context, orgId int64, userId int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var role string
+	if err := r.db.QueryRowContext(ctx, getMembershipRoleQuery, orgId, userId).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotOrgMember
+		}
+		return "", ErrInternalServerError
+	}
+
+	return role, nil
+}
+
+func (r *OrganizationRepositoryImpl) GetMembers(ctx context.Context, orgId int64) ([]*models.OrgMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, getOrgMembersQuery, orgId)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	members := []*models.OrgMember{}
+	for rows.Next() {
+		member := &models.OrgMember{}
+		if err := rows.Scan(&member.OrgId, &member.UserId, &member.Username, &member.Email, &member.Role, &member.JoinedAt); err != nil {
+			return nil, ErrInternalServerError
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return members, nil
+}
+
+func (r *OrganizationRepositoryImpl) UpdateMemberRole(ctx context.Context, orgId int64, userId int64, role string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	if role != "owner" {
+		if err := ensureNotLastOrgOwner(ctx, tx, orgId, userId); err != nil {
+			return false, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, updateOrgMemberRoleQuery, role, orgId, userId)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	if err := requireMembershipChange(ctx, tx, result, orgId, userId); err != nil {
+		return false, err
+	}
+
+	// Tokens carrying the old org role must be re-evaluated
+	if _, err := tx.ExecContext(ctx, bumpAuthzVersionQuery, userId); err != nil {
+		return false, ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, ErrInternalServerError
+	}
+	authzVersions.forget(int(userId))
+
+	return true, nil
+}
+
+func (r *OrganizationRepositoryImpl) RemoveMember(ctx context.Context, orgId int64, userId int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	if err := ensureNotLastOrgOwner(ctx, tx, orgId, userId); err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(ctx, removeOrgMemberQuery, orgId, userId)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	if rowsAffected == 0 {
+		return false, ErrNotOrgMember
+	}
+
+	if _, err := tx.ExecContext(ctx, bumpAuthzVersionQuery, userId); err != nil {
+		return false, ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, ErrInternalServerError
+	}
+	authzVersions.forget(int(userId))
+
+	return true, nil
+}
+
+func (r *OrganizationRepositoryImpl) CreateInvitation(ctx context.Context, orgId int64, email string, role string, tokenHash string, invitedBy int64, ttl time.Duration) (*models.OrgInvitation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, createOrgInvitationQuery, orgId, email, role, tokenHash, invitedBy, int(ttl.Seconds()))
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	invitationId, err := result.LastInsertId()
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	invitation := &models.OrgInvitation{}
+	if err := scanOrgInvitation(r.db.QueryRowContext(ctx, getOrgInvitationByIdQuery, invitationId), invitation); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return invitation, nil
+}
+
+func (r *OrganizationRepositoryImpl) GetPendingInvitations(ctx context.Context, orgId int64) ([]*models.OrgInvitation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, getPendingInvitationsQuery, orgId)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	invitations := []*models.OrgInvitation{}
+	for rows.Next() {
+		invitation := &models.OrgInvitation{}
+		if err := scanOrgInvitation(rows, invitation); err != nil {
+			return nil, ErrInternalServerError
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return invitations, nil
+}
+
+// Adds the user to the organization, the invitation must be pending and addressed to their email
+func (r *OrganizationRepositoryImpl) AcceptInvitation(ctx context.Context, tokenHash string, userId int64, email string) (*models.OrgInvitation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	invitation := &models.OrgInvitation{}
+	if err := scanOrgInvitation(tx.QueryRowContext(ctx, lockPendingInvitationQuery, tokenHash), invitation); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	if !strings.EqualFold(invitation.Email, email) {
+		return nil, ErrInvitationNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, upsertOrgMemberQuery, invitation.OrgId, userId, invitation.Role); err != nil {
+		return nil, ErrInternalServerError
+	}
+	if _, err := tx.ExecContext(ctx, acceptOrgInvitationQuery, invitation.Id); err != nil {
+		return nil, ErrInternalServerError
+	}
+	if _, err := tx.ExecContext(ctx, bumpAuthzVersionQuery, userId); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrInternalServerError
+	}
+	authzVersions.forget(int(userId))
+
+	return invitation, nil
+}
+
+// Refuses to demote or remove the only owner of an organization
+func ensureNotLastOrgOwner(ctx context.Context, tx *sql.Tx, orgId int64, userId int64) error {
+	rows, err := tx.QueryContext(ctx, lockOrgOwnersQuery, orgId)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	defer rows.Close()
+
+	isOwner := false
+	otherOwners := 0
+	for rows.Next() {
+		var ownerId int64
+		if err := rows.Scan(&ownerId); err != nil {
+			return ErrInternalServerError
+		}
+		if ownerId == userId {
+			isOwner = true
+		} else {
+			otherOwners++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return ErrInternalServerError
+	}
+
+	if isOwner && otherOwners == 0 {
+		return ErrLastOrgOwner
+	}
+	return nil
+}
+
+// MySQL reports zero affected rows when the value is unchanged, so tell that apart from a missing member
+func requireMembershipChange(ctx context.Context, tx *sql.Tx, result sql.Result, orgId int64, userId int64) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return ErrInternalServerError
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var role string
+	if err := tx.QueryRowContext(ctx, getMembershipRoleQuery, orgId, userId).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotOrgMember
+		}
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrgInvitation(row rowScanner, invitation *models.OrgInvitation) error {
+	return row.Scan(&invitation.Id, &invitation.OrgId, &invitation.Email, &invitation.Role, &invitation.InvitedBy, &invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.CreatedAt)
+}
+
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
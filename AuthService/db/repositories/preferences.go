@@ -0,0 +1,90 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type PreferencesRepository interface {
+	GetByUserId(ctx context.Context, userId int64) (*models.UserPreferences, error)
+	Save(ctx context.Context, userId int64, preferences models.Preferences, expectedVersion int) error
+}
+
+type PreferencesRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewPreferencesRepository(_db *sql.DB) PreferencesRepository {
+	return &PreferencesRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	ErrPreferencesNotFound        = errors.New("preferences not found")
+	ErrPreferencesVersionConflict = errors.New("preferences were changed by another session")
+)
+
+var (
+	getPreferencesQuery    = "SELECT document, version, updated_at FROM user_preferences WHERE user_id = ?"
+	insertPreferencesQuery = "INSERT INTO user_preferences (user_id, document, version) VALUES (?, ?, 1)"
+	updatePreferencesQuery = "UPDATE user_preferences SET document = ?, version = version + 1 WHERE user_id = ? AND version = ?"
+)
+
+// Keys missing from the stored document keep the zero value, the service fills in defaults
+func (r *PreferencesRepositoryImpl) GetByUserId(ctx context.Context, userId int64) (*models.UserPreferences, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var document []byte
+	preferences := &models.UserPreferences{}
+	if err := r.db.QueryRowContext(ctx, getPreferencesQuery, userId).Scan(&document, &preferences.Version, &preferences.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPreferencesNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	if err := json.Unmarshal(document, &preferences.Preferences); err != nil {
+		return nil, ErrInternalServerError
+	}
+	return preferences, nil
+}
+
+// Writes only when the stored version still is expectedVersion, zero meaning nothing is stored yet.
+// A concurrent write from another session makes this fail with ErrPreferencesVersionConflict.
+func (r *PreferencesRepositoryImpl) Save(ctx context.Context, userId int64, preferences models.Preferences, expectedVersion int) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	document, err := json.Marshal(preferences)
+	if err != nil {
+		return ErrInternalServerError
+	}
+
+	if expectedVersion == 0 {
+		if _, err := r.db.ExecContext(ctx, insertPreferencesQuery, userId, string(document)); err != nil {
+			if isDuplicateKeyError(err) {
+				return ErrPreferencesVersionConflict
+			}
+			return ErrInternalServerError
+		}
+		return nil
+	}
+
+	result, err := r.db.ExecContext(ctx, updatePreferencesQuery, string(document), userId, expectedVersion)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return ErrInternalServerError
+	}
+	if rowsAffected == 0 {
+		return ErrPreferencesVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type ProfileRepository interface {
+	GetByUsername(ctx context.Context, username string) (*models.UserProfile, error)
+	GetByUserId(ctx context.Context, userId int64) (*models.UserProfile, error)
+	Upsert(ctx context.Context, profile *models.UserProfile) error
+	SetAvatarKey(ctx context.Context, userId int64, avatarKey string) (string, error)
+}
+
+type ProfileRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewProfileRepository(_db *sql.DB) ProfileRepository {
+	return &ProfileRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+)
+
+var (
+	// Profile rows are only written on the first edit, until then the column defaults apply
+	profileColumns = `
+		SELECT u.id, u.username, COALESCE(p.display_name, ''), COALESCE(p.bio, ''), COALESCE(p.avatar_key, ''), u.created_at,
+			COALESCE(p.display_name_public, true), COALESCE(p.bio_public, true), COALESCE(p.avatar_public, true),
+			COALESCE(p.join_date_public, true), COALESCE(p.stats_public, true)
+		FROM users u
+		LEFT JOIN user_profiles p ON p.user_id = u.id`
+	// Deleted and guest accounts have no public profile
+	getProfileByUsernameQuery = profileColumns + " WHERE u.username = ? AND COALESCE(u.is_deleted, false) = false AND u.is_guest = false"
+	getProfileByUserIdQuery   = profileColumns + " WHERE u.id = ?"
+	upsertProfileQuery        = `
+		INSERT INTO user_profiles (user_id, display_name, bio, display_name_public, bio_public, avatar_public, join_date_public, stats_public)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			display_name = VALUES(display_name), bio = VALUES(bio),
+			display_name_public = VALUES(display_name_public), bio_public = VALUES(bio_public), avatar_public = VALUES(avatar_public),
+			join_date_public = VALUES(join_date_public), stats_public = VALUES(stats_public)`
+	lockAvatarKeyQuery = "SELECT COALESCE(avatar_key, '') FROM user_profiles WHERE user_id = ? FOR UPDATE"
+	setAvatarKeyQuery  = `
+		INSERT INTO user_profiles (user_id, avatar_key) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE avatar_key = VALUES(avatar_key)`
+)
+
+func (r *ProfileRepositoryImpl) GetByUsername(ctx context.Context, username string) (*models.UserProfile, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	return scanProfile(r.db.QueryRowContext(ctx, getProfileByUsernameQuery, username))
+}
+
+func (r *ProfileRepositoryImpl) GetByUserId(ctx context.Context, userId int64) (*models.UserProfile, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	return scanProfile(r.db.QueryRowContext(ctx, getProfileByUserIdQuery, userId))
+}
+
+func (r *ProfileRepositoryImpl) Upsert(ctx context.Context, profile *models.UserProfile) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	privacy := profile.Privacy
+	_, err := r.db.ExecContext(ctx, upsertProfileQuery, profile.UserId, profile.DisplayName, profile.Bio,
+		privacy.DisplayName, privacy.Bio, privacy.Avatar, privacy.JoinDate, privacy.Stats)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// Points the profile at a new set of avatar thumbnails, an empty key removes the avatar.
+// Returns the previous key so its files can be deleted.
+func (r *ProfileRepositoryImpl) SetAvatarKey(ctx context.Context, userId int64, avatarKey string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	var previous string
+	if err := tx.QueryRowContext(ctx, lockAvatarKeyQuery, userId).Scan(&previous); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInternalServerError
+	}
+
+	var key any
+	if avatarKey != "" {
+		key = avatarKey
+	}
+	if _, err := tx.ExecContext(ctx, setAvatarKeyQuery, userId, key); err != nil {
+		return "", ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", ErrInternalServerError
+	}
+	return previous, nil
+}
+
+func scanProfile(row *sql.Row) (*models.UserProfile, error) {
+	profile := &models.UserProfile{}
+	privacy := &profile.Privacy
+	err := row.Scan(&profile.UserId, &profile.Username, &profile.DisplayName, &profile.Bio, &profile.AvatarKey, &profile.JoinedAt,
+		&privacy.DisplayName, &privacy.Bio, &privacy.Avatar, &privacy.JoinDate, &privacy.Stats)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	return profile, nil
+}
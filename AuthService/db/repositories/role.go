@@ -14,6 +14,8 @@ type RoleRepository interface {
 	GetAllRoles(ctx context.Context) ([]*models.Role, error)
 	CreateRole(ctx context.Context, name string, description string) (*models.Role, error)
 	UpdateRoleById(ctx context.Context, id int, name string, description string) (*models.Role, error)
+	DeleteRoleById(ctx context.Context, id int, reassignToId int) (bool, error)
+	GetRoleMembers(ctx context.Context, id int) ([]*models.RoleMember, error)
 }
 
 type RoleRepositoryImpl struct {
@@ -27,15 +29,36 @@ func NewRoleRepository(_db *sql.DB) RoleRepository {
 }
 
 var (
-	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleNotFound    = errors.New("role not found")
+	ErrSystemRole      = errors.New("system roles cannot be renamed or deleted")
+	ErrInvalidReassign = errors.New("members cannot be reassigned to the role being deleted")
 )
 
 var (
 	createRoleQuery     = "INSERT INTO roles (name,description,created_at) VALUES (?,?,NOW())"
 	updateRoleByIdQuery = "UPDATE roles SET name = CASE WHEN ? <> '' THEN ? ELSE name END, description = CASE WHEN ? <> '' THEN ? ELSE description END, updated_at = NOW() WHERE id = ?"
-	getRoleByIdQuery    = "SELECT id, name, description, created_at, updated_at FROM roles WHERE id = ?"
-	getRoleByNameQuery  = "SELECT id, name, description, created_at, updated_at  FROM roles WHERE name = ?"
-	getAllRolesQuery    = "SELECT id, name, description, created_at, updated_at FROM roles"
+	getRoleByIdQuery    = "SELECT id, name, description, created_at, updated_at, is_system FROM roles WHERE id = ?"
+	lockRoleQuery       = "SELECT id, name, description, created_at, updated_at, is_system FROM roles WHERE id = ? FOR UPDATE"
+	getRoleByNameQuery  = "SELECT id, name, description, created_at, updated_at, is_system FROM roles WHERE name = ?"
+	getAllRolesQuery    = "SELECT id, name, description, created_at, updated_at, is_system FROM roles"
+	roleExistsQuery     = "SELECT COUNT(*) > 0 FROM roles WHERE id = ?"
+	lockRoleByIdQuery   = "SELECT id, is_system FROM roles WHERE id = ? FOR UPDATE"
+	deleteRoleByIdQuery = "DELETE FROM roles WHERE id = ?"
+	getRoleMembersQuery = `
+		SELECT ur.id, u.id, u.username, u.email, ur.created_at
+		FROM user_roles ur
+		INNER JOIN users u ON ur.user_id = u.id
+		WHERE ur.role_id = ?
+		ORDER BY ur.id`
+	bumpRoleMembersAuthzVersionQuery = `
+		UPDATE users SET authz_version = authz_version + 1
+		WHERE id IN (SELECT user_id FROM user_roles WHERE role_id = ?)`
+	reassignRoleMembersQuery = `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT ur.user_id, ? FROM user_roles ur
+		WHERE ur.role_id = ?
+		AND NOT EXISTS (SELECT 1 FROM user_roles existing WHERE existing.user_id = ur.user_id AND existing.role_id = ?)`
+	deleteRoleMembersQuery = "DELETE FROM user_roles WHERE role_id = ?"
 )
 
 func (r *RoleRepositoryImpl) CreateRole(ctx context.Context, name string, description string) (*models.Role, error) {
@@ -53,7 +76,7 @@ func (r *RoleRepositoryImpl) CreateRole(ctx context.Context, name string, descri
 	}
 
 	var role models.Role
-	err = r.db.QueryRowContext(ctx, getRoleByIdQuery, lastInsertedId).Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt)
+	err = r.db.QueryRowContext(ctx, getRoleByIdQuery, lastInsertedId).Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt, &role.IsSystem)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRoleNotFound
@@ -68,8 +91,14 @@ func (r *RoleRepositoryImpl) UpdateRoleById(ctx context.Context, id int, name st
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
 	var existingRole models.Role
-	err := r.db.QueryRowContext(ctx, getRoleByIdQuery, id).Scan(&existingRole.Id, &existingRole.Name, &existingRole.Description, &existingRole.CreatedAt, &existingRole.UpdatedAt)
+	err = tx.QueryRowContext(ctx, lockRoleQuery, id).Scan(&existingRole.Id, &existingRole.Name, &existingRole.Description, &existingRole.CreatedAt, &existingRole.UpdatedAt, &existingRole.IsSystem)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRoleNotFound
@@ -77,7 +106,12 @@ func (r *RoleRepositoryImpl) UpdateRoleById(ctx context.Context, id int, name st
 		return nil, ErrInternalServerError
 	}
 
-	result, err := r.db.ExecContext(ctx, updateRoleByIdQuery, name, name, description, description, id)
+	// Policies and middlewares refer to system roles by name
+	if existingRole.IsSystem && name != "" && name != existingRole.Name {
+		return nil, ErrSystemRole
+	}
+
+	result, err := tx.ExecContext(ctx, updateRoleByIdQuery, name, name, description, description, id)
 	if err != nil {
 		return nil, ErrInternalServerError
 	}
@@ -89,8 +123,16 @@ func (r *RoleRepositoryImpl) UpdateRoleById(ctx context.Context, id int, name st
 		return nil, ErrRoleNotFound
 	}
 
+	// Tokens of the members carry the old name
+	renamed := name != "" && name != existingRole.Name
+	if renamed {
+		if _, err := tx.ExecContext(ctx, bumpRoleMembersAuthzVersionQuery, id); err != nil {
+			return nil, ErrInternalServerError
+		}
+	}
+
 	var role models.Role
-	err = r.db.QueryRowContext(ctx, getRoleByIdQuery, id).Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt)
+	err = tx.QueryRowContext(ctx, getRoleByIdQuery, id).Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt, &role.IsSystem)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRoleNotFound
@@ -98,6 +140,13 @@ func (r *RoleRepositoryImpl) UpdateRoleById(ctx context.Context, id int, name st
 		return nil, ErrInternalServerError
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, ErrInternalServerError
+	}
+	if renamed {
+		authzVersions.clear()
+	}
+
 	return &role, nil
 }
 
@@ -108,7 +157,7 @@ func (r *RoleRepositoryImpl) GetRoleById(ctx context.Context, id int) (*models.R
 	row := r.db.QueryRowContext(ctx, getRoleByIdQuery, id)
 
 	role := &models.Role{}
-	if err := row.Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt); err != nil {
+	if err := row.Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt, &role.IsSystem); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRoleNotFound
 		}
@@ -124,7 +173,7 @@ func (r *RoleRepositoryImpl) GetRoleByName(ctx context.Context, name string) (*m
 
 	row := r.db.QueryRowContext(ctx, getRoleByNameQuery, name)
 	role := &models.Role{}
-	if err := row.Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt); err != nil {
+	if err := row.Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt, &role.IsSystem); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRoleNotFound
 		}
@@ -147,7 +196,7 @@ func (r *RoleRepositoryImpl) GetAllRoles(ctx context.Context) ([]*models.Role, e
 	roles := []*models.Role{}
 	for rows.Next() {
 		role := &models.Role{}
-		if scanErr := rows.Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt); scanErr != nil {
+		if scanErr := rows.Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt, &role.IsSystem); scanErr != nil {
 			return nil, ErrInternalServerError
 		}
 		roles = append(roles, role)
@@ -159,3 +208,96 @@ func (r *RoleRepositoryImpl) GetAllRoles(ctx context.Context) ([]*models.Role, e
 
 	return roles, nil
 }
+
+// Deletes a non-system role, moving its members to reassignToId first when it is set
+func (r *RoleRepositoryImpl) DeleteRoleById(ctx context.Context, id int, reassignToId int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if reassignToId == id {
+		return false, ErrInvalidReassign
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	var roleId int
+	var isSystem bool
+	if err := tx.QueryRowContext(ctx, lockRoleByIdQuery, id).Scan(&roleId, &isSystem); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrRoleNotFound
+		}
+		return false, ErrInternalServerError
+	}
+	if isSystem {
+		return false, ErrSystemRole
+	}
+
+	if reassignToId != 0 {
+		var targetId int
+		var targetIsSystem bool
+		if err := tx.QueryRowContext(ctx, lockRoleByIdQuery, reassignToId).Scan(&targetId, &targetIsSystem); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return false, ErrRoleNotFound
+			}
+			return false, ErrInternalServerError
+		}
+		if _, err := tx.ExecContext(ctx, reassignRoleMembersQuery, reassignToId, id, reassignToId); err != nil {
+			return false, ErrInternalServerError
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, bumpRoleMembersAuthzVersionQuery, id); err != nil {
+		return false, ErrInternalServerError
+	}
+	if _, err := tx.ExecContext(ctx, deleteRoleMembersQuery, id); err != nil {
+		return false, ErrInternalServerError
+	}
+	if _, err := tx.ExecContext(ctx, deleteRoleByIdQuery, id); err != nil {
+		return false, ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, ErrInternalServerError
+	}
+	authzVersions.clear()
+
+	return true, nil
+}
+
+func (r *RoleRepositoryImpl) GetRoleMembers(ctx context.Context, id int) ([]*models.RoleMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, roleExistsQuery, id).Scan(&exists); err != nil {
+		return nil, ErrInternalServerError
+	}
+	if !exists {
+		return nil, ErrRoleNotFound
+	}
+
+	rows, err := r.db.QueryContext(ctx, getRoleMembersQuery, id)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	members := []*models.RoleMember{}
+	for rows.Next() {
+		member := &models.RoleMember{}
+		if err := rows.Scan(&member.UserRoleId, &member.UserId, &member.Username, &member.Email, &member.AssignedAt); err != nil {
+			return nil, ErrInternalServerError
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return members, nil
+}
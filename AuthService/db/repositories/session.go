@@ -0,0 +1,163 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, userId int64, tokenId string, deviceLabel string, userAgent string, ipAddress string) error
+	Touch(ctx context.Context, userId int64, tokenId string, ipAddress string) error
+	GetActiveByUserId(ctx context.Context, userId int64) ([]*models.UserSession, error)
+	Revoke(ctx context.Context, userId int64, sessionId int64) (bool, error)
+	RevokeByTokenId(ctx context.Context, tokenId string) error
+	RevokeAllExcept(ctx context.Context, userId int64, keepTokenId string) (int64, error)
+}
+
+type SessionRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(_db *sql.DB) SessionRepository {
+	return &SessionRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has been revoked")
+)
+
+var (
+	createSessionQuery    = "INSERT INTO user_sessions (user_id, token_id, device_label, user_agent, ip_address) VALUES (?, ?, ?, ?, ?)"
+	getSessionStatusQuery = "SELECT revoked_at IS NOT NULL FROM user_sessions WHERE token_id = ? AND user_id = ?"
+	// last_seen_at is only written once a minute so busy clients don't turn every request into a write
+	touchSessionQuery = `
+		UPDATE user_sessions SET last_seen_at = NOW(), ip_address = ?
+		WHERE token_id = ? AND revoked_at IS NULL AND last_seen_at < NOW() - INTERVAL 60 SECOND`
+	getActiveSessionsQuery = `
+		SELECT id, user_id, token_id, device_label, user_agent, ip_address, created_at, last_seen_at, COALESCE(revoked_at, '')
+		FROM user_sessions
+		WHERE user_id = ? AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC`
+	revokeSessionQuery          = "UPDATE user_sessions SET revoked_at = NOW() WHERE id = ? AND user_id = ? AND revoked_at IS NULL"
+	revokeSessionByTokenIdQuery = "UPDATE user_sessions SET revoked_at = NOW() WHERE token_id = ? AND revoked_at IS NULL"
+	revokeOtherSessionsQuery    = "UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = ? AND token_id <> ? AND revoked_at IS NULL"
+)
+
+func (r *SessionRepositoryImpl) Create(ctx context.Context, userId int64, tokenId string, deviceLabel string, userAgent string, ipAddress string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, createSessionQuery, userId, tokenId, deviceLabel, truncate(userAgent, 512), ipAddress); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// Checks that the session is still active and records that it was seen
+func (r *SessionRepositoryImpl) Touch(ctx context.Context, userId int64, tokenId string, ipAddress string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var revoked bool
+	if err := r.db.QueryRowContext(ctx, getSessionStatusQuery, tokenId, userId).Scan(&revoked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return ErrInternalServerError
+	}
+	if revoked {
+		return ErrSessionRevoked
+	}
+
+	if _, err := r.db.ExecContext(ctx, touchSessionQuery, ipAddress, tokenId); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (r *SessionRepositoryImpl) GetActiveByUserId(ctx context.Context, userId int64) ([]*models.UserSession, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, getActiveSessionsQuery, userId)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	sessions := []*models.UserSession{}
+	for rows.Next() {
+		session := &models.UserSession{}
+		if err := rows.Scan(&session.Id, &session.UserId, &session.TokenId, &session.DeviceLabel, &session.UserAgent, &session.IpAddress, &session.CreatedAt, &session.LastSeenAt, &session.RevokedAt); err != nil {
+			return nil, ErrInternalServerError
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return sessions, nil
+}
+
+// Revokes one of the user's own sessions, sessions of other users are reported as not found
+func (r *SessionRepositoryImpl) Revoke(ctx context.Context, userId int64, sessionId int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, revokeSessionQuery, sessionId, userId)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	if rowsAffected == 0 {
+		return false, ErrSessionNotFound
+	}
+
+	return true, nil
+}
+
+func (r *SessionRepositoryImpl) RevokeByTokenId(ctx context.Context, tokenId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, revokeSessionByTokenIdQuery, tokenId); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (r *SessionRepositoryImpl) RevokeAllExcept(ctx context.Context, userId int64, keepTokenId string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, revokeOtherSessionsQuery, userId, keepTokenId)
+	if err != nil {
+		return 0, ErrInternalServerError
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, ErrInternalServerError
+	}
+
+	return rowsAffected, nil
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	// Cutting inside a multi-byte character would be rejected by MySQL
+	return strings.ToValidUTF8(value[:max], "")
+}
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type SuspensionRepository interface {
+	Create(ctx context.Context, userId int64, scope string, reason string, createdBy int64, duration time.Duration) (*models.Suspension, error)
+	GetById(ctx context.Context, id int64) (*models.Suspension, error)
+	GetActiveByUserId(ctx context.Context, userId int64, authzVersion int) ([]*models.Suspension, error)
+	GetAll(ctx context.Context, userId int64, activeOnly bool, page int, limit int) ([]*models.Suspension, int, error)
+	Lift(ctx context.Context, id int64, liftedBy int64) error
+}
+
+type SuspensionRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewSuspensionRepository(_db *sql.DB) SuspensionRepository {
+	return &SuspensionRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	ErrSuspensionNotFound = errors.New("suspension not found or already lifted")
+)
+
+var (
+	// A zero duration stores no expiry, the suspension lasts until it is lifted
+	createSuspensionQuery = `
+		INSERT INTO user_suspensions (user_id, scope, reason, created_by, expires_at)
+		VALUES (?, ?, ?, ?, IF(? = 0, NULL, DATE_ADD(NOW(), INTERVAL ? SECOND)))`
+	suspensionColumns = `
+		SELECT id, user_id, scope, reason, created_by, COALESCE(expires_at, ''), COALESCE(lifted_at, ''), COALESCE(lifted_by, 0), created_at
+		FROM user_suspensions`
+	activeSuspensionCondition = "lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())"
+
+	getSuspensionByIdQuery    = suspensionColumns + " WHERE id = ?"
+	getActiveSuspensionsQuery = suspensionColumns + " WHERE user_id = ? AND " + activeSuspensionCondition + " ORDER BY id DESC"
+	suspensionFilterCondition = " WHERE (? = 0 OR user_id = ?) AND (? = false OR (" + activeSuspensionCondition + "))"
+	countSuspensionsQuery     = "SELECT COUNT(*) FROM user_suspensions" + suspensionFilterCondition
+	getSuspensionsQuery       = suspensionColumns + suspensionFilterCondition + " ORDER BY id DESC LIMIT ? OFFSET ?"
+	liftSuspensionQuery       = "UPDATE user_suspensions SET lifted_at = NOW(), lifted_by = ? WHERE id = ? AND " + activeSuspensionCondition
+	getSuspensionUserQuery    = "SELECT user_id FROM user_suspensions WHERE id = ? FOR UPDATE"
+	// Seconds until the first active suspension of the user runs out, NULL when none does
+	getSuspensionsEndQuery = "SELECT TIMESTAMPDIFF(SECOND, NOW(), MIN(expires_at)) FROM user_suspensions WHERE user_id = ? AND " + activeSuspensionCondition
+)
+
+func (r *SuspensionRepositoryImpl) Create(ctx context.Context, userId int64, scope string, reason string, createdBy int64, duration time.Duration) (*models.Suspension, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	seconds := int64(duration.Seconds())
+	result, err := tx.ExecContext(ctx, createSuspensionQuery, userId, scope, reason, createdBy, seconds, seconds)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	// Cached suspensions are kept per authz version, the bump makes every replica load them again
+	if _, err := tx.ExecContext(ctx, bumpAuthzVersionQuery, userId); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrInternalServerError
+	}
+	authzVersions.forget(int(userId))
+	activeSuspensions.forget(userId)
+
+	return r.GetById(ctx, id)
+}
+
+func (r *SuspensionRepositoryImpl) GetById(ctx context.Context, id int64) (*models.Suspension, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	suspension, err := scanSuspension(r.db.QueryRowContext(ctx, getSuspensionByIdQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSuspensionNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	return suspension, nil
+}
+
+// Suspensions that are neither lifted nor expired, checked on every authenticated request.
+// Served from a cache for the user's current authz version, see suspensionCache.
+func (r *SuspensionRepositoryImpl) GetActiveByUserId(ctx context.Context, userId int64, authzVersion int) ([]*models.Suspension, error) {
+	now := time.Now()
+	if suspensions, ok := activeSuspensions.get(userId, authzVersion, now); ok {
+		return suspensions, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, getActiveSuspensionsQuery, userId)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	suspensions, err := scanSuspensions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Asked of the database, whose clock decided what is active
+	var until time.Time
+	for _, suspension := range suspensions {
+		if suspension.ExpiresAt == "" {
+			continue
+		}
+		var seconds sql.NullInt64
+		if err := r.db.QueryRowContext(ctx, getSuspensionsEndQuery, userId).Scan(&seconds); err != nil {
+			return nil, ErrInternalServerError
+		}
+		if seconds.Valid {
+			until = now.Add(time.Duration(max(seconds.Int64, 0)) * time.Second)
+		}
+		break
+	}
+	activeSuspensions.set(userId, authzVersion, suspensions, now, until)
+
+	return suspensions, nil
+}
+
+// A zero user id matches every user
+func (r *SuspensionRepositoryImpl) GetAll(ctx context.Context, userId int64, activeOnly bool, page int, limit int) ([]*models.Suspension, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, countSuspensionsQuery, userId, userId, activeOnly).Scan(&total); err != nil {
+		return nil, 0, ErrInternalServerError
+	}
+
+	rows, err := r.db.QueryContext(ctx, getSuspensionsQuery, userId, userId, activeOnly, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	suspensions, err := scanSuspensions(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return suspensions, total, nil
+}
+
+// Lifting keeps the row, so the history of a user's suspensions stays visible to admins
+func (r *SuspensionRepositoryImpl) Lift(ctx context.Context, id int64, liftedBy int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	var userId int64
+	if err := tx.QueryRowContext(ctx, getSuspensionUserQuery, id).Scan(&userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSuspensionNotFound
+		}
+		return ErrInternalServerError
+	}
+
+	result, err := tx.ExecContext(ctx, liftSuspensionQuery, liftedBy, id)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return ErrInternalServerError
+	}
+	if rowsAffected == 0 {
+		return ErrSuspensionNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, bumpAuthzVersionQuery, userId); err != nil {
+		return ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ErrInternalServerError
+	}
+	authzVersions.forget(int(userId))
+	activeSuspensions.forget(userId)
+
+	return nil
+}
+
+func scanSuspension(row *sql.Row) (*models.Suspension, error) {
+	suspension := &models.Suspension{}
+	err := row.Scan(&suspension.Id, &suspension.UserId, &suspension.Scope, &suspension.Reason, &suspension.CreatedBy, &suspension.ExpiresAt, &suspension.LiftedAt, &suspension.LiftedBy, &suspension.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return suspension, nil
+}
+
+func scanSuspensions(rows *sql.Rows) ([]*models.Suspension, error) {
+	suspensions := []*models.Suspension{}
+	for rows.Next() {
+		suspension := &models.Suspension{}
+		if err := rows.Scan(&suspension.Id, &suspension.UserId, &suspension.Scope, &suspension.Reason, &suspension.CreatedBy, &suspension.ExpiresAt, &suspension.LiftedAt, &suspension.LiftedBy, &suspension.CreatedAt); err != nil {
+			return nil, ErrInternalServerError
+		}
+		suspensions = append(suspensions, suspension)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrInternalServerError
+	}
+	return suspensions, nil
+}
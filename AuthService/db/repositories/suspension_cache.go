@@ -0,0 +1,68 @@
+package db
+
+import (
+	"AuthService/models"
+	"sync"
+	"time"
+)
+
+const (
+	// Suspending or lifting bumps the user's authz version, which other replicas see within authzVersionTTL.
+	// The TTL only bounds how late a change made around the repository is noticed.
+	suspensionCacheTTL = time.Minute
+
+	// Past this many entries the expired ones are swept on the next write
+	suspensionCacheSweepSize = 10000
+)
+
+type suspensionEntry struct {
+	authzVersion int
+	suspensions  []*models.Suspension
+	expiresAt    time.Time
+}
+
+// Active suspensions are read on every authenticated request, an entry is kept while the user's
+// authz version stays the one it was loaded under and none of its suspensions ran out
+type suspensionCache struct {
+	mu      sync.Mutex
+	entries map[int64]suspensionEntry
+}
+
+var activeSuspensions = &suspensionCache{entries: make(map[int64]suspensionEntry)}
+
+func (c *suspensionCache) get(userId int64, authzVersion int, now time.Time) ([]*models.Suspension, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userId]
+	if !ok || entry.authzVersion != authzVersion || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.suspensions, true
+}
+
+// A zero until keeps the entry for the whole TTL, otherwise it ends when the first suspension expires
+func (c *suspensionCache) set(userId int64, authzVersion int, suspensions []*models.Suspension, now time.Time, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= suspensionCacheSweepSize {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	expiresAt := now.Add(suspensionCacheTTL)
+	if !until.IsZero() && until.Before(expiresAt) {
+		expiresAt = until
+	}
+	c.entries[userId] = suspensionEntry{authzVersion: authzVersion, suspensions: suspensions, expiresAt: expiresAt}
+}
+
+func (c *suspensionCache) forget(userId int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, userId)
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSuspensionCache(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	suspensions := []*models.Suspension{{Id: 1, UserId: 7, Scope: models.SuspensionScopeFull}}
+
+	tests := []struct {
+		name         string
+		until        time.Time
+		authzVersion int
+		at           time.Time
+		want         bool
+	}{
+		{name: "same version within the TTL", authzVersion: 3, at: now.Add(suspensionCacheTTL - time.Second), want: true},
+		{name: "bumped version", authzVersion: 4, at: now},
+		{name: "past the TTL", authzVersion: 3, at: now.Add(suspensionCacheTTL)},
+		{name: "a suspension ran out", until: now.Add(10 * time.Second), authzVersion: 3, at: now.Add(10 * time.Second)},
+		{name: "before a suspension runs out", until: now.Add(10 * time.Second), authzVersion: 3, at: now.Add(9 * time.Second), want: true},
+		{name: "expiry later than the TTL", until: now.Add(time.Hour), authzVersion: 3, at: now.Add(suspensionCacheTTL)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &suspensionCache{entries: make(map[int64]suspensionEntry)}
+			cache.set(7, 3, suspensions, now, tt.until)
+
+			got, ok := cache.get(7, tt.authzVersion, tt.at)
+			if ok != tt.want || (ok && len(got) != 1) {
+				t.Errorf("get() = %v, %v, want cached %v", got, ok, tt.want)
+			}
+		})
+	}
+
+	cache := &suspensionCache{entries: make(map[int64]suspensionEntry)}
+	cache.set(7, 3, suspensions, now, time.Time{})
+	cache.forget(7)
+	if _, ok := cache.get(7, 3, now); ok {
+		t.Errorf("get() after forget() hit the cache")
+	}
+}
+
+func TestSuspensionChangesBumpAuthzVersion(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer conn.Close()
+	repo := NewSuspensionRepository(conn)
+	const userId = 7
+	ctx := context.Background()
+	now := time.Now()
+
+	authzVersions.set(userId, 3, now)
+	activeSuspensions.set(userId, 3, []*models.Suspension{}, now, time.Time{})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO user_suspensions").WillReturnResult(sqlmock.NewResult(11, 1))
+	mock.ExpectExec("UPDATE users SET authz_version = authz_version \\+ 1").WithArgs(int64(userId)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("FROM user_suspensions WHERE id = ?").WithArgs(int64(11)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "scope", "reason", "created_by", "expires_at", "lifted_at", "lifted_by", "created_at"}).
+			AddRow(11, userId, models.SuspensionScopeFull, "spam", 1, "", "", 0, "2026-10-19 12:00:00"))
+	if _, err := repo.Create(ctx, userId, models.SuspensionScopeFull, "spam", 1, 0); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, ok := authzVersions.get(userId, now); ok {
+		t.Errorf("authz version still cached after a suspension")
+	}
+	if _, ok := activeSuspensions.get(userId, 3, now); ok {
+		t.Errorf("suspensions still cached after a suspension")
+	}
+
+	authzVersions.set(userId, 4, now)
+	activeSuspensions.set(userId, 4, []*models.Suspension{{Id: 11}}, now, time.Time{})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT user_id FROM user_suspensions").WithArgs(int64(11)).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userId))
+	mock.ExpectExec("UPDATE user_suspensions SET lifted_at").WithArgs(int64(1), int64(11)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users SET authz_version = authz_version \\+ 1").WithArgs(int64(userId)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.Lift(ctx, 11, 1); err != nil {
+		t.Fatalf("Lift() error = %v", err)
+	}
+	if _, ok := authzVersions.get(userId, now); ok {
+		t.Errorf("authz version still cached after a lift")
+	}
+	if _, ok := activeSuspensions.get(userId, 4, now); ok {
+		t.Errorf("suspensions still cached after a lift")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("queries: %v", err)
+	}
+}
+
+func TestActiveSuspensionsEndWithTheFirstExpiry(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer conn.Close()
+	repo := NewSuspensionRepository(conn)
+	const userId = 8
+	activeSuspensions.forget(userId)
+
+	mock.ExpectQuery("FROM user_suspensions WHERE user_id = ?").WithArgs(int64(userId)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "scope", "reason", "created_by", "expires_at", "lifted_at", "lifted_by", "created_at"}).
+			AddRow(12, userId, models.SuspensionScopeSubmissions, "spam", 1, "2026-10-19 12:00:30", "", 0, "2026-10-19 12:00:00"))
+	mock.ExpectQuery("SELECT TIMESTAMPDIFF").WithArgs(int64(userId)).WillReturnRows(sqlmock.NewRows([]string{"seconds"}).AddRow(30))
+
+	before := time.Now()
+	suspensions, err := repo.GetActiveByUserId(context.Background(), userId, 5)
+	if err != nil || len(suspensions) != 1 {
+		t.Fatalf("GetActiveByUserId() = %v, %v, want the suspension", suspensions, err)
+	}
+	// Served from the cache until the suspension runs out, not for the whole TTL
+	if _, err := repo.GetActiveByUserId(context.Background(), userId, 5); err != nil {
+		t.Fatalf("cached GetActiveByUserId() error = %v", err)
+	}
+	if _, ok := activeSuspensions.get(userId, 5, before.Add(31*time.Second)); ok {
+		t.Errorf("suspension still cached after it ran out")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("queries: %v", err)
+	}
+}
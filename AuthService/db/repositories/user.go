@@ -12,8 +12,11 @@ type UserRepository interface {
 	GetById(ctx context.Context, id string) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	Create(ctx context.Context, username string, email string, hashedPassword string) (*models.User, error)
-	GetAll(ctx context.Context) ([]*models.User, error)
+	List(ctx context.Context, filter models.UserListFilter, after *models.UserListCursor, limit int) ([]*models.UserSummary, error)
 	DeleteById(ctx context.Context, id string) (bool, error)
+	GetPasswordHashById(ctx context.Context, id int64) (string, error)
+	UpdatePassword(ctx context.Context, id int64, hashedPassword string) (bool, error)
+	MarkVerified(ctx context.Context, id int64) (bool, error)
 }
 
 type UserRepositoryImpl struct {
@@ -29,14 +32,19 @@ func NewUserRepository(_db *sql.DB) UserRepository {
 var (
 	ErrUserNotFound        = errors.New("user not found")
 	ErrInternalServerError = errors.New("internal server error")
+	ErrEmailTaken          = errors.New("email already registered")
+	ErrUsernameTaken       = errors.New("username already taken")
 )
 
 var (
 	getByIdQuery    = "SELECT id, email, username, created_at, updated_at FROM users WHERE id = ?"
 	getByEmailQuery = "SELECT id, email, username, password, created_at, updated_at FROM users WHERE email = ?"
 	createQuery     = "INSERT INTO users (username, email, password) VALUES (?, ?, ?)"
-	getAllQuery     = "SELECT id, email, username, created_at, updated_at FROM users"
 	deleteByIdQuery = "UPDATE users SET is_deleted = 1 WHERE id = ?"
+
+	getPasswordHashByIdQuery = "SELECT password FROM users WHERE id = ?"
+	updatePasswordQuery      = "UPDATE users SET password = ?, updated_at = NOW() WHERE id = ?"
+	markVerifiedQuery        = "UPDATE users SET is_verified = true, updated_at = NOW() WHERE id = ?"
 )
 
 func (r *UserRepositoryImpl) GetById(ctx context.Context, id string) (*models.User, error) {
@@ -79,6 +87,9 @@ func (r *UserRepositoryImpl) Create(ctx context.Context, username string, email
 
 	result, err := r.db.ExecContext(ctx, createQuery, username, email, hashedPassword)
 	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrUsernameTaken
+		}
 		return nil, ErrInternalServerError
 	}
 	lastInsertedId, err := result.LastInsertId()
@@ -94,42 +105,64 @@ func (r *UserRepositoryImpl) Create(ctx context.Context, username string, email
 	return user, nil
 }
 
-func (r *UserRepositoryImpl) GetAll(ctx context.Context) ([]*models.User, error) {
+func (r *UserRepositoryImpl) DeleteById(ctx context.Context, id string) (bool, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	rows, err := r.db.QueryContext(ctx, getAllQuery)
+	row, err := r.db.ExecContext(ctx, deleteByIdQuery, id)
 	if err != nil {
-		return nil, ErrInternalServerError
+		return false, ErrInternalServerError
+	}
+
+	rowsAffected, rowErr := row.RowsAffected()
+	if rowErr != nil {
+		return false, ErrInternalServerError
+	}
+
+	if rowsAffected == 0 {
+		return false, ErrUserNotFound
 	}
-	defer rows.Close()
 
-	users := []*models.User{}
-	for rows.Next() {
-		user := &models.User{}
-		if scanErr := rows.Scan(&user.Id, &user.Email, &user.Username, &user.CreatedAt, &user.UpdatedAt); scanErr != nil {
-			return nil, ErrInternalServerError
+	return true, nil
+}
+
+func (r *UserRepositoryImpl) GetPasswordHashById(ctx context.Context, id int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var hashedPassword string
+	if err := r.db.QueryRowContext(ctx, getPasswordHashByIdQuery, id).Scan(&hashedPassword); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
 		}
-		users = append(users, user)
+		return "", ErrInternalServerError
 	}
 
-	return users, nil
+	return hashedPassword, nil
 }
 
-func (r *UserRepositoryImpl) DeleteById(ctx context.Context, id string) (bool, error) {
+func (r *UserRepositoryImpl) UpdatePassword(ctx context.Context, id int64, hashedPassword string) (bool, error) {
+	return r.execForUser(ctx, updatePasswordQuery, hashedPassword, id)
+}
+
+func (r *UserRepositoryImpl) MarkVerified(ctx context.Context, id int64) (bool, error) {
+	return r.execForUser(ctx, markVerifiedQuery, id)
+}
+
+// Runs an update that must touch exactly one existing user
+func (r *UserRepositoryImpl) execForUser(ctx context.Context, query string, args ...any) (bool, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	row, err := r.db.ExecContext(ctx, deleteByIdQuery, id)
+	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return false, ErrInternalServerError
 	}
 
-	rowsAffected, rowErr := row.RowsAffected()
-	if rowErr != nil {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
 		return false, ErrInternalServerError
 	}
-
 	if rowsAffected == 0 {
 		return false, ErrUserNotFound
 	}
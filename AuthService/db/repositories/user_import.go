@@ -0,0 +1,211 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type UserImportRepository interface {
+	ImportBatch(ctx context.Context, entries []*models.UserImportEntry) ([]*models.UserImportResult, error)
+	CreateJob(ctx context.Context, id string, total int) error
+	SetJobStatus(ctx context.Context, id string, status string) error
+	AddJobResults(ctx context.Context, id string, processed int, results []*models.UserImportResult) error
+	GetJob(ctx context.Context, id string) (*models.UserImportJob, error)
+	DeleteJobsFinishedBefore(ctx context.Context, age time.Duration) error
+}
+
+type UserImportRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewUserImportRepository(_db *sql.DB) UserImportRepository {
+	return &UserImportRepositoryImpl{
+		db: _db,
+	}
+}
+
+var (
+	emailExistsQuery       = "SELECT COUNT(*) > 0 FROM users WHERE email = ?"
+	importUserQuery        = "INSERT INTO users (username, email, password) VALUES (?, ?, ?)"
+	importUserRoleQuery    = "INSERT INTO user_roles (user_id, role_id) VALUES (?, ?)"
+	importSavepointQuery   = "SAVEPOINT import_row"
+	importRollbackRowQuery = "ROLLBACK TO SAVEPOINT import_row"
+	importReleaseRowQuery  = "RELEASE SAVEPOINT import_row"
+
+	createImportJobQuery = "INSERT INTO user_import_jobs (id, total, results) VALUES (?, ?, JSON_ARRAY())"
+	// Completed and failed are final, the finish time is set with them
+	setImportJobStatusQuery = `
+		UPDATE user_import_jobs SET status = ?, finished_at = IF(? IN ('completed', 'failed'), NOW(), NULL)
+		WHERE id = ?`
+	addImportJobResultsQuery = `
+		UPDATE user_import_jobs
+		SET processed = ?, created = created + ?, failed = failed + ?, results = JSON_MERGE_PRESERVE(results, CAST(? AS JSON))
+		WHERE id = ?`
+	// A job its process stopped updating, by a restart or a crash, is reported as failed instead of running forever
+	getImportJobQuery = `
+		SELECT id, IF(finished_at IS NULL AND updated_at < NOW() - INTERVAL ? SECOND, 'failed', status),
+			total, processed, created, failed, results, created_at, COALESCE(finished_at, '')
+		FROM user_import_jobs WHERE id = ?`
+	deleteFinishedImportJobsQuery = "DELETE FROM user_import_jobs WHERE finished_at < NOW() - INTERVAL ? SECOND"
+)
+
+var (
+	ErrUserImportJobNotFound = errors.New("import job not found")
+)
+
+// Every batch updates the job, one that saw no update for this long is no longer running
+const importJobStaleAfter = 10 * time.Minute
+
+// Creates a batch of users with their roles in one transaction.
+// Every row runs under a savepoint, so a failing row is reported without losing the rest of the batch.
+func (r *UserImportRepositoryImpl) ImportBatch(ctx context.Context, entries []*models.UserImportEntry) ([]*models.UserImportResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	results := make([]*models.UserImportResult, 0, len(entries))
+	for _, entry := range entries {
+		result := &models.UserImportResult{
+			Row:      entry.Row,
+			Email:    entry.Email,
+			Username: entry.Username,
+			Status:   "failed",
+		}
+		results = append(results, result)
+
+		if _, err := tx.ExecContext(ctx, importSavepointQuery); err != nil {
+			return nil, ErrInternalServerError
+		}
+
+		userId, rowErr := importRow(ctx, tx, entry)
+		if rowErr != nil {
+			if _, err := tx.ExecContext(ctx, importRollbackRowQuery); err != nil {
+				return nil, ErrInternalServerError
+			}
+			result.Error = rowErr.Error()
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, importReleaseRowQuery); err != nil {
+			return nil, ErrInternalServerError
+		}
+		result.Status = "created"
+		result.UserId = userId
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return results, nil
+}
+
+func importRow(ctx context.Context, tx *sql.Tx, entry *models.UserImportEntry) (int64, error) {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, emailExistsQuery, entry.Email).Scan(&exists); err != nil {
+		return 0, ErrInternalServerError
+	}
+	if exists {
+		return 0, ErrEmailTaken
+	}
+
+	result, err := tx.ExecContext(ctx, importUserQuery, entry.Username, entry.Email, entry.HashedPassword)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return 0, ErrUsernameTaken
+		}
+		return 0, ErrInternalServerError
+	}
+	userId, err := result.LastInsertId()
+	if err != nil {
+		return 0, ErrInternalServerError
+	}
+
+	for _, roleId := range entry.RoleIds {
+		if _, err := tx.ExecContext(ctx, importUserRoleQuery, userId, roleId); err != nil {
+			return 0, ErrInternalServerError
+		}
+	}
+
+	return userId, nil
+}
+
+func (r *UserImportRepositoryImpl) CreateJob(ctx context.Context, id string, total int) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, createImportJobQuery, id, total); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (r *UserImportRepositoryImpl) SetJobStatus(ctx context.Context, id string, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, setImportJobStatusQuery, status, status, id); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// Appends the results of one batch and counts them, processed is the number of rows done so far
+func (r *UserImportRepositoryImpl) AddJobResults(ctx context.Context, id string, processed int, results []*models.UserImportResult) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	created := 0
+	for _, result := range results {
+		if result.Status == "created" {
+			created++
+		}
+	}
+	encoded, err := json.Marshal(results)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	if _, err := r.db.ExecContext(ctx, addImportJobResultsQuery, processed, created, len(results)-created, string(encoded), id); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (r *UserImportRepositoryImpl) GetJob(ctx context.Context, id string) (*models.UserImportJob, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	job := &models.UserImportJob{}
+	var results []byte
+	err := r.db.QueryRowContext(ctx, getImportJobQuery, int64(importJobStaleAfter.Seconds()), id).
+		Scan(&job.Id, &job.Status, &job.Total, &job.Processed, &job.Created, &job.Failed, &results, &job.CreatedAt, &job.FinishedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserImportJobNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	if err := json.Unmarshal(results, &job.Results); err != nil {
+		return nil, ErrInternalServerError
+	}
+	return job, nil
+}
+
+func (r *UserImportRepositoryImpl) DeleteJobsFinishedBefore(ctx context.Context, age time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, deleteFinishedImportJobsQuery, int64(age.Seconds())); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
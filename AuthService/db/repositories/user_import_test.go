@@ -0,0 +1,53 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestImportJobs(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	defer conn.Close()
+	repo := NewUserImportRepository(conn)
+	ctx := context.Background()
+
+	// One created and one failed row are counted and appended as a JSON array
+	mock.ExpectExec("UPDATE user_import_jobs").
+		WithArgs(2, 1, 1, `[{"row":1,"email":"ada@example.com","username":"ada","status":"created","user_id":9,"invite_sent":true},{"row":2,"email":"bob@example.com","username":"bob","status":"failed","invite_sent":false,"error":"email already taken"}]`, "job-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = repo.AddJobResults(ctx, "job-1", 2, []*models.UserImportResult{
+		{Row: 1, Email: "ada@example.com", Username: "ada", Status: "created", UserId: 9, InviteSent: true},
+		{Row: 2, Email: "bob@example.com", Username: "bob", Status: "failed", Error: "email already taken"},
+	})
+	if err != nil {
+		t.Fatalf("AddJobResults() error = %v", err)
+	}
+
+	columns := []string{"id", "status", "total", "processed", "created", "failed", "results", "created_at", "finished_at"}
+	mock.ExpectQuery("FROM user_import_jobs WHERE id = ?").WithArgs(int64(importJobStaleAfter.Seconds()), "job-1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("job-1", "running", 60, 2, 1, 1, []byte(`[{"row":1,"status":"created","user_id":9}]`), "2026-10-19 12:00:00", ""))
+	job, err := repo.GetJob(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if job.Status != "running" || job.Total != 60 || job.Processed != 2 || len(job.Results) != 1 || job.Results[0].UserId != 9 {
+		t.Errorf("GetJob() = %+v, want the stored job", job)
+	}
+
+	mock.ExpectQuery("FROM user_import_jobs WHERE id = ?").WithArgs(int64(importJobStaleAfter.Seconds()), "missing").
+		WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := repo.GetJob(ctx, "missing"); !errors.Is(err, ErrUserImportJobNotFound) {
+		t.Errorf("GetJob() error = %v, want %v", err, ErrUserImportJobNotFound)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("queries: %v", err)
+	}
+}
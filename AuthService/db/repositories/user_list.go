@@ -0,0 +1,129 @@
+package db
+
+import (
+	"AuthService/models"
+	"context"
+	"strings"
+	"time"
+)
+
+// Sortable columns of the users list, each one is indexed
+var userListSortColumns = map[string]string{
+	"id":         "u.id",
+	"created_at": "u.created_at",
+	"username":   "u.username",
+	"email":      "u.email",
+}
+
+var (
+	userSuspendedCondition = `EXISTS (
+		SELECT 1 FROM user_suspensions s
+		WHERE s.user_id = u.id AND s.lifted_at IS NULL AND (s.expires_at IS NULL OR s.expires_at > NOW()))`
+	userHasRoleCondition = `EXISTS (
+		SELECT 1 FROM user_roles ur INNER JOIN roles r ON ur.role_id = r.id
+		WHERE ur.user_id = u.id AND r.name = ?)`
+	listUsersSelect = `
+		SELECT u.id, u.username, u.email, u.is_verified, u.is_guest, COALESCE(u.is_deleted, false), ` + userSuspendedCondition + `, u.created_at, u.updated_at
+		FROM users u`
+)
+
+func IsUserListSortColumn(name string) bool {
+	_, ok := userListSortColumns[name]
+	return ok
+}
+
+// Reads one keyset page, rows strictly after the cursor in the filter's order. A nil cursor starts at the beginning.
+func (r *UserRepositoryImpl) List(ctx context.Context, filter models.UserListFilter, after *models.UserListCursor, limit int) ([]*models.UserSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	sortColumn, ok := userListSortColumns[filter.SortBy]
+	if !ok {
+		sortColumn = "u.id"
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	conditions := []string{}
+	args := []any{}
+	if filter.Role != "" {
+		conditions = append(conditions, userHasRoleCondition)
+		args = append(args, filter.Role)
+	}
+	if filter.CreatedFrom != "" {
+		conditions = append(conditions, "u.created_at >= ?")
+		args = append(args, filter.CreatedFrom)
+	}
+	if filter.CreatedTo != "" {
+		conditions = append(conditions, "u.created_at < ?")
+		args = append(args, filter.CreatedTo)
+	}
+	if filter.Deleted != nil {
+		conditions = append(conditions, "COALESCE(u.is_deleted, false) = ?")
+		args = append(args, *filter.Deleted)
+	}
+	if filter.Verified != nil {
+		conditions = append(conditions, "u.is_verified = ?")
+		args = append(args, *filter.Verified)
+	}
+	if filter.Suspended != nil {
+		if *filter.Suspended {
+			conditions = append(conditions, userSuspendedCondition)
+		} else {
+			conditions = append(conditions, "NOT "+userSuspendedCondition)
+		}
+	}
+	if filter.Search != "" {
+		// Prefix matches keep the username and email indexes usable
+		pattern := escapeLike(filter.Search) + "%"
+		conditions = append(conditions, "(u.username LIKE ? OR u.email LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	if after != nil {
+		if sortColumn == "u.id" {
+			conditions = append(conditions, "u.id "+comparison+" ?")
+			args = append(args, after.Id)
+		} else {
+			conditions = append(conditions, "("+sortColumn+" "+comparison+" ? OR ("+sortColumn+" = ? AND u.id "+comparison+" ?))")
+			args = append(args, after.SortValue, after.SortValue, after.Id)
+		}
+	}
+
+	query := listUsersSelect
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + sortColumn + " " + direction
+	if sortColumn != "u.id" {
+		query += ", u.id " + direction
+	}
+	query += " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	defer rows.Close()
+
+	users := []*models.UserSummary{}
+	for rows.Next() {
+		user := &models.UserSummary{}
+		if err := rows.Scan(&user.Id, &user.Username, &user.Email, &user.IsVerified, &user.IsGuest, &user.IsDeleted, &user.Suspended, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, ErrInternalServerError
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return users, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
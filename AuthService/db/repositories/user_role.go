@@ -4,6 +4,7 @@ import (
 	"AuthService/models"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -18,6 +19,8 @@ type UserRoleRepository interface {
 	HasAnyRole(ctx context.Context, userId int, roleNames []string) (bool, error)
 	AssignRole(ctx context.Context, userId int, roleId int) (bool, error)
 	RemoveRole(ctx context.Context, userRoleId int) (bool, error)
+	RemoveUserRole(ctx context.Context, userId int, roleId int) (bool, error)
+	GetAuthzVersion(ctx context.Context, userId int) (int, error)
 }
 
 type UserRoleRepositoryImpl struct {
@@ -30,9 +33,13 @@ func NewUserRoleRepository(_db *sql.DB) UserRoleRepository {
 	}
 }
 
+var (
+	ErrLastAdmin = errors.New("the last admin cannot lose the admin role")
+)
+
 var (
 	getUserRolesQuery = `
-		SELECT r.id, r.name, r.description, r.created_at, r.updated_at
+		SELECT r.id, r.name, r.description, r.created_at, r.updated_at, r.is_system
 		FROM user_roles ur
 		INNER JOIN roles r ON ur.role_id = r.id
 		WHERE ur.user_id = ?
@@ -66,6 +73,22 @@ var (
 	removeRoleQuery = `
 	DELETE FROM user_roles where id = ?
 	`
+	getUserRoleByIdQuery = `
+	SELECT user_id, role_id FROM user_roles WHERE id = ?
+	`
+	removeUserRoleQuery = `
+	DELETE FROM user_roles WHERE user_id = ? AND role_id = ?
+	`
+	getRoleNameQuery      = "SELECT name FROM roles WHERE id = ?"
+	lockActiveAdminsQuery = `
+		SELECT ur.user_id
+		FROM user_roles ur
+		INNER JOIN roles r ON ur.role_id = r.id
+		INNER JOIN users u ON ur.user_id = u.id
+		WHERE r.name = 'admin' AND u.is_deleted = false
+		FOR UPDATE`
+	getAuthzVersionQuery  = "SELECT authz_version FROM users WHERE id = ?"
+	bumpAuthzVersionQuery = "UPDATE users SET authz_version = authz_version + 1 WHERE id = ?"
 )
 
 func (u *UserRoleRepositoryImpl) GetUserRoles(ctx context.Context, userId int64) ([]*models.Role, error) {
@@ -81,7 +104,7 @@ func (u *UserRoleRepositoryImpl) GetUserRoles(ctx context.Context, userId int64)
 	var roles []*models.Role
 	for rows.Next() {
 		role := &models.Role{}
-		if err := rows.Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		if err := rows.Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt, &role.IsSystem); err != nil {
 			return nil, ErrInternalServerError
 		}
 		roles = append(roles, role)
@@ -200,7 +223,13 @@ func (u *UserRoleRepositoryImpl) AssignRole(ctx context.Context, userId int, rol
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	result, err := u.db.ExecContext(ctx, assignRoleQuery, userId, roleId)
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, assignRoleQuery, userId, roleId)
 	if err != nil {
 		return false, ErrInternalServerError
 	}
@@ -210,6 +239,16 @@ func (u *UserRoleRepositoryImpl) AssignRole(ctx context.Context, userId int, rol
 		return false, ErrInternalServerError
 	}
 
+	// Tokens issued before this change carry a stale role set
+	if _, err := tx.ExecContext(ctx, bumpAuthzVersionQuery, userId); err != nil {
+		return false, ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, ErrInternalServerError
+	}
+	authzVersions.forget(userId)
+
 	return true, nil
 }
 
@@ -217,7 +256,25 @@ func (u *UserRoleRepositoryImpl) RemoveRole(ctx context.Context, userRoleId int)
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	row, err := u.db.ExecContext(ctx, removeRoleQuery, userRoleId)
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	var userId, roleId int
+	if err := tx.QueryRowContext(ctx, getUserRoleByIdQuery, userRoleId).Scan(&userId, &roleId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrRoleNotFound
+		}
+		return false, ErrInternalServerError
+	}
+
+	if err := ensureNotLastAdmin(ctx, tx, userId, roleId); err != nil {
+		return false, err
+	}
+
+	row, err := tx.ExecContext(ctx, removeRoleQuery, userRoleId)
 	if err != nil {
 		return false, ErrInternalServerError
 	}
@@ -230,5 +287,115 @@ func (u *UserRoleRepositoryImpl) RemoveRole(ctx context.Context, userRoleId int)
 		return false, ErrRoleNotFound
 	}
 
+	if _, err := tx.ExecContext(ctx, bumpAuthzVersionQuery, userId); err != nil {
+		return false, ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, ErrInternalServerError
+	}
+	authzVersions.forget(userId)
+
 	return true, nil
 }
+
+func (u *UserRoleRepositoryImpl) RemoveUserRole(ctx context.Context, userId int, roleId int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+	defer tx.Rollback()
+
+	if err := ensureNotLastAdmin(ctx, tx, userId, roleId); err != nil {
+		return false, err
+	}
+
+	row, err := tx.ExecContext(ctx, removeUserRoleQuery, userId, roleId)
+	if err != nil {
+		return false, ErrInternalServerError
+	}
+
+	rowsAffected, rowsAffectedErr := row.RowsAffected()
+	if rowsAffectedErr != nil {
+		return false, ErrInternalServerError
+	}
+	if rowsAffected == 0 {
+		return false, ErrRoleNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, bumpAuthzVersionQuery, userId); err != nil {
+		return false, ErrInternalServerError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, ErrInternalServerError
+	}
+	authzVersions.forget(userId)
+
+	return true, nil
+}
+
+// Refuses to take the admin role away from the only remaining active admin
+func ensureNotLastAdmin(ctx context.Context, tx *sql.Tx, userId int, roleId int) error {
+	var roleName string
+	if err := tx.QueryRowContext(ctx, getRoleNameQuery, roleId).Scan(&roleName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRoleNotFound
+		}
+		return ErrInternalServerError
+	}
+	if roleName != "admin" {
+		return nil
+	}
+
+	// Locks the admin assignments so two concurrent removals can't both pass
+	rows, err := tx.QueryContext(ctx, lockActiveAdminsQuery)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	defer rows.Close()
+
+	otherAdmins := 0
+	for rows.Next() {
+		var adminId int
+		if err := rows.Scan(&adminId); err != nil {
+			return ErrInternalServerError
+		}
+		if adminId != userId {
+			otherAdmins++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return ErrInternalServerError
+	}
+
+	if otherAdmins == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// Served from a short lived cache, see authzVersionCache
+func (u *UserRoleRepositoryImpl) GetAuthzVersion(ctx context.Context, userId int) (int, error) {
+	now := time.Now()
+	if version, ok := authzVersions.get(userId, now); ok {
+		return version, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var version int
+	if err := u.db.QueryRowContext(ctx, getAuthzVersionQuery, userId).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		return 0, ErrInternalServerError
+	}
+	authzVersions.set(userId, version, now)
+
+	return version, nil
+}
@@ -1,3 +1,4 @@
-INSERT INTO roles (name, description) VALUES
-('admin', 'Administrator with full access'),
-('user', 'Regular user with limited access');
\ No newline at end of file
+INSERT INTO roles (name, description, is_system) VALUES
+('admin', 'Administrator with full access', true),
+('user', 'Regular user with limited access', true),
+('guest', 'Anonymous visitor trying the platform before signing up', true);
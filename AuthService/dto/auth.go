@@ -18,3 +18,89 @@ type UserIdDTO struct {
 type UserEmailDTO struct {
 	Email string `json:"email" validate:"required,email"`
 }
+
+type TokenClaimsDTO struct {
+	UserId       int      `json:"id"`
+	Email        string   `json:"email"`
+	Roles        []string `json:"roles"`
+	Permissions  []string `json:"permissions"`
+	AuthzVersion int      `json:"authz_version"`
+	OrgId        int64    `json:"org_id"`
+	OrgRole      string   `json:"org_role"`
+	SessionId    string   `json:"sid,omitempty"`
+	// Set only on impersonation tokens, the admin acting as the user and when the token stops working
+	ImpersonatorId int   `json:"act,omitempty"`
+	ExpiresAt      int64 `json:"exp,omitempty"`
+}
+
+type UserImportRowDTO struct {
+	Email    string   `json:"email" validate:"required,email"`
+	Username string   `json:"username" validate:"required,min=2"`
+	Roles    []string `json:"roles"`
+}
+
+type AcceptInviteDTO struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+type ImpersonateUserDTO struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+type MagicLinkRequestDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLinkLoginDTO struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type UpgradeGuestDTO struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8"`
+	Username string `json:"username" validate:"required,min=2"`
+}
+
+type CreateSuspensionDTO struct {
+	UserId int64  `json:"user_id" validate:"required"`
+	Scope  string `json:"scope" validate:"required,oneof=full submissions"`
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+	// Left out, the suspension lasts until an admin lifts it
+	DurationMinutes int `json:"duration_minutes" validate:"omitempty,min=1"`
+}
+
+// Replaces the whole profile, a privacy flag left out hides that field. The avatar has its own upload endpoint.
+type UpdateProfileDTO struct {
+	DisplayName string            `json:"display_name" validate:"max=100"`
+	Bio         string            `json:"bio" validate:"max=500"`
+	Privacy     ProfilePrivacyDTO `json:"privacy"`
+}
+
+type ProfilePrivacyDTO struct {
+	DisplayName bool `json:"display_name"`
+	Bio         bool `json:"bio"`
+	Avatar      bool `json:"avatar"`
+	JoinDate    bool `json:"join_date"`
+	Stats       bool `json:"stats"`
+}
+
+type PreferencesDTO struct {
+	EditorTheme string `json:"editor_theme" validate:"required,oneof=vs vs-dark hc-black"`
+	Language    string `json:"language" validate:"required,oneof=cpp javascript java python"`
+	FontSize    int    `json:"font_size" validate:"required,min=10,max=32"`
+	Keybindings string `json:"keybindings" validate:"required,oneof=default vim emacs"`
+}
+
+// Only the fields present are changed
+type PatchPreferencesDTO struct {
+	EditorTheme *string `json:"editor_theme" validate:"omitempty,oneof=vs vs-dark hc-black"`
+	Language    *string `json:"language" validate:"omitempty,oneof=cpp javascript java python"`
+	FontSize    *int    `json:"font_size" validate:"omitempty,min=10,max=32"`
+	Keybindings *string `json:"keybindings" validate:"omitempty,oneof=default vim emacs"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+type CreateOrganizationDTO struct {
+	Name string `json:"name" validate:"required,min=2,max=255"`
+	Slug string `json:"slug" validate:"required,min=2,max=100"`
+}
+
+type UpdateOrgMemberDTO struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type CreateOrgInvitationDTO struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=admin member"`
+}
+
+type AcceptOrgInvitationDTO struct {
+	Token string `json:"token" validate:"required"`
+}
@@ -13,3 +13,11 @@ type UpdateRoleDTO struct {
 type RoleIdDTO struct {
 	Id int `json:"id" validate:"required,min=1"`
 }
+
+type PolicyExplainDTO struct {
+	Method      string   `json:"method" validate:"required"`
+	Path        string   `json:"path" validate:"required,startswith=/"`
+	UserId      int      `json:"userId" validate:"omitempty,min=1"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
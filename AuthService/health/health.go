@@ -0,0 +1,148 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Where the process is in its life, only a serving one takes traffic
+const (
+	PhaseStarting  = "starting"
+	PhaseMigrating = "migrating"
+	PhaseServing   = "serving"
+	PhaseStopping  = "stopping"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// Reports whether a dependency can be used, nil when it can
+type Check func(ctx context.Context) error
+
+type check struct {
+	name     string
+	critical bool
+	run      Check
+}
+
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Ready only while serving with every critical check up. A failed check that is not critical
+// degrades the report without taking the instance out of rotation.
+type Report struct {
+	Status    string        `json:"status"`
+	Phase     string        `json:"phase"`
+	Ready     bool          `json:"ready"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []CheckResult `json:"checks"`
+}
+
+// Checks of the dependencies the gateway needs, added as they come up during startup
+type Checker struct {
+	timeout time.Duration
+	phase   atomic.Value
+
+	mu     sync.RWMutex
+	checks []check
+}
+
+// Every check gets timeout to answer, all of them run at the same time
+func NewChecker(timeout time.Duration) *Checker {
+	checker := &Checker{timeout: timeout}
+	checker.phase.Store(PhaseStarting)
+	return checker
+}
+
+// A critical check that fails makes the instance not ready
+func (c *Checker) Add(name string, critical bool, run Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, check{name: name, critical: critical, run: run})
+}
+
+func (c *Checker) SetPhase(phase string) {
+	c.phase.Store(phase)
+}
+
+func (c *Checker) Phase() string {
+	return c.phase.Load().(string)
+}
+
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	report := Report{
+		Status:    StatusUp,
+		Phase:     c.Phase(),
+		CheckedAt: time.Now().UTC(),
+		Checks:    make([]CheckResult, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, result := range report.Checks {
+		if result.Status == StatusUp {
+			continue
+		}
+		if result.Critical {
+			healthy = false
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	report.Ready = healthy && report.Phase == PhaseServing
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	// A check that ignores its context still cannot hold the report past the timeout
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.run(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := CheckResult{
+		Name:      check.name,
+		Status:    StatusUp,
+		Critical:  check.critical,
+		LatencyMs: float64(time.Since(started).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gateway"
+
+// Reasons an authenticated route turned a request away
+const (
+	AuthMissingToken   = "missing_token"
+	AuthInvalidToken   = "invalid_token"
+	AuthSessionExpired = "session_expired"
+	AuthSuspended      = "suspended"
+	AuthForbidden      = "forbidden"
+)
+
+// Everything the gateway reports, served by Handler. Go runtime and process metrics are included.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	// Labelled by chi's route pattern, never the raw path, so ids in urls do not multiply the series
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Requests answered, by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+	httpDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to answer a request, by route pattern, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	upstreamRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Tries sent to upstream replicas, by upstream and outcome. Retries count separately.",
+	}, []string{"upstream", "outcome"})
+	upstreamDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Time an upstream replica took to answer one try, by upstream and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"upstream", "outcome"})
+
+	authFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Requests turned away by authentication or authorization, by reason.",
+	}, []string{"reason"})
+
+	rateLimitRejections = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected with a 429, by rate limit bucket.",
+	}, []string{"bucket"})
+
+	WebsocketConnections = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "Websocket connections open on this instance.",
+	})
+
+	evaluatedReceived = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "evaluated_events_received_total",
+		Help:      "Evaluated submission events read from Redis.",
+	})
+	evaluatedDelivered = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "evaluated_events_delivered_total",
+		Help:      "Evaluated submission events that reached at least one open websocket of the user.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Reports the connection pool of db under the given name, read from db.Stats() on every scrape
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Requests that matched no route share one label, as do unknown methods
+func ObserveRequest(route string, method string, status int, duration time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+	default:
+		method = "OTHER"
+	}
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, code).Inc()
+	httpDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+func ObserveUpstream(upstream string, failed bool, duration time.Duration) {
+	outcome := "success"
+	if failed {
+		outcome = "failure"
+	}
+	upstreamRequests.WithLabelValues(upstream, outcome).Inc()
+	upstreamDuration.WithLabelValues(upstream, outcome).Observe(duration.Seconds())
+}
+
+// A try the client cancelled before the upstream answered, kept apart from successes and failures
+func ObserveUpstreamCancelled(upstream string, duration time.Duration) {
+	upstreamRequests.WithLabelValues(upstream, "cancelled").Inc()
+	upstreamDuration.WithLabelValues(upstream, "cancelled").Observe(duration.Seconds())
+}
+
+func AuthFailure(reason string) {
+	authFailures.WithLabelValues(reason).Inc()
+}
+
+func RateLimitRejected(bucket string) {
+	rateLimitRejections.WithLabelValues(bucket).Inc()
+}
+
+// An evaluated event was read, delivered tells whether any connection of the user got it
+func EvaluatedEvent(delivered bool) {
+	evaluatedReceived.Inc()
+	if delivered {
+		evaluatedDelivered.Inc()
+	}
+}
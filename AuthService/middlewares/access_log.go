@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"AuthService/metrics"
+	"AuthService/tracing"
+	"AuthService/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// Logged for a client that went away before anything was written, as nginx does
+const statusClientClosedRequest = 499
+
+// Writes one structured line per request once it is answered and counts it in the request metrics.
+// The user and upstream are filled in by the handlers that learn them, the route is chi's pattern
+// so requests to one endpoint group together.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		fields := &utils.AccessLogFields{}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		r = r.WithContext(context.WithValue(r.Context(), utils.AccessLogKey, fields))
+
+		next.ServeHTTP(ww, r)
+
+		status := responseStatus(r, ww.Status())
+		route := ""
+		if routeContext := chi.RouteContext(r.Context()); routeContext != nil {
+			route = routeContext.RoutePattern()
+		}
+		meta := utils.GetRequestMeta(r.Context())
+		latency := time.Since(start)
+		metrics.ObserveRequest(route, r.Method, status, latency)
+
+		entry := logrus.WithFields(logrus.Fields{
+			"method":         r.Method,
+			"path":           r.URL.Path,
+			"route":          route,
+			"status":         status,
+			"latency_ms":     float64(latency.Microseconds()) / 1000,
+			"bytes":          ww.BytesWritten(),
+			"user_id":        fields.UserId,
+			"upstream":       fields.Upstream,
+			"target":         fields.Target,
+			"cache":          ww.Header().Get("X-Cache"),
+			"request_id":     meta.RequestId,
+			"correlation_id": meta.CorrelationId,
+			"trace_id":       tracing.TraceId(r.Context()),
+			"ip_address":     meta.IpAddress,
+			"type":           "access",
+		})
+		if fields.ImpersonatorId != 0 {
+			entry = entry.WithField("impersonator_id", fields.ImpersonatorId)
+		}
+		if status >= 500 {
+			entry.Error("Request handled")
+			return
+		}
+		entry.Info("Request handled")
+	})
+}
+
+// The status a request was answered with. A handler that wrote nothing answered 200, the server
+// sends that for it, unless the client went away first.
+func responseStatus(r *http.Request, status int) int {
+	if status != 0 {
+		return status
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		// A hijacked connection never reports its 101
+		return http.StatusSwitchingProtocols
+	}
+	if errors.Is(r.Context().Err(), context.Canceled) {
+		return statusClientClosedRequest
+	}
+	return http.StatusOK
+}
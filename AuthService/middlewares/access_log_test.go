@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestAccessLogStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		cancel  bool
+		upgrade bool
+		want    int
+	}{
+		{
+			name:    "written status",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			want:    http.StatusNotFound,
+		},
+		{
+			name:    "body without a status",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+			want:    http.StatusOK,
+		},
+		{
+			name:    "nothing written",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			want:    http.StatusOK,
+		},
+		{
+			name:    "client went away",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			cancel:  true,
+			want:    statusClientClosedRequest,
+		},
+		{
+			name:    "client went away after the answer",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) },
+			cancel:  true,
+			want:    http.StatusAccepted,
+		},
+		{
+			name:    "websocket",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			upgrade: true,
+			want:    http.StatusSwitchingProtocols,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := test.NewGlobal()
+			defer hook.Reset()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			request := httptest.NewRequest(http.MethodGet, "/problems", nil).WithContext(ctx)
+			if tt.upgrade {
+				request.Header.Set("Upgrade", "websocket")
+			}
+			AccessLog(tt.handler).ServeHTTP(httptest.NewRecorder(), request)
+
+			var entry *logrus.Entry
+			for _, logged := range hook.AllEntries() {
+				if logged.Data["type"] == "access" {
+					entry = logged
+				}
+			}
+			if entry == nil {
+				t.Fatal("no access log line was written")
+			}
+			if status := entry.Data["status"]; status != tt.want {
+				t.Errorf("logged status = %v, want %d", status, tt.want)
+			}
+		})
+	}
+}
@@ -2,14 +2,17 @@ package middlewares
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	dbConfig "AuthService/config/db"
 	env "AuthService/config/env"
 	db "AuthService/db/repositories"
 	"AuthService/dto"
+	"AuthService/metrics"
 	"AuthService/models"
 	"AuthService/utils"
 
@@ -24,6 +27,7 @@ func JWTAuthMiddleware(next http.Handler) http.Handler {
 		if authHeader == "" {
 			cookie, err := r.Cookie("access_token")
 			if err != nil || cookie == nil {
+				metrics.AuthFailure(metrics.AuthMissingToken)
 				utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Authorization header is required")
 				return
 			}
@@ -35,32 +39,125 @@ func JWTAuthMiddleware(next http.Handler) http.Handler {
 		// }
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 		if token == "" {
+			metrics.AuthFailure(metrics.AuthMissingToken)
 			utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Token is required")
 			return
 		}
-		claims := jwt.MapClaims{}
-		_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(env.GetString("SECRET_KEY", "TOKEN")), nil
-		})
-		if err != nil {
-			// http.Error(w, "Invalid token: "+err.Error(), http.StatusUnauthorized)
+		tokenClaims, ok := parseTokenClaims(token)
+		if !ok {
+			metrics.AuthFailure(metrics.AuthInvalidToken)
 			utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Invalid token")
 			return
 		}
-		userId, okId := claims["id"].(float64)
-		email, okEmail := claims["email"].(string)
-		if !okId || !okEmail {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
-			utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Invalid token")
+		accessLog := utils.GetAccessLogFields(r.Context())
+		accessLog.UserId, accessLog.ImpersonatorId = tokenClaims.UserId, tokenClaims.ImpersonatorId
+
+		// Every login token belongs to a session, a revoked session turns its token away immediately.
+		// Suspensions apply to the user's own requests, an admin acting as them is investigating.
+		var suspensions []*models.Suspension
+		if tokenClaims.ImpersonatorId == 0 {
+			if !checkSession(w, r, tokenClaims) {
+				return
+			}
+			if suspensions, ok = checkSuspensions(w, r, tokenClaims); !ok {
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), utils.UserIDKey, dto.UserIdDTO{UserId: tokenClaims.UserId})
+		ctx = context.WithValue(ctx, utils.EmailKey, tokenClaims.Email)
+		ctx = context.WithValue(ctx, utils.ClaimsKey, tokenClaims)
+		ctx = context.WithValue(ctx, utils.SuspensionsKey, suspensions)
+
+		if tokenClaims.ImpersonatorId != 0 {
+			auditImpersonatedRequest(next, w, r.WithContext(ctx), tokenClaims)
 			return
 		}
-		ctx := context.WithValue(r.Context(), utils.UserIDKey, dto.UserIdDTO{UserId: int(userId)})
-		ctx = context.WithValue(ctx, utils.EmailKey, email)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// Verifies a signed token and reads its claims, without checking the session behind it
+func parseTokenClaims(token string) (dto.TokenClaimsDTO, bool) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(env.GetString("SECRET_KEY", "TOKEN")), nil
+	})
+	if err != nil {
+		return dto.TokenClaimsDTO{}, false
+	}
+	userId, okId := claims["id"].(float64)
+	email, okEmail := claims["email"].(string)
+	if !okId || !okEmail {
+		return dto.TokenClaimsDTO{}, false
+	}
+	tokenClaims := dto.TokenClaimsDTO{
+		UserId:         int(userId),
+		Email:          email,
+		Roles:          claimStrings(claims["roles"]),
+		Permissions:    claimStrings(claims["permissions"]),
+		AuthzVersion:   claimInt(claims["authz_version"]),
+		OrgId:          int64(claimInt(claims["org_id"])),
+		OrgRole:        claimString(claims["org_role"]),
+		ImpersonatorId: claimInt(claims["act"]),
+		SessionId:      claimString(claims["sid"]),
+		ExpiresAt:      int64(claimInt(claims["exp"])),
+	}
+	// Impersonation tokens are always short-lived, one without an expiry was not issued by us
+	if tokenClaims.ImpersonatorId != 0 && tokenClaims.ExpiresAt == 0 {
+		return dto.TokenClaimsDTO{}, false
+	}
+	return tokenClaims, true
+}
+
+// The token from the Authorization header, or the access_token cookie when there is none
+func requestToken(r *http.Request) string {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		cookie, err := r.Cookie("access_token")
+		if err != nil || cookie == nil {
+			return ""
+		}
+		authHeader = cookie.Value
+	}
+	return strings.TrimPrefix(authHeader, "Bearer ")
+}
+
+// Tokens issued before sessions were introduced carry no sid and never expire. They are accepted until
+// SESSIONLESS_TOKENS_UNTIL so a rollout need not sign everyone out at once, without it their holders must sign in again.
+func checkSession(w http.ResponseWriter, r *http.Request, tokenClaims dto.TokenClaimsDTO) bool {
+	if tokenClaims.SessionId == "" {
+		if acceptsSessionlessTokens(time.Now()) {
+			return true
+		}
+		metrics.AuthFailure(metrics.AuthSessionExpired)
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Session expired, please sign in again")
+		return false
+	}
+
+	err := db.NewSessionRepository(dbConfig.DB).Touch(r.Context(), int64(tokenClaims.UserId), tokenClaims.SessionId, utils.ClientIP(r))
+	if err != nil {
+		if errors.Is(err, db.ErrSessionNotFound) || errors.Is(err, db.ErrSessionRevoked) {
+			metrics.AuthFailure(metrics.AuthSessionExpired)
+			utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Session expired, please sign in again")
+			return false
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "You are not authorized to access this route", db.ErrInternalServerError.Error())
+		return false
+	}
+	return true
+}
+
+// An unset or unreadable cutoff accepts none, a typo must not keep tokens without a session working forever
+func acceptsSessionlessTokens(now time.Time) bool {
+	cutoff, err := time.Parse(time.RFC3339, env.GetString("SESSIONLESS_TOKENS_UNTIL", ""))
+	if err != nil {
+		return false
+	}
+	return now.Before(cutoff)
+}
+
 func RequireAllRoles(roles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -76,6 +173,22 @@ func RequireAllRoles(roles ...string) func(http.Handler) http.Handler {
 
 			user_role_repo := db.NewUserRoleRepository(dbConn)
 
+			// Trust the roles embedded in the token unless they were changed after it was issued
+			if tokenClaims, ok := currentTokenClaims(r, user_role_repo); ok {
+				if !containsAllRoleNames(tokenClaims.Roles, roles) {
+					metrics.AuthFailure(metrics.AuthForbidden)
+					utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Forbidden: You do not have the required roles")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r, ok = enforceOrgMembership(w, r)
+			if !ok {
+				return
+			}
+
 			hasAllRoles, hasAllRolesErr := user_role_repo.HasAllRoles(r.Context(), userId, roles)
 			if hasAllRolesErr != nil {
 				// http.Error(w, "Error checking user roles: "+hasAllRolesErr.Error(), http.StatusInternalServerError)
@@ -84,6 +197,7 @@ func RequireAllRoles(roles ...string) func(http.Handler) http.Handler {
 			}
 
 			if !hasAllRoles {
+				metrics.AuthFailure(metrics.AuthForbidden)
 				utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Forbidden: You do not have the required roles")
 				return
 			}
@@ -108,6 +222,21 @@ func RequireAnyRole(roles ...string) func(http.Handler) http.Handler {
 
 			urr := db.NewUserRoleRepository(dbConn)
 
+			if tokenClaims, ok := currentTokenClaims(r, urr); ok {
+				if !containsAnyRoleName(tokenClaims.Roles, roles) {
+					metrics.AuthFailure(metrics.AuthForbidden)
+					utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Forbidden: You do not have the required roles")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r, ok = enforceOrgMembership(w, r)
+			if !ok {
+				return
+			}
+
 			hasAnyRole, hasAnyRolesErr := urr.HasAnyRole(r.Context(), userId, roles)
 			if hasAnyRolesErr != nil {
 				// http.Error(w, "Error checking user roles: "+hasAnyRolesErr.Error(), http.StatusInternalServerError)
@@ -116,6 +245,7 @@ func RequireAnyRole(roles ...string) func(http.Handler) http.Handler {
 			}
 
 			if !hasAnyRole {
+				metrics.AuthFailure(metrics.AuthForbidden)
 				utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Forbidden: You do not have the required roles")
 				return
 			}
@@ -125,6 +255,101 @@ func RequireAnyRole(roles ...string) func(http.Handler) http.Handler {
 	}
 }
 
+// Returns the token claims when the user's authz version still matches the one they were issued with
+func currentTokenClaims(r *http.Request, urr db.UserRoleRepository) (dto.TokenClaimsDTO, bool) {
+	tokenClaims, ok := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+	if !ok || tokenClaims.AuthzVersion == 0 {
+		return dto.TokenClaimsDTO{}, false
+	}
+
+	version, err := urr.GetAuthzVersion(r.Context(), tokenClaims.UserId)
+	if err != nil || version != tokenClaims.AuthzVersion {
+		return dto.TokenClaimsDTO{}, false
+	}
+
+	return tokenClaims, true
+}
+
+// Stale tokens are re-checked against the DB, so a user removed from their active organization loses access at once.
+// The returned request carries the current org role.
+func enforceOrgMembership(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	tokenClaims, ok := r.Context().Value(utils.ClaimsKey).(dto.TokenClaimsDTO)
+	if !ok || tokenClaims.OrgId == 0 {
+		return r, true
+	}
+
+	orgRole, err := db.NewOrganizationRepository(dbConfig.DB).GetMembershipRole(r.Context(), tokenClaims.OrgId, int64(tokenClaims.UserId))
+	if err != nil {
+		if errors.Is(err, db.ErrNotOrgMember) {
+			metrics.AuthFailure(metrics.AuthForbidden)
+			utils.WriteErrorResponse(w, http.StatusForbidden, "You are not authorized to access this route", "Forbidden: "+db.ErrNotOrgMember.Error())
+			return r, false
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "You are not authorized to access this route", db.ErrInternalServerError.Error())
+		return r, false
+	}
+
+	tokenClaims.OrgRole = orgRole
+	return r.WithContext(context.WithValue(r.Context(), utils.ClaimsKey, tokenClaims)), true
+}
+
+func containsAllRoleNames(userRoles []string, required []string) bool {
+	for _, role := range required {
+		if !containsRoleName(userRoles, role) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAnyRoleName(userRoles []string, required []string) bool {
+	if len(required) == 0 {
+		return true
+	}
+	for _, role := range required {
+		if containsRoleName(userRoles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRoleName(userRoles []string, role string) bool {
+	for _, r := range userRoles {
+		if strings.EqualFold(role, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func claimStrings(value any) []string {
+	items, ok := value.([]any)
+	if !ok {
+		return []string{}
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+func claimString(value any) string {
+	str, _ := value.(string)
+	return str
+}
+
+func claimInt(value any) int {
+	number, ok := value.(float64)
+	if !ok {
+		return 0
+	}
+	return int(number)
+}
+
 func containsRole(roles []*models.Role, role string) bool {
 	for _, r := range roles {
 		if strings.EqualFold(role, r.Name) {
@@ -172,6 +397,7 @@ func RequireSelfOrAdmin() func(http.Handler) http.Handler {
 				}
 			}
 
+			metrics.AuthFailure(metrics.AuthForbidden)
 			utils.WriteErrorResponse(w, http.StatusForbidden, "Forbidden", "You are not allowed to access this resource")
 		})
 	}
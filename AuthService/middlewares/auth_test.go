@@ -0,0 +1,119 @@
+package middlewares
+
+import (
+	"AuthService/dto"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCheckSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		sessionId  string
+		cutoff     string
+		mock       func(mock sqlmock.Sqlmock)
+		wantOk     bool
+		wantStatus int
+	}{
+		{
+			name:      "active session",
+			sessionId: "session-1",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT revoked_at IS NOT NULL FROM user_sessions").WithArgs("session-1", int64(42)).
+					WillReturnRows(sqlmock.NewRows([]string{"revoked"}).AddRow(false))
+				mock.ExpectExec("UPDATE user_sessions SET last_seen_at").WithArgs(sqlmock.AnyArg(), "session-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantOk: true,
+		},
+		{
+			name:      "revoked session",
+			sessionId: "session-1",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT revoked_at IS NOT NULL FROM user_sessions").WithArgs("session-1", int64(42)).
+					WillReturnRows(sqlmock.NewRows([]string{"revoked"}).AddRow(true))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:      "unknown session",
+			sessionId: "session-1",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT revoked_at IS NOT NULL FROM user_sessions").WithArgs("session-1", int64(42)).
+					WillReturnRows(sqlmock.NewRows([]string{"revoked"}))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:      "database down",
+			sessionId: "session-1",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT revoked_at IS NOT NULL FROM user_sessions").WillReturnError(errors.New("connection refused"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "missing sid",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "missing sid before the cutoff",
+			cutoff: time.Now().Add(time.Hour).Format(time.RFC3339),
+			wantOk: true,
+		},
+		{
+			name:       "missing sid after the cutoff",
+			cutoff:     time.Now().Add(-time.Hour).Format(time.RFC3339),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing sid with an unreadable cutoff",
+			cutoff:     "next month",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SESSIONLESS_TOKENS_UNTIL", tt.cutoff)
+			mock := mockDB(t)
+			if tt.mock != nil {
+				tt.mock(mock)
+			}
+
+			recorder := httptest.NewRecorder()
+			ok := checkSession(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/problems", nil), dto.TokenClaimsDTO{UserId: 42, SessionId: tt.sessionId})
+			if ok != tt.wantOk {
+				t.Fatalf("checkSession() = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok && recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("queries: %v", err)
+			}
+		})
+	}
+}
+
+func TestRevokedSessionIsTurnedAway(t *testing.T) {
+	mock := mockDB(t)
+	mock.ExpectQuery("SELECT revoked_at IS NOT NULL FROM user_sessions").WithArgs("session-1", int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"revoked"}).AddRow(true))
+	handler := JWTAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler reached with a revoked session")
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, signedRequest(t, http.MethodGet, "/api/v1/problems", dto.TokenClaimsDTO{UserId: 42, Email: "ada@example.com", SessionId: "session-1"}))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("queries: %v", err)
+	}
+}
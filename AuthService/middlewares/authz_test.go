@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"AuthService/dto"
+	"AuthService/utils"
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// A request as JWTAuthMiddleware leaves it, carrying the user id and the token's claims
+func claimsRequest(claims dto.TokenClaimsDTO) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/roles", nil)
+	ctx := context.WithValue(request.Context(), utils.UserIDKey, dto.UserIdDTO{UserId: claims.UserId})
+	return request.WithContext(context.WithValue(ctx, utils.ClaimsKey, claims))
+}
+
+func TestRoleChecksTrustCurrentTokens(t *testing.T) {
+	tests := []struct {
+		name         string
+		userId       int
+		tokenVersion int
+		tokenRoles   []string
+		// Zero when the token carries no version and the stored one is never read
+		storedVersion int
+		// Whether the roles are read from the DB instead of taken from the token
+		readsRoles    bool
+		storedHasRole bool
+		want          int
+	}{
+		{name: "current token with the role", userId: 201, tokenVersion: 3, tokenRoles: []string{"admin"}, storedVersion: 3, want: http.StatusNoContent},
+		{name: "current token without the role", userId: 202, tokenVersion: 3, tokenRoles: []string{"user"}, storedVersion: 3, want: http.StatusUnauthorized},
+		{name: "role removed after the token was issued", userId: 203, tokenVersion: 3, tokenRoles: []string{"admin"}, storedVersion: 4, readsRoles: true, want: http.StatusUnauthorized},
+		{name: "role granted after the token was issued", userId: 204, tokenVersion: 3, tokenRoles: []string{"user"}, storedVersion: 4, readsRoles: true, storedHasRole: true, want: http.StatusNoContent},
+		{name: "token without a version", userId: 205, tokenRoles: []string{"admin"}, readsRoles: true, want: http.StatusUnauthorized},
+	}
+	checks := []struct {
+		name    string
+		require func(roles ...string) func(http.Handler) http.Handler
+		query   string
+		args    func(userId int) []driver.Value
+	}{
+		{name: "all roles", require: RequireAllRoles, query: "SELECT COUNT\\(\\*\\) = \\?", args: func(userId int) []driver.Value { return []driver.Value{1, userId, "admin"} }},
+		{name: "any role", require: RequireAnyRole, query: "SELECT COUNT\\(\\*\\) > 0 FROM user_roles", args: func(userId int) []driver.Value { return []driver.Value{userId, "admin"} }},
+	}
+	for i, check := range checks {
+		for _, tt := range tests {
+			t.Run(check.name+"/"+tt.name, func(t *testing.T) {
+				mock := mockDB(t)
+				// Each check reads the stored version afresh, not from the other check's cache entry
+				userId := tt.userId + i*100
+				if tt.storedVersion != 0 {
+					mock.ExpectQuery("SELECT authz_version FROM users").WithArgs(userId).
+						WillReturnRows(sqlmock.NewRows([]string{"authz_version"}).AddRow(tt.storedVersion))
+				}
+				if tt.readsRoles {
+					mock.ExpectQuery(check.query).WithArgs(check.args(userId)...).
+						WillReturnRows(sqlmock.NewRows([]string{"has_role"}).AddRow(tt.storedHasRole))
+				}
+
+				handler := check.require("admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNoContent)
+				}))
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, claimsRequest(dto.TokenClaimsDTO{UserId: userId, Roles: tt.tokenRoles, AuthzVersion: tt.tokenVersion}))
+
+				if recorder.Code != tt.want {
+					t.Errorf("status = %d, want %d", recorder.Code, tt.want)
+				}
+				if err := mock.ExpectationsWereMet(); err != nil {
+					t.Errorf("queries: %v", err)
+				}
+			})
+		}
+	}
+}
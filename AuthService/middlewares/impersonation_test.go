@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	dbConfig "AuthService/config/db"
+	"AuthService/dto"
+	"AuthService/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// Replaces the shared connection the middlewares use with a mock for the duration of the test
+func mockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	previous := dbConfig.DB
+	dbConfig.DB = conn
+	t.Cleanup(func() {
+		dbConfig.DB = previous
+		conn.Close()
+	})
+	return mock
+}
+
+func signedRequest(t *testing.T, method string, path string, claims dto.TokenClaimsDTO) *http.Request {
+	t.Helper()
+	t.Setenv("SECRET_KEY", "middleware-test-secret")
+	token, err := utils.CreateJwtToken(claims)
+	if err != nil {
+		t.Fatalf("CreateJwtToken() error = %v", err)
+	}
+	request := httptest.NewRequest(method, path, nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	return request
+}
+
+func TestImpersonatedRequestsAreAudited(t *testing.T) {
+	mock := mockDB(t)
+	claims := dto.TokenClaimsDTO{UserId: 42, Email: "ada@example.com", Roles: []string{"user"}, ImpersonatorId: 7, ExpiresAt: time.Now().Add(time.Minute).Unix()}
+	handler := JWTAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	requests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{method: http.MethodGet, path: "/api/v1/problems?page=2", wantStatus: http.StatusOK},
+		{method: http.MethodPost, path: "/api/v1/submission", wantStatus: http.StatusCreated},
+	}
+	for _, request := range requests {
+		// Sessions and suspensions are not checked for the admin acting as the user, the audit row is the only query
+		mock.ExpectExec("INSERT INTO impersonation_logs").
+			WithArgs(int64(7), int64(42), request.method, request.path, request.wantStatus, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, signedRequest(t, request.method, request.path, claims))
+		if recorder.Code != request.wantStatus {
+			t.Errorf("%s %s status = %d, want %d", request.method, request.path, recorder.Code, request.wantStatus)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("audit rows: %v", err)
+	}
+}
+
+func TestImpersonationTokenWithoutExpiryIsRejected(t *testing.T) {
+	mockDB(t)
+	handler := JWTAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler reached with an impersonation token that never expires")
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, signedRequest(t, http.MethodGet, "/api/v1/problems", dto.TokenClaimsDTO{UserId: 42, Email: "ada@example.com", ImpersonatorId: 7}))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"AuthService/utils"
+)
+
+// Lets scrapers through only with the configured bearer token, an empty token leaves the endpoint
+// open for setups where only the internal network can reach it
+func RequireMetricsToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token != "" {
+				given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+				if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+					utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "Invalid metrics token")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"net/http"
+
+	dbConfig "AuthService/config/db"
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/metrics"
+	"AuthService/policy"
+	"AuthService/utils"
+)
+
+func RequirePolicy(engine *policy.Engine) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject, fromToken, err := resolveSubject(r)
+			if err != nil {
+				utils.WriteErrorResponse(w, http.StatusInternalServerError, "You are not authorized to access this route", db.ErrInternalServerError.Error())
+				return
+			}
+
+			if !fromToken {
+				var ok bool
+				if r, ok = enforceOrgMembership(w, r); !ok {
+					return
+				}
+			}
+
+			decision := engine.Evaluate(subject, r.Method, r.URL.Path)
+			if !decision.Allowed {
+				metrics.AuthFailure(metrics.AuthForbidden)
+				utils.WriteErrorResponse(w, http.StatusForbidden, "You are not authorized to access this route", "Forbidden: "+decision.Reason)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Builds the policy subject from the token claims, or from the DB when they are stale.
+// The bool reports whether the token claims were current.
+func resolveSubject(r *http.Request) (policy.Subject, bool, error) {
+	userIdDto, ok := r.Context().Value(utils.UserIDKey).(dto.UserIdDTO)
+	if !ok {
+		return policy.Subject{}, false, db.ErrUserNotFound
+	}
+
+	urr := db.NewUserRoleRepository(dbConfig.DB)
+	if tokenClaims, ok := currentTokenClaims(r, urr); ok {
+		return policy.Subject{
+			UserId:      tokenClaims.UserId,
+			Roles:       tokenClaims.Roles,
+			Permissions: tokenClaims.Permissions,
+		}, true, nil
+	}
+
+	roles, err := urr.GetUserRoles(r.Context(), int64(userIdDto.UserId))
+	if err != nil {
+		return policy.Subject{}, false, err
+	}
+	permissions, err := urr.GetUserPermissions(r.Context(), int64(userIdDto.UserId))
+	if err != nil {
+		return policy.Subject{}, false, err
+	}
+
+	subject := policy.Subject{UserId: userIdDto.UserId}
+	for _, role := range roles {
+		subject.Roles = append(subject.Roles, role.Name)
+	}
+	for _, permission := range permissions {
+		subject.Permissions = append(subject.Permissions, permission.Name)
+	}
+	return subject, false, nil
+}
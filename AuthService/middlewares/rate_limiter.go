@@ -1,20 +1,65 @@
 package middlewares
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
-	"golang.org/x/time/rate"
+	"AuthService/metrics"
+	"AuthService/ratelimit"
+	"AuthService/utils"
+
+	"github.com/sirupsen/logrus"
 )
 
-var limiter = rate.NewLimiter(rate.Every(1*time.Second), 20) // 20 requests per second
+// Counts every request against a token bucket shared by all gateway replicas, per user when the
+// request carries a valid token and per IP otherwise. The route and the caller's roles pick the limit.
+// When Redis cannot be reached requests are let through, an outage there should not take the gateway down.
+func RateLimit(limiter *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bucket, limits := limiter.Config().Match(r.Method, r.URL.Path)
+
+			key := "ip:" + utils.ClientIP(r)
+			var limit ratelimit.Limit
+			if tokenClaims, ok := parseTokenClaims(requestToken(r)); ok {
+				key = "user:" + strconv.Itoa(tokenClaims.UserId)
+				limit = limits.For(true, tokenClaims.Roles)
+			} else {
+				limit = limits.For(false, nil)
+			}
+
+			result, err := limiter.Allow(r.Context(), bucket, key, limit)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"err":        err,
+					"bucket":     bucket,
+					"request_id": utils.GetRequestMeta(r.Context()).RequestId,
+					"type":       "rate_limit_error",
+				}).Error("Rate limit could not be checked")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			if !result.Allowed {
+				metrics.RateLimitRejected(bucket)
+				w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+				utils.WriteErrorResponse(w, http.StatusTooManyRequests, "Too many requests", map[string]any{
+					"code":        "rate_limited",
+					"bucket":      bucket,
+					"retry_after": max(ceilSeconds(result.RetryAfter), 1),
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-func RateLimitMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !limiter.Allow() {
-			http.Error(w, "Too many requests", http.StatusTooManyRequests)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
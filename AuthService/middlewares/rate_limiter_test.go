@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"AuthService/dto"
+	"AuthService/ratelimit"
+	"AuthService/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newRateLimitedHandler(t *testing.T) (http.Handler, *miniredis.Miniredis) {
+	t.Helper()
+	t.Setenv("SECRET_KEY", "rate-limit-test-secret")
+
+	server := miniredis.RunT(t)
+	server.SetTime(time.Unix(1760000000, 0))
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	config, err := ratelimit.Config{
+		Default: ratelimit.Limits{
+			Anonymous:     ratelimit.Limit{Rate: 1, Burst: 2},
+			Authenticated: ratelimit.Limit{Rate: 1, Burst: 3},
+			Roles:         map[string]ratelimit.Limit{"admin": {Rate: 10, Burst: 10}, "judge": {Rate: 5, Burst: 5}},
+		},
+		Routes: []ratelimit.RouteLimit{
+			{Name: "login", Prefix: "/auth/login", Methods: []string{"POST"}, Limits: ratelimit.Limits{Anonymous: ratelimit.Limit{Rate: 0.1, Burst: 1}}},
+		},
+	}.WithDefaults()
+	if err != nil {
+		t.Fatalf("WithDefaults() error = %v", err)
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return RateLimit(ratelimit.NewLimiter(client, config))(next), server
+}
+
+func rateLimitedRequest(t *testing.T, handler http.Handler, method string, path string, roles []string) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(method, path, nil)
+	request.RemoteAddr = "203.0.113.9:51000"
+	if roles != nil {
+		token, err := utils.CreateJwtToken(dto.TokenClaimsDTO{UserId: 42, Email: "ada@example.com", Roles: roles})
+		if err != nil {
+			t.Fatalf("CreateJwtToken() error = %v", err)
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRateLimitRejectsOverTheLimit(t *testing.T) {
+	handler, _ := newRateLimitedHandler(t)
+
+	for i := 0; i < 2; i++ {
+		if recorder := rateLimitedRequest(t, handler, http.MethodGet, "/problems", nil); recorder.Code != http.StatusNoContent {
+			t.Fatalf("request %d status = %d, want %d", i+1, recorder.Code, http.StatusNoContent)
+		}
+	}
+	recorder := rateLimitedRequest(t, handler, http.MethodGet, "/problems", nil)
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusTooManyRequests)
+	}
+	for header, want := range map[string]string{
+		"X-RateLimit-Limit":     "2",
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     "2",
+		"Retry-After":           "1",
+	} {
+		if got := recorder.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	var body struct {
+		Error struct {
+			Code   string `json:"code"`
+			Bucket string `json:"bucket"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body.Error.Code != "rate_limited" || body.Error.Bucket != ratelimit.DefaultBucket {
+		t.Errorf("body = %s", recorder.Body.String())
+	}
+}
+
+func TestRateLimitPicksTheLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		roles  []string
+		limit  string
+	}{
+		{name: "anonymous", method: http.MethodGet, path: "/problems", limit: "2"},
+		{name: "signed in", method: http.MethodGet, path: "/problems", roles: []string{"user"}, limit: "3"},
+		{name: "most generous role", method: http.MethodGet, path: "/problems", roles: []string{"judge", "admin", "user"}, limit: "10"},
+		{name: "route override", method: http.MethodPost, path: "/auth/login", limit: "1"},
+		{name: "route override for another method", method: http.MethodGet, path: "/auth/login", limit: "2"},
+		// Role limits belong to the limits they are listed in, the default's do not carry over to routes
+		{name: "route override without role limits", method: http.MethodPost, path: "/auth/login", roles: []string{"judge"}, limit: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newRateLimitedHandler(t)
+			recorder := rateLimitedRequest(t, handler, tt.method, tt.path, tt.roles)
+			if recorder.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d", recorder.Code, http.StatusNoContent)
+			}
+			if got := recorder.Header().Get("X-RateLimit-Limit"); got != tt.limit {
+				t.Errorf("X-RateLimit-Limit = %q, want %q", got, tt.limit)
+			}
+		})
+	}
+}
+
+func TestRateLimitBucketsAreSeparate(t *testing.T) {
+	handler, _ := newRateLimitedHandler(t)
+
+	if recorder := rateLimitedRequest(t, handler, http.MethodPost, "/auth/login", nil); recorder.Code != http.StatusNoContent {
+		t.Fatalf("first login status = %d", recorder.Code)
+	}
+	if recorder := rateLimitedRequest(t, handler, http.MethodPost, "/auth/login", nil); recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("second login status = %d, want %d", recorder.Code, http.StatusTooManyRequests)
+	}
+	// The same IP still has its default bucket, and a signed in user is counted on their own
+	if recorder := rateLimitedRequest(t, handler, http.MethodGet, "/problems", nil); recorder.Code != http.StatusNoContent {
+		t.Errorf("default bucket status = %d", recorder.Code)
+	}
+	if recorder := rateLimitedRequest(t, handler, http.MethodPost, "/auth/login", []string{"user"}); recorder.Code != http.StatusNoContent {
+		t.Errorf("signed in login status = %d", recorder.Code)
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	handler, server := newRateLimitedHandler(t)
+	server.Close()
+
+	for i := 0; i < 5; i++ {
+		recorder := rateLimitedRequest(t, handler, http.MethodGet, "/problems", nil)
+		if recorder.Code != http.StatusNoContent {
+			t.Fatalf("request %d status = %d, want %d", i+1, recorder.Code, http.StatusNoContent)
+		}
+		if recorder.Header().Get("X-RateLimit-Limit") != "" {
+			t.Error("rate limit headers were set without a result")
+		}
+	}
+}
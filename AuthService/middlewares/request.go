@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"AuthService/utils"
+)
+
+// Tags the request with an id, reusing the caller's X-Request-ID when it sent a sane one, and records the client address.
+// The X-Correlation-ID of the caller is kept the same way, a request starting new work is correlated by its own id.
+// Both are echoed back so a client can quote them when reporting a problem, and proxied upstream with the request.
+func RequestMetadata(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get("X-Request-ID")
+		if !validRequestId(requestId) {
+			requestId, _ = utils.RandomToken(8)
+		}
+		correlationId := r.Header.Get("X-Correlation-ID")
+		if !validRequestId(correlationId) {
+			correlationId = requestId
+		}
+		r.Header.Set("X-Request-ID", requestId)
+		r.Header.Set("X-Correlation-ID", correlationId)
+		w.Header().Set("X-Request-ID", requestId)
+		w.Header().Set("X-Correlation-ID", correlationId)
+
+		ctx := context.WithValue(r.Context(), utils.RequestMetaKey, utils.RequestMeta{
+			RequestId:     requestId,
+			CorrelationId: correlationId,
+			IpAddress:     utils.ClientIP(r),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Ids end up in logs and upstream headers, so only short ones made of plain characters are taken from callers
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	dbConfig "AuthService/config/db"
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/metrics"
+	"AuthService/models"
+	"AuthService/utils"
+)
+
+// Loads the active suspensions of the caller and turns fully suspended users away
+func checkSuspensions(w http.ResponseWriter, r *http.Request, tokenClaims dto.TokenClaimsDTO) ([]*models.Suspension, bool) {
+	authzVersion, err := db.NewUserRoleRepository(dbConfig.DB).GetAuthzVersion(r.Context(), tokenClaims.UserId)
+	if errors.Is(err, db.ErrUserNotFound) {
+		metrics.AuthFailure(metrics.AuthInvalidToken)
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "You are not authorized to access this route", "User not found")
+		return nil, false
+	}
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "You are not authorized to access this route", db.ErrInternalServerError.Error())
+		return nil, false
+	}
+
+	suspensions, err := db.NewSuspensionRepository(dbConfig.DB).GetActiveByUserId(r.Context(), int64(tokenClaims.UserId), authzVersion)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "You are not authorized to access this route", db.ErrInternalServerError.Error())
+		return nil, false
+	}
+
+	if suspension := findSuspension(suspensions, models.SuspensionScopeFull); suspension != nil {
+		writeSuspendedResponse(w, suspension)
+		return nil, false
+	}
+	return suspensions, true
+}
+
+// Rejects callers with an active suspension of the given scope, an empty scope lets everyone through
+func RequireNotSuspended(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scope == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			suspensions, _ := r.Context().Value(utils.SuspensionsKey).([]*models.Suspension)
+			if suspension := findSuspension(suspensions, scope); suspension != nil {
+				writeSuspendedResponse(w, suspension)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func findSuspension(suspensions []*models.Suspension, scope string) *models.Suspension {
+	for _, suspension := range suspensions {
+		if suspension.Scope == scope {
+			return suspension
+		}
+	}
+	return nil
+}
+
+// Tells the client why and until when, so it can show the user something better than a bare 403
+func writeSuspendedResponse(w http.ResponseWriter, suspension *models.Suspension) {
+	metrics.AuthFailure(metrics.AuthSuspended)
+	utils.WriteErrorResponse(w, http.StatusForbidden, "Your account is suspended", map[string]any{
+		"code":       "account_suspended",
+		"scope":      suspension.Scope,
+		"reason":     suspension.Reason,
+		"expires_at": suspension.ExpiresAt,
+	})
+}
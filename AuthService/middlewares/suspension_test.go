@@ -0,0 +1,128 @@
+package middlewares
+
+import (
+	"AuthService/dto"
+	"AuthService/models"
+	"AuthService/policy"
+	"AuthService/utils"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var suspensionColumns = []string{"id", "user_id", "scope", "reason", "created_by", "expires_at", "lifted_at", "lifted_by", "created_at"}
+
+func expectSession(mock sqlmock.Sqlmock, userId int) {
+	mock.ExpectQuery("SELECT revoked_at IS NOT NULL FROM user_sessions").WithArgs("session-1", int64(userId)).
+		WillReturnRows(sqlmock.NewRows([]string{"revoked"}).AddRow(false))
+	mock.ExpectExec("UPDATE user_sessions SET last_seen_at").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// The authz version and suspensions are only read while the caches hold nothing for the user
+func expectSuspensions(mock sqlmock.Sqlmock, userId int, authzVersion int, scopes ...string) {
+	mock.ExpectQuery("SELECT authz_version FROM users").WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows([]string{"authz_version"}).AddRow(authzVersion))
+	rows := sqlmock.NewRows(suspensionColumns)
+	for i, scope := range scopes {
+		rows.AddRow(i+1, userId, scope, "spam", 1, "", "", 0, "2026-10-19 12:00:00")
+	}
+	mock.ExpectQuery("FROM user_suspensions WHERE user_id = ?").WithArgs(int64(userId)).WillReturnRows(rows)
+}
+
+func sessionClaims(userId int) dto.TokenClaimsDTO {
+	return dto.TokenClaimsDTO{UserId: userId, Email: "ada@example.com", Roles: []string{"user"}, SessionId: "session-1"}
+}
+
+// Every proxied route of the gateway policy, guarded the way the router guards it
+func gatewayRoutes(t *testing.T) []policy.Route {
+	t.Helper()
+	doc, err := policy.LoadFile("../config/policy/gateway.yaml")
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	engine, err := policy.NewEngine(*doc)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	return engine.Routes()
+}
+
+func TestSuspensionsByRoute(t *testing.T) {
+	routes := gatewayRoutes(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name      string
+		userId    int
+		scopes    []string
+		wantBlock func(route policy.Route) bool
+	}{
+		{name: "not suspended", userId: 101, wantBlock: func(route policy.Route) bool { return false }},
+		{name: "full ban", userId: 102, scopes: []string{models.SuspensionScopeFull}, wantBlock: func(route policy.Route) bool { return true }},
+		{name: "submissions ban", userId: 103, scopes: []string{models.SuspensionScopeSubmissions}, wantBlock: func(route policy.Route) bool {
+			return route.Prefix == "/api/v1/submission"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockDB(t)
+			blocked := 0
+			for i, route := range routes {
+				expectSession(mock, tt.userId)
+				if i == 0 {
+					expectSuspensions(mock, tt.userId, 1, tt.scopes...)
+				}
+				handler := JWTAuthMiddleware(RequireNotSuspended(route.SuspensionScope)(next))
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, signedRequest(t, http.MethodGet, route.Prefix+"/1", sessionClaims(tt.userId)))
+
+				want := http.StatusNoContent
+				if tt.wantBlock(route) {
+					want = http.StatusForbidden
+					blocked++
+				}
+				if recorder.Code != want {
+					t.Errorf("%s status = %d, want %d", route.Prefix, recorder.Code, want)
+				}
+				if recorder.Code == http.StatusForbidden {
+					var body struct {
+						Error struct {
+							Code  string `json:"code"`
+							Scope string `json:"scope"`
+						} `json:"error"`
+					}
+					if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body.Error.Code != "account_suspended" || body.Error.Scope != tt.scopes[0] {
+						t.Errorf("%s body = %s, want the suspension", route.Prefix, strings.TrimSpace(recorder.Body.String()))
+					}
+				}
+			}
+			if tt.scopes != nil && blocked == 0 {
+				t.Errorf("no route blocked for %v", tt.scopes)
+			}
+			// Later requests were answered from the caches, the suspensions were read once
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("queries: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequireNotSuspendedWithoutScope(t *testing.T) {
+	handler := RequireNotSuspended("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/problems", nil)
+	suspensions := []*models.Suspension{{Id: 1, Scope: models.SuspensionScopeSubmissions}}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request.WithContext(context.WithValue(request.Context(), utils.SuspensionsKey, suspensions)))
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+}
@@ -99,3 +99,348 @@ func RoleUpdateRequestValidator(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+func PolicyExplainRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.PolicyExplainDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func AcceptInviteRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.AcceptInviteDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func OrganizationCreateRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.CreateOrganizationDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func OrgMemberUpdateRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.UpdateOrgMemberDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func OrgInvitationCreateRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.CreateOrgInvitationDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func OrgInvitationAcceptRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.AcceptOrgInvitationDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func ChangePasswordRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.ChangePasswordDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func ImpersonateUserRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.ImpersonateUserDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func MagicLinkRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.MagicLinkRequestDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func MagicLinkLoginRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.MagicLinkLoginDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func UpgradeGuestRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.UpgradeGuestDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func CreateSuspensionRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.CreateSuspensionDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func UpdateProfileRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.UpdateProfileDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func PreferencesRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.PreferencesDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func PatchPreferencesRequestValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.PatchPreferencesDTO
+
+		// Read and decode the JSON body into the payload
+		if err := utils.ReadJsonBody(r, &payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		// Validate the payload using the validator instance
+		if err := utils.Validator.Struct(payload); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+
+		req_context := r.Context()
+		ctx := context.WithValue(req_context, "payload", payload)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
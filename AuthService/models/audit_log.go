@@ -0,0 +1,27 @@
+package models
+
+import "encoding/json"
+
+// Changes maps every field that changed to its before and after value
+type AuditLog struct {
+	Id             int64           `json:"id"`
+	ActorId        int64           `json:"actor_id"`
+	ImpersonatorId int64           `json:"impersonator_id,omitempty"`
+	Action         string          `json:"action"`
+	TargetType     string          `json:"target_type"`
+	TargetId       string          `json:"target_id"`
+	Changes        json.RawMessage `json:"changes"`
+	RequestId      string          `json:"request_id"`
+	IpAddress      string          `json:"ip_address"`
+	CreatedAt      string          `json:"created_at"`
+}
+
+// Empty fields match every entry, From and To bound created_at
+type AuditLogFilter struct {
+	ActorId    int64
+	Action     string
+	TargetType string
+	TargetId   string
+	From       string
+	To         string
+}
@@ -0,0 +1,14 @@
+package models
+
+type ImpersonationLog struct {
+	Id             int64  `json:"id"`
+	ImpersonatorId int64  `json:"impersonator_id"`
+	UserId         int64  `json:"user_id"`
+	Action         string `json:"action"`
+	Method         string `json:"method,omitempty"`
+	Path           string `json:"path,omitempty"`
+	StatusCode     int    `json:"status_code,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+	IpAddress      string `json:"ip_address"`
+	CreatedAt      string `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+type LoginAttempt struct {
+	Id          int64  `json:"id"`
+	UserId      int64  `json:"user_id,omitempty"`
+	Email       string `json:"email"`
+	Outcome     string `json:"outcome"`
+	IpAddress   string `json:"ip_address"`
+	IpRange     string `json:"ip_range"`
+	UserAgent   string `json:"user_agent"`
+	DeviceLabel string `json:"device_label"`
+	NewDevice   bool   `json:"new_device"`
+	CreatedAt   string `json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+type Organization struct {
+	Id        int64  `json:"id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	CreatedBy int64  `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Role      string `json:"role,omitempty"`
+}
+
+type OrgMember struct {
+	OrgId    int64  `json:"org_id"`
+	UserId   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	JoinedAt string `json:"joined_at"`
+}
+
+type OrgInvitation struct {
+	Id         int64  `json:"id"`
+	OrgId      int64  `json:"org_id"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	InvitedBy  int64  `json:"invited_by"`
+	ExpiresAt  string `json:"expires_at"`
+	AcceptedAt string `json:"accepted_at,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+type Page[T any] struct {
+	Items []T `json:"items"`
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+	Total int `json:"total"`
+}
+
+// Keyset page, NextCursor is passed back as the cursor parameter to read on and is empty on the last page
+type CursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
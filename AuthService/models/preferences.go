@@ -0,0 +1,17 @@
+package models
+
+// Editor settings the ClientUI applies on every machine the user signs in from
+type Preferences struct {
+	EditorTheme string `json:"editor_theme"`
+	Language    string `json:"language"`
+	FontSize    int    `json:"font_size"`
+	Keybindings string `json:"keybindings"`
+}
+
+// Version 0 means the user never saved anything and gets the defaults
+type UserPreferences struct {
+	Preferences Preferences `json:"preferences"`
+	Version     int         `json:"version"`
+	Hash        string      `json:"hash"`
+	UpdatedAt   string      `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// Profile as its owner edits it, users without a saved profile get the defaults: empty fields, everything public
+type UserProfile struct {
+	UserId      int64          `json:"-"`
+	Username    string         `json:"username"`
+	DisplayName string         `json:"display_name"`
+	Bio         string         `json:"bio"`
+	AvatarKey   string         `json:"-"`
+	AvatarUrl   string         `json:"avatar_url"`
+	AvatarUrls  AvatarUrls     `json:"avatar_urls,omitempty"`
+	JoinedAt    string         `json:"joined_at"`
+	Privacy     ProfilePrivacy `json:"privacy"`
+}
+
+// Which profile fields anyone may see, the username is always public
+type ProfilePrivacy struct {
+	DisplayName bool `json:"display_name"`
+	Bio         bool `json:"bio"`
+	Avatar      bool `json:"avatar"`
+	JoinDate    bool `json:"join_date"`
+	Stats       bool `json:"stats"`
+}
+
+// What others see. It carries no email or ids, hidden fields are left out.
+type PublicProfile struct {
+	Username    string        `json:"username"`
+	DisplayName string        `json:"display_name,omitempty"`
+	Bio         string        `json:"bio,omitempty"`
+	AvatarUrl   string        `json:"avatar_url,omitempty"`
+	AvatarUrls  AvatarUrls    `json:"avatar_urls,omitempty"`
+	JoinedAt    string        `json:"joined_at,omitempty"`
+	Stats       *ProfileStats `json:"stats,omitempty"`
+}
+
+type ProfileStats struct {
+	Solved         int     `json:"solved"`
+	Attempted      int     `json:"attempted"`
+	Submissions    int     `json:"submissions"`
+	AcceptanceRate float64 `json:"acceptance_rate"`
+}
+
+// Thumbnail urls keyed by their side length in pixels
+type AvatarUrls map[string]string
@@ -6,6 +6,15 @@ type Role struct {
 	Description string `json:"description,omitempty"`
 	CreatedAt   string `json:"created_at,omitempty"`
 	UpdatedAt   string `json:"updated_at,omitempty"`
+	IsSystem    bool   `json:"is_system"`
+}
+
+type RoleMember struct {
+	UserRoleId int64  `json:"user_role_id"`
+	UserId     int64  `json:"user_id"`
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	AssignedAt string `json:"assigned_at"`
 }
 
 type Permission struct {
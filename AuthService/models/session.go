@@ -0,0 +1,14 @@
+package models
+
+type UserSession struct {
+	Id          int64  `json:"id"`
+	UserId      int64  `json:"user_id"`
+	TokenId     string `json:"-"`
+	DeviceLabel string `json:"device_label"`
+	UserAgent   string `json:"user_agent"`
+	IpAddress   string `json:"ip_address"`
+	CreatedAt   string `json:"created_at"`
+	LastSeenAt  string `json:"last_seen_at"`
+	RevokedAt   string `json:"revoked_at,omitempty"`
+	Current     bool   `json:"current"`
+}
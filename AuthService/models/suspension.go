@@ -0,0 +1,19 @@
+package models
+
+const (
+	SuspensionScopeFull        = "full"
+	SuspensionScopeSubmissions = "submissions"
+)
+
+// A suspension without ExpiresAt lasts until an admin lifts it
+type Suspension struct {
+	Id        int64  `json:"id"`
+	UserId    int64  `json:"user_id"`
+	Scope     string `json:"scope"`
+	Reason    string `json:"reason"`
+	CreatedBy int64  `json:"created_by"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	LiftedAt  string `json:"lifted_at,omitempty"`
+	LiftedBy  int64  `json:"lifted_by,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
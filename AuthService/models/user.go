@@ -8,3 +8,68 @@ type User struct {
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
+
+type UserImportEntry struct {
+	Row            int
+	Email          string
+	Username       string
+	HashedPassword string
+	RoleIds        []int
+}
+
+// Imports answered right away queue their invites instead of sending them, the outcome is only logged
+type UserImportResult struct {
+	Row          int    `json:"row"`
+	Email        string `json:"email"`
+	Username     string `json:"username"`
+	Status       string `json:"status"`
+	UserId       int64  `json:"user_id,omitempty"`
+	InviteSent   bool   `json:"invite_sent"`
+	InviteQueued bool   `json:"invite_queued,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type UserImportJob struct {
+	Id         string              `json:"id"`
+	Status     string              `json:"status"`
+	Total      int                 `json:"total"`
+	Processed  int                 `json:"processed"`
+	Created    int                 `json:"created"`
+	Failed     int                 `json:"failed"`
+	Results    []*UserImportResult `json:"results"`
+	CreatedAt  string              `json:"created_at"`
+	FinishedAt string              `json:"finished_at,omitempty"`
+}
+
+// Row of the admin users list
+type UserSummary struct {
+	Id         int64  `json:"id"`
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	IsVerified bool   `json:"is_verified"`
+	IsGuest    bool   `json:"is_guest"`
+	IsDeleted  bool   `json:"is_deleted"`
+	Suspended  bool   `json:"suspended"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// Nil flags and empty strings do not filter. Results are ordered by SortBy, then id, in the same direction.
+type UserListFilter struct {
+	Role        string
+	CreatedFrom string
+	CreatedTo   string
+	Deleted     *bool
+	Suspended   *bool
+	Verified    *bool
+	Search      string
+	SortBy      string
+	Descending  bool
+}
+
+// Position after the last row of a page, SortValue is that row's value of the sort column
+type UserListCursor struct {
+	SortBy    string `json:"s"`
+	SortValue string `json:"v"`
+	Id        int64  `json:"id"`
+}
@@ -0,0 +1,282 @@
+package policy
+
+import (
+	env "AuthService/config/env"
+	"AuthService/ratelimit"
+	"AuthService/upstream"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Outcome of evaluating a request, Trace explains how it was reached
+type Decision struct {
+	Allowed  bool     `json:"allowed"`
+	Route    string   `json:"route,omitempty"`
+	Upstream string   `json:"upstream,omitempty"`
+	Rule     string   `json:"rule,omitempty"`
+	Reason   string   `json:"reason"`
+	Trace    []string `json:"trace"`
+}
+
+type Engine struct {
+	doc Document
+}
+
+// Validates the document and runs its test cases before handing out an engine
+func NewEngine(doc Document) (*Engine, error) {
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+
+	engine := &Engine{doc: doc}
+	if err := engine.Verify(); err != nil {
+		return nil, err
+	}
+
+	return engine, nil
+}
+
+func (e *Engine) Routes() []Route {
+	return e.doc.Routes
+}
+
+func (e *Engine) RateLimits() ratelimit.Config {
+	return e.doc.RateLimits
+}
+
+// Resolves the base urls of an upstream's replicas from the environment
+func (e *Engine) UpstreamURLs(name string) []string {
+	config := e.doc.Upstreams[name]
+	value := config.Default
+	if config.Env != "" {
+		value = env.GetString(config.Env, config.Default)
+	}
+
+	urls := []string{}
+	for _, url := range strings.Split(value, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// Builds a balanced pool for every upstream, its health checks start with Registry.Start
+func (e *Engine) UpstreamPools() (*upstream.Registry, error) {
+	names := make([]string, 0, len(e.doc.Upstreams))
+	for name := range e.doc.Upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pools := make([]*upstream.Pool, 0, len(names))
+	for _, name := range names {
+		pool, err := upstream.NewPool(name, e.UpstreamURLs(name), e.doc.Upstreams[name].Config)
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+	return upstream.NewRegistry(pools...), nil
+}
+
+func (e *Engine) Evaluate(subject Subject, method string, path string) Decision {
+	decision := Decision{Trace: []string{}}
+
+	route, ok := e.matchRoute(path)
+	if !ok {
+		decision.Reason = "no route matches " + path
+		return decision
+	}
+	decision.Route = route.Prefix
+	decision.Upstream = route.Upstream
+
+	rest := strings.TrimPrefix(path, route.Prefix)
+	for _, rule := range route.Rules {
+		if !matchMethod(rule.Methods, method) {
+			decision.Trace = append(decision.Trace, fmt.Sprintf("%s: method %s not listed", rule.Name, method))
+			continue
+		}
+		params, ok := matchPath(rule.Path, rest)
+		if !ok {
+			decision.Trace = append(decision.Trace, fmt.Sprintf("%s: path %s does not match %s", rule.Name, rest, rule.Path))
+			continue
+		}
+
+		if reason := checkRequirements(rule, subject, params); reason != "" {
+			decision.Trace = append(decision.Trace, fmt.Sprintf("%s: %s", rule.Name, reason))
+			// A denial is reported against the first matching rule
+			if decision.Rule == "" {
+				decision.Rule = rule.Name
+				decision.Reason = reason
+			}
+			continue
+		}
+
+		decision.Trace = append(decision.Trace, fmt.Sprintf("%s: all requirements met", rule.Name))
+		decision.Allowed = true
+		decision.Rule = rule.Name
+		decision.Reason = "allowed by " + rule.Name
+		return decision
+	}
+
+	if decision.Rule == "" {
+		decision.Reason = fmt.Sprintf("no rule under %s matches %s %s", route.Prefix, method, path)
+	}
+	return decision
+}
+
+// Runs the test cases of the policy, every rule must be exercised by at least one
+func (e *Engine) Verify() error {
+	var failures []string
+	covered := map[string]bool{}
+
+	for _, test := range e.doc.Tests {
+		decision := e.Evaluate(test.Subject, test.Method, test.Path)
+		covered[decision.Rule] = true
+
+		if decision.Allowed != (test.Expect == "allow") {
+			failures = append(failures, fmt.Sprintf("test %q: expected %s, got %q", test.Name, test.Expect, decision.Reason))
+			continue
+		}
+		if test.Rule != "" && decision.Rule != test.Rule {
+			failures = append(failures, fmt.Sprintf("test %q: expected rule %s, decided by %q", test.Name, test.Rule, decision.Rule))
+		}
+	}
+
+	for _, route := range e.doc.Routes {
+		for _, rule := range route.Rules {
+			if !covered[rule.Name] {
+				failures = append(failures, fmt.Sprintf("rule %q has no test", rule.Name))
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidPolicy, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Picks the route with the longest prefix containing the path
+func (e *Engine) matchRoute(path string) (Route, bool) {
+	var best Route
+	found := false
+	for _, route := range e.doc.Routes {
+		if path != route.Prefix && !strings.HasPrefix(path, route.Prefix+"/") {
+			continue
+		}
+		if !found || len(route.Prefix) > len(best.Prefix) {
+			best = route
+			found = true
+		}
+	}
+	return best, found
+}
+
+func matchMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == "*" || strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches a path relative to the route prefix against a pattern like /user/{userId}/*
+func matchPath(pattern string, path string) (map[string]string, bool) {
+	if pattern == "" {
+		pattern = "/*"
+	}
+	patternSegments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	params := map[string]string{}
+
+	for i, segment := range patternSegments {
+		if segment == "*" && i == len(patternSegments)-1 {
+			return params, true
+		}
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+
+	if len(pathSegments) != len(patternSegments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// Returns why the subject fails the rule, or an empty string when it passes
+func checkRequirements(rule Rule, subject Subject, params map[string]string) string {
+	if len(rule.AnyRoles) > 0 && !containsAny(subject.Roles, rule.AnyRoles) {
+		return "requires any of roles " + strings.Join(rule.AnyRoles, ",")
+	}
+	for _, role := range rule.AllRoles {
+		if !contains(subject.Roles, role) {
+			return "requires role " + role
+		}
+	}
+	for _, permission := range rule.Permissions {
+		if !contains(subject.Permissions, permission) {
+			return "requires permission " + permission
+		}
+	}
+	for _, condition := range rule.Conditions {
+		kind, arg, _ := parseCondition(condition)
+		switch kind {
+		case "owner":
+			if params[arg] != strconv.Itoa(subject.UserId) {
+				return fmt.Sprintf("only the owner (%s) may access this resource", arg)
+			}
+		default:
+			// Validation rejects these, a rule that slipped through must not allow more than it says
+			return fmt.Sprintf("unknown condition %q", condition)
+		}
+	}
+	return ""
+}
+
+// Conditions are written as kind:argument, e.g. owner:userId
+func parseCondition(condition string) (string, string, error) {
+	kind, arg, _ := strings.Cut(condition, ":")
+	switch kind {
+	case "owner":
+		if arg == "" {
+			return "", "", errors.New("owner condition needs a path parameter, e.g. owner:userId")
+		}
+		return kind, arg, nil
+	}
+	return "", "", fmt.Errorf("unknown condition %q", condition)
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if strings.EqualFold(i, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(items []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if contains(items, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,284 @@
+package policy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		ok      bool
+		params  map[string]string
+	}{
+		{"wildcard matches the prefix itself", "/*", "", true, map[string]string{}},
+		{"wildcard matches a trailing slash", "/*", "/", true, map[string]string{}},
+		{"wildcard matches nested paths", "/*", "/a/b/c", true, map[string]string{}},
+		{"empty pattern is a wildcard", "", "/a/b", true, map[string]string{}},
+		{"root matches the prefix itself", "/", "", true, map[string]string{}},
+		{"root matches a trailing slash", "/", "/", true, map[string]string{}},
+		{"root does not match a child", "/", "/abc", false, nil},
+		{"param captures a segment", "/{id}", "/abc", true, map[string]string{"id": "abc"}},
+		{"param needs a value", "/{id}", "/", false, nil},
+		{"param does not match the prefix itself", "/{id}", "", false, nil},
+		{"param does not match a trailing slash", "/{id}", "/abc/", false, nil},
+		{"param does not match two segments", "/{id}", "/a/b", false, nil},
+		{"literal segment must match", "/problem/{id}", "/problems/abc", false, nil},
+		{"literal and param", "/problem/{id}", "/problem/abc", true, map[string]string{"id": "abc"}},
+		{"param then wildcard matches nothing after", "/user/{userId}/*", "/user/2", true, map[string]string{"userId": "2"}},
+		{"param then wildcard matches the rest", "/user/{userId}/*", "/user/2/a/b", true, map[string]string{"userId": "2"}},
+		{"param then wildcard needs the param", "/user/{userId}/*", "/user", false, nil},
+		{"wildcard inside a pattern is literal", "/a/*/b", "/a/x/b", false, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, ok := matchPath(test.pattern, test.path)
+			if ok != test.ok {
+				t.Fatalf("matchPath(%q, %q) matched = %v, want %v", test.pattern, test.path, ok, test.ok)
+			}
+			if ok && !reflect.DeepEqual(params, test.params) {
+				t.Errorf("matchPath(%q, %q) params = %v, want %v", test.pattern, test.path, params, test.params)
+			}
+		})
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	engine := &Engine{doc: Document{Routes: []Route{
+		{Prefix: "/api/v1/problem"},
+		{Prefix: "/api/v1/problem/admin"},
+	}}}
+
+	tests := []struct {
+		path   string
+		ok     bool
+		prefix string
+	}{
+		{"/api/v1/problem", true, "/api/v1/problem"},
+		{"/api/v1/problem/", true, "/api/v1/problem"},
+		{"/api/v1/problem/42", true, "/api/v1/problem"},
+		{"/api/v1/problem/admin", true, "/api/v1/problem/admin"},
+		{"/api/v1/problem/admin/42", true, "/api/v1/problem/admin"},
+		{"/api/v1/problem/administrators", true, "/api/v1/problem"},
+		{"/api/v1/problems", false, ""},
+		{"/api/v1", false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			route, ok := engine.matchRoute(test.path)
+			if ok != test.ok {
+				t.Fatalf("matchRoute(%q) matched = %v, want %v", test.path, ok, test.ok)
+			}
+			if ok && route.Prefix != test.prefix {
+				t.Errorf("matchRoute(%q) = %q, want %q", test.path, route.Prefix, test.prefix)
+			}
+		})
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		condition string
+		kind      string
+		arg       string
+		ok        bool
+	}{
+		{"owner:userId", "owner", "userId", true},
+		{"owner", "", "", false},
+		{"owner:", "", "", false},
+		{"member:orgId", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.condition, func(t *testing.T) {
+			kind, arg, err := parseCondition(test.condition)
+			if (err == nil) != test.ok {
+				t.Fatalf("parseCondition(%q) error = %v, want ok %v", test.condition, err, test.ok)
+			}
+			if kind != test.kind || arg != test.arg {
+				t.Errorf("parseCondition(%q) = %q, %q, want %q, %q", test.condition, kind, arg, test.kind, test.arg)
+			}
+		})
+	}
+}
+
+func TestCheckRequirements(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		subject Subject
+		params  map[string]string
+		allowed bool
+	}{
+		{"no requirements allow anyone", Rule{}, Subject{UserId: 1}, nil, true},
+		{"empty role list allows a subject without roles", Rule{AnyRoles: []string{}}, Subject{UserId: 1}, nil, true},
+		{"any role matches one of them", Rule{AnyRoles: []string{"user", "admin"}}, Subject{Roles: []string{"admin"}}, nil, true},
+		{"any role compares case insensitively", Rule{AnyRoles: []string{"admin"}}, Subject{Roles: []string{"Admin"}}, nil, true},
+		{"any role denies other roles", Rule{AnyRoles: []string{"admin"}}, Subject{Roles: []string{"user"}}, nil, false},
+		{"any role denies a subject without roles", Rule{AnyRoles: []string{"user"}}, Subject{}, nil, false},
+		{"all roles needs every role", Rule{AllRoles: []string{"user", "editor"}}, Subject{Roles: []string{"user", "editor"}}, nil, true},
+		{"all roles denies a missing role", Rule{AllRoles: []string{"user", "editor"}}, Subject{Roles: []string{"user"}}, nil, false},
+		{"permission present", Rule{Permissions: []string{"problem:write"}}, Subject{Permissions: []string{"problem:write"}}, nil, true},
+		{"permission missing", Rule{Permissions: []string{"problem:write"}}, Subject{Permissions: []string{"problem:read"}}, nil, false},
+		{"owner matches the path parameter", Rule{Conditions: []string{"owner:userId"}}, Subject{UserId: 2}, map[string]string{"userId": "2"}, true},
+		{"owner denies another user", Rule{Conditions: []string{"owner:userId"}}, Subject{UserId: 2}, map[string]string{"userId": "5"}, false},
+		{"owner denies a missing parameter", Rule{Conditions: []string{"owner:userId"}}, Subject{UserId: 2}, map[string]string{}, false},
+		{"unknown condition denies", Rule{Conditions: []string{"member:orgId"}}, Subject{UserId: 2}, map[string]string{"orgId": "2"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason := checkRequirements(test.rule, test.subject, test.params)
+			if (reason == "") != test.allowed {
+				t.Errorf("checkRequirements() = %q, want allowed %v", reason, test.allowed)
+			}
+		})
+	}
+}
+
+func testDocument() Document {
+	return Document{
+		Upstreams: map[string]Upstream{
+			"problem":    {Default: "http://localhost:3000/api/v1"},
+			"submission": {Default: "http://localhost:3002/api/v1"},
+		},
+		Routes: []Route{
+			{
+				Prefix:   "/api/v1/problem",
+				Upstream: "problem",
+				Rules: []Rule{
+					{Name: "problem-read", Methods: []string{"GET"}, Path: "/*", AnyRoles: []string{"user", "admin"}},
+					{Name: "problem-write", Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Path: "/*", AnyRoles: []string{"admin"}},
+				},
+			},
+			{
+				Prefix:   "/api/v1/submission",
+				Upstream: "submission",
+				Rules: []Rule{
+					{Name: "submission-read-own", Methods: []string{"GET"}, Path: "/user/{userId}/*", AnyRoles: []string{"user"}, Conditions: []string{"owner:userId"}},
+					{Name: "submission-create", Methods: []string{"POST"}, Path: "/", AnyRoles: []string{"user", "guest"}},
+					{Name: "submission-any", Methods: []string{"*"}, Path: "/*", AllRoles: []string{"admin"}},
+				},
+			},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	doc := testDocument()
+	if err := doc.validate(); err != nil {
+		t.Fatalf("validate() = %v", err)
+	}
+	engine := &Engine{doc: doc}
+
+	user := Subject{UserId: 2, Roles: []string{"user"}}
+	guest := Subject{UserId: 9, Roles: []string{"guest"}}
+	admin := Subject{UserId: 1, Roles: []string{"admin"}}
+	nobody := Subject{UserId: 3}
+
+	tests := []struct {
+		name    string
+		subject Subject
+		method  string
+		path    string
+		allowed bool
+		rule    string
+	}{
+		{"user reads problems", user, "GET", "/api/v1/problem/42", true, "problem-read"},
+		{"user reads the problem list", user, "GET", "/api/v1/problem", true, "problem-read"},
+		{"method matches case insensitively", user, "get", "/api/v1/problem/42", true, "problem-read"},
+		{"subject without roles cannot read problems", nobody, "GET", "/api/v1/problem/42", false, "problem-read"},
+		{"user cannot write problems", user, "DELETE", "/api/v1/problem/42", false, "problem-write"},
+		{"admin writes problems", admin, "PUT", "/api/v1/problem/42", true, "problem-write"},
+		{"unlisted method is denied", admin, "OPTIONS", "/api/v1/problem/42", false, ""},
+		{"user reads own submissions", user, "GET", "/api/v1/submission/user/2/abc", true, "submission-read-own"},
+		{"user cannot read other submissions", user, "GET", "/api/v1/submission/user/5", false, "submission-read-own"},
+		{"user creates a submission", user, "POST", "/api/v1/submission", true, "submission-create"},
+		{"user creates a submission with a trailing slash", user, "POST", "/api/v1/submission/", true, "submission-create"},
+		{"guest creates a submission", guest, "POST", "/api/v1/submission", true, "submission-create"},
+		{"user cannot post below the root", user, "POST", "/api/v1/submission/abc", false, "submission-any"},
+		{"admin matches the method wildcard", admin, "PATCH", "/api/v1/submission/abc", true, "submission-any"},
+		{"admin reads any submissions", admin, "GET", "/api/v1/submission/user/5", true, "submission-any"},
+		{"route prefix must end at a segment", admin, "GET", "/api/v1/submissions", false, ""},
+		{"unknown route is denied", admin, "GET", "/api/v1/unknown", false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision := engine.Evaluate(test.subject, test.method, test.path)
+			if decision.Allowed != test.allowed {
+				t.Fatalf("Evaluate() allowed = %v, want %v: %s", decision.Allowed, test.allowed, decision.Reason)
+			}
+			if decision.Rule != test.rule {
+				t.Errorf("Evaluate() rule = %q, want %q", decision.Rule, test.rule)
+			}
+		})
+	}
+}
+
+func TestNewEngineRejectsInvalidPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(doc *Document)
+	}{
+		{"unknown condition", func(doc *Document) {
+			doc.Routes[0].Rules[0].Conditions = []string{"member:orgId"}
+		}},
+		{"duplicate rule name", func(doc *Document) {
+			doc.Routes[0].Rules[1].Name = doc.Routes[0].Rules[0].Name
+		}},
+		{"rule without methods", func(doc *Document) {
+			doc.Routes[0].Rules[0].Methods = nil
+		}},
+		{"prefix with a trailing slash", func(doc *Document) {
+			doc.Routes[0].Prefix = "/api/v1/problem/"
+		}},
+		{"unknown upstream", func(doc *Document) {
+			doc.Routes[0].Upstream = "missing"
+		}},
+		{"rule without a test", func(doc *Document) {
+			doc.Tests = doc.Tests[1:]
+		}},
+		{"failing test", func(doc *Document) {
+			doc.Tests[0].Expect = "deny"
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := testDocument()
+			doc.Tests = []TestCase{
+				{Name: "read", Method: "GET", Path: "/api/v1/problem/1", Subject: Subject{Roles: []string{"user"}}, Expect: "allow", Rule: "problem-read"},
+				{Name: "write", Method: "POST", Path: "/api/v1/problem", Subject: Subject{Roles: []string{"admin"}}, Expect: "allow", Rule: "problem-write"},
+				{Name: "own", Method: "GET", Path: "/api/v1/submission/user/2", Subject: Subject{UserId: 2, Roles: []string{"user"}}, Expect: "allow", Rule: "submission-read-own"},
+				{Name: "create", Method: "POST", Path: "/api/v1/submission", Subject: Subject{Roles: []string{"guest"}}, Expect: "allow", Rule: "submission-create"},
+				{Name: "any", Method: "DELETE", Path: "/api/v1/submission/1", Subject: Subject{Roles: []string{"admin"}}, Expect: "allow", Rule: "submission-any"},
+			}
+			if _, err := NewEngine(doc); err != nil {
+				t.Fatalf("NewEngine() on the valid document = %v", err)
+			}
+
+			test.modify(&doc)
+			if _, err := NewEngine(doc); !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("NewEngine() = %v, want %v", err, ErrInvalidPolicy)
+			}
+		})
+	}
+}
+
+// The tests of the shipped policy, the same ones run on startup
+func TestGatewayPolicy(t *testing.T) {
+	doc, err := LoadFile("../config/policy/gateway.yaml")
+	if err != nil {
+		t.Fatalf("LoadFile() = %v", err)
+	}
+	if _, err := NewEngine(*doc); err != nil {
+		t.Fatalf("NewEngine() = %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"AuthService/models"
+	"AuthService/ratelimit"
+	"AuthService/upstream"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+var (
+	ErrInvalidPolicy = errors.New("invalid policy")
+)
+
+// Policy file describing which upstream serves a path, who may call it and how often
+type Document struct {
+	Upstreams  map[string]Upstream `json:"upstreams"`
+	Routes     []Route             `json:"routes"`
+	RateLimits ratelimit.Config    `json:"rateLimits"`
+	Tests      []TestCase          `json:"tests"`
+}
+
+// Upstream base urls, read from Env when set. Several replicas are separated by commas.
+// The embedded config picks the balancer, health check and outlier ejection of the replicas.
+type Upstream struct {
+	Env     string `json:"env"`
+	Default string `json:"default"`
+	upstream.Config
+}
+
+// All requests under Prefix are proxied to Upstream once a rule allows them.
+// Users with an active suspension of SuspensionScope are turned away from the whole route.
+// The embedded config sets the timeout and retries of the proxied requests.
+type Route struct {
+	Prefix          string `json:"prefix"`
+	Upstream        string `json:"upstream"`
+	SuspensionScope string `json:"suspensionScope"`
+	Rules           []Rule `json:"rules"`
+	upstream.RouteConfig
+}
+
+// A request is allowed when any rule matching its method and path is satisfied
+type Rule struct {
+	Name        string   `json:"name"`
+	Methods     []string `json:"methods"`
+	Path        string   `json:"path"`
+	AnyRoles    []string `json:"anyRoles"`
+	AllRoles    []string `json:"allRoles"`
+	Permissions []string `json:"permissions"`
+	Conditions  []string `json:"conditions"`
+}
+
+// Expected decision for a request, checked every time the policy is loaded
+type TestCase struct {
+	Name    string  `json:"name"`
+	Method  string  `json:"method"`
+	Path    string  `json:"path"`
+	Subject Subject `json:"subject"`
+	Expect  string  `json:"expect"`
+	Rule    string  `json:"rule"`
+}
+
+// Caller the policy is evaluated for
+type Subject struct {
+	UserId      int      `json:"userId"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// Reads a YAML or JSON policy file, selected by extension
+func LoadFile(path string) (*Document, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		content, err = yaml.YAMLToJSON(content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, err)
+		}
+	case ".json":
+	default:
+		return nil, fmt.Errorf("%w: unsupported file type %s", ErrInvalidPolicy, path)
+	}
+
+	var doc Document
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, err)
+	}
+
+	return &doc, nil
+}
+
+func (d *Document) validate() error {
+	ruleNames := map[string]bool{}
+	prefixes := map[string]bool{}
+
+	for name, config := range d.Upstreams {
+		if err := config.Validate(); err != nil {
+			return fmt.Errorf("%w: upstream %q: %s", ErrInvalidPolicy, name, err)
+		}
+	}
+
+	for i, route := range d.Routes {
+		if !strings.HasPrefix(route.Prefix, "/") || strings.HasSuffix(route.Prefix, "/") {
+			return fmt.Errorf("%w: route prefix %q must start and not end with /", ErrInvalidPolicy, route.Prefix)
+		}
+		if prefixes[route.Prefix] {
+			return fmt.Errorf("%w: duplicate route prefix %q", ErrInvalidPolicy, route.Prefix)
+		}
+		prefixes[route.Prefix] = true
+
+		if _, ok := d.Upstreams[route.Upstream]; !ok {
+			return fmt.Errorf("%w: route %q uses unknown upstream %q", ErrInvalidPolicy, route.Prefix, route.Upstream)
+		}
+		// Full suspensions are enforced on every route already
+		if route.SuspensionScope != "" && route.SuspensionScope != models.SuspensionScopeSubmissions {
+			return fmt.Errorf("%w: route %q uses unknown suspension scope %q", ErrInvalidPolicy, route.Prefix, route.SuspensionScope)
+		}
+		routeConfig, err := route.RouteConfig.WithDefaults()
+		if err != nil {
+			return fmt.Errorf("%w: route %q: %s", ErrInvalidPolicy, route.Prefix, err)
+		}
+		d.Routes[i].RouteConfig = routeConfig
+
+		for _, rule := range route.Rules {
+			if rule.Name == "" {
+				return fmt.Errorf("%w: every rule under %q needs a name", ErrInvalidPolicy, route.Prefix)
+			}
+			if ruleNames[rule.Name] {
+				return fmt.Errorf("%w: duplicate rule name %q", ErrInvalidPolicy, rule.Name)
+			}
+			ruleNames[rule.Name] = true
+
+			if len(rule.Methods) == 0 {
+				return fmt.Errorf("%w: rule %q has no methods", ErrInvalidPolicy, rule.Name)
+			}
+			for _, condition := range rule.Conditions {
+				if _, _, err := parseCondition(condition); err != nil {
+					return fmt.Errorf("%w: rule %q: %s", ErrInvalidPolicy, rule.Name, err)
+				}
+			}
+		}
+	}
+
+	rateLimits, err := d.RateLimits.WithDefaults()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPolicy, err)
+	}
+	d.RateLimits = rateLimits
+
+	for _, test := range d.Tests {
+		if test.Expect != "allow" && test.Expect != "deny" {
+			return fmt.Errorf("%w: test %q must expect allow or deny", ErrInvalidPolicy, test.Name)
+		}
+		if test.Rule != "" && !ruleNames[test.Rule] {
+			return fmt.Errorf("%w: test %q references unknown rule %q", ErrInvalidPolicy, test.Name, test.Rule)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const DefaultBucket = "default"
+
+var (
+	ErrInvalidConfig = errors.New("invalid rate limit config")
+)
+
+// Default applies to every request no route limit matches
+type Config struct {
+	Default Limits       `json:"default"`
+	Routes  []RouteLimit `json:"routes"`
+}
+
+// Requests under Prefix with one of Methods use this bucket instead of the default one.
+// No methods means every method.
+type RouteLimit struct {
+	Name    string   `json:"name"`
+	Prefix  string   `json:"prefix"`
+	Methods []string `json:"methods"`
+	Limits
+}
+
+// Anonymous callers are limited per IP, signed in ones per user. A user gets the most generous limit
+// among their roles listed in Roles, and the Authenticated one when none are listed.
+type Limits struct {
+	Anonymous     Limit            `json:"anonymous"`
+	Authenticated Limit            `json:"authenticated"`
+	Roles         map[string]Limit `json:"roles"`
+}
+
+// A token bucket refilled at Rate tokens per second and holding at most Burst, 0.1 is one request every 10 seconds
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+func (l Limit) isZero() bool {
+	return l.Rate == 0 && l.Burst == 0
+}
+
+func (l Limit) validate() error {
+	if l.Rate <= 0 || l.Burst < 1 {
+		return fmt.Errorf("%w: a limit needs a positive rate and a burst of at least 1", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// Fills in what the config leaves out and rejects what makes no sense. Without a default,
+// anonymous callers get 10 requests per second and signed in ones 20, bursting to twice that.
+// Route limits take the default's anonymous and authenticated limits unless they set their own.
+func (c Config) WithDefaults() (Config, error) {
+	if c.Default.Anonymous.isZero() {
+		c.Default.Anonymous = Limit{Rate: 10, Burst: 20}
+	}
+	if c.Default.Authenticated.isZero() {
+		c.Default.Authenticated = Limit{Rate: 20, Burst: 40}
+	}
+	if err := c.Default.validate(); err != nil {
+		return c, fmt.Errorf("default: %w", err)
+	}
+
+	names := map[string]bool{DefaultBucket: true}
+	routes := make([]RouteLimit, 0, len(c.Routes))
+	for _, route := range c.Routes {
+		if route.Name == "" || names[route.Name] {
+			return c, fmt.Errorf("%w: route limit names must be unique and not %q", ErrInvalidConfig, DefaultBucket)
+		}
+		names[route.Name] = true
+		if !strings.HasPrefix(route.Prefix, "/") {
+			return c, fmt.Errorf("%w: route limit %q needs a prefix starting with /", ErrInvalidConfig, route.Name)
+		}
+		for i, method := range route.Methods {
+			route.Methods[i] = strings.ToUpper(method)
+		}
+
+		if route.Anonymous.isZero() {
+			route.Anonymous = c.Default.Anonymous
+		}
+		if route.Authenticated.isZero() {
+			route.Authenticated = c.Default.Authenticated
+		}
+		if err := route.validate(); err != nil {
+			return c, fmt.Errorf("route limit %q: %w", route.Name, err)
+		}
+		routes = append(routes, route)
+	}
+	c.Routes = routes
+	return c, nil
+}
+
+func (l Limits) validate() error {
+	if err := l.Anonymous.validate(); err != nil {
+		return err
+	}
+	if err := l.Authenticated.validate(); err != nil {
+		return err
+	}
+	for _, limit := range l.Roles {
+		if err := limit.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The bucket a request is counted in, the first matching route limit or the default
+func (c Config) Match(method string, path string) (string, Limits) {
+	for _, route := range c.Routes {
+		if !matchPrefix(route.Prefix, path) {
+			continue
+		}
+		if len(route.Methods) > 0 && !contains(route.Methods, method) {
+			continue
+		}
+		return route.Name, route.Limits
+	}
+	return DefaultBucket, c.Default
+}
+
+// The limit of a caller, anonymous when authenticated is false
+func (l Limits) For(authenticated bool, roles []string) Limit {
+	if !authenticated {
+		return l.Anonymous
+	}
+	limit, found := Limit{}, false
+	for _, role := range roles {
+		if roleLimit, ok := l.Roles[role]; ok && (!found || roleLimit.Rate > limit.Rate) {
+			limit, found = roleLimit, true
+		}
+	}
+	if !found {
+		return l.Authenticated
+	}
+	return limit
+}
+
+func matchPrefix(prefix string, path string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+func contains(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+)
+
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	config, err := Config{
+		Default: Limits{
+			Roles: map[string]Limit{
+				"admin":  {Rate: 100, Burst: 200},
+				"judge":  {Rate: 50, Burst: 100},
+				"viewer": {Rate: 5, Burst: 10},
+			},
+		},
+		Routes: []RouteLimit{
+			{Name: "login", Prefix: "/auth/login", Methods: []string{"post"}, Limits: Limits{Anonymous: Limit{Rate: 0.1, Burst: 5}}},
+			{Name: "submission", Prefix: "/submission/", Limits: Limits{Authenticated: Limit{Rate: 1, Burst: 3}}},
+		},
+	}.WithDefaults()
+	if err != nil {
+		t.Fatalf("WithDefaults() error = %v", err)
+	}
+	return config
+}
+
+func TestLimitsFor(t *testing.T) {
+	limits := testConfig(t).Default
+	tests := []struct {
+		name          string
+		authenticated bool
+		roles         []string
+		want          Limit
+	}{
+		{name: "anonymous", want: Limit{Rate: 10, Burst: 20}},
+		{name: "anonymous roles are ignored", roles: []string{"admin"}, want: Limit{Rate: 10, Burst: 20}},
+		{name: "no roles", authenticated: true, want: Limit{Rate: 20, Burst: 40}},
+		{name: "unlisted role", authenticated: true, roles: []string{"user"}, want: Limit{Rate: 20, Burst: 40}},
+		{name: "listed role", authenticated: true, roles: []string{"user", "judge"}, want: Limit{Rate: 50, Burst: 100}},
+		{name: "most generous role", authenticated: true, roles: []string{"viewer", "admin", "judge"}, want: Limit{Rate: 100, Burst: 200}},
+		// A listed role applies even when it is below the authenticated limit
+		{name: "stricter listed role", authenticated: true, roles: []string{"user", "viewer"}, want: Limit{Rate: 5, Burst: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limits.For(tt.authenticated, tt.roles); got != tt.want {
+				t.Errorf("For(%v, %v) = %+v, want %+v", tt.authenticated, tt.roles, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigMatch(t *testing.T) {
+	config := testConfig(t)
+	tests := []struct {
+		method string
+		path   string
+		bucket string
+	}{
+		{method: "POST", path: "/auth/login", bucket: "login"},
+		{method: "GET", path: "/auth/login", bucket: DefaultBucket},
+		{method: "POST", path: "/auth/login/magic", bucket: "login"},
+		{method: "POST", path: "/auth/loginx", bucket: DefaultBucket},
+		{method: "PUT", path: "/submission/7", bucket: "submission"},
+		{method: "GET", path: "/submission", bucket: DefaultBucket},
+		{method: "GET", path: "/problems", bucket: DefaultBucket},
+	}
+	for _, tt := range tests {
+		if bucket, _ := config.Match(tt.method, tt.path); bucket != tt.bucket {
+			t.Errorf("Match(%s, %s) = %s, want %s", tt.method, tt.path, bucket, tt.bucket)
+		}
+	}
+
+	// A route override keeps what it sets and takes the rest from the default
+	_, limits := config.Match("POST", "/auth/login")
+	if limits.Anonymous != (Limit{Rate: 0.1, Burst: 5}) || limits.Authenticated != config.Default.Authenticated {
+		t.Errorf("login limits = %+v", limits)
+	}
+	_, limits = config.Match("GET", "/submission/7")
+	if limits.Authenticated != (Limit{Rate: 1, Burst: 3}) || limits.Anonymous != config.Default.Anonymous {
+		t.Errorf("submission limits = %+v", limits)
+	}
+}
+
+func TestConfigWithDefaultsRejects(t *testing.T) {
+	tests := map[string]Config{
+		"zero burst":        {Default: Limits{Anonymous: Limit{Rate: 1}}},
+		"negative rate":     {Default: Limits{Roles: map[string]Limit{"admin": {Rate: -1, Burst: 1}}}},
+		"unnamed route":     {Routes: []RouteLimit{{Prefix: "/auth"}}},
+		"default name":      {Routes: []RouteLimit{{Name: DefaultBucket, Prefix: "/auth"}}},
+		"duplicate name":    {Routes: []RouteLimit{{Name: "auth", Prefix: "/auth"}, {Name: "auth", Prefix: "/user"}}},
+		"relative prefix":   {Routes: []RouteLimit{{Name: "auth", Prefix: "auth"}}},
+		"invalid route one": {Routes: []RouteLimit{{Name: "auth", Prefix: "/auth", Limits: Limits{Authenticated: Limit{Burst: 1}}}}},
+	}
+	for name, config := range tests {
+		if _, err := config.WithDefaults(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: WithDefaults() error = %v, want %v", name, err, ErrInvalidConfig)
+		}
+	}
+}
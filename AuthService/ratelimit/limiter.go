@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Refills the bucket for the time since it was last touched and takes a token when one is there.
+// Redis' clock is used so every gateway replica agrees on the time. The bucket expires once it would be full again.
+// Returns whether the request is allowed, the tokens left, and in milliseconds the wait for the next token and until full.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+local full = math.ceil((burst - tokens) * 1000 / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.max(full, 1000))
+return {allowed, math.floor(tokens), retry, full}
+`)
+
+// Outcome of taking a token, Reset is how long until the bucket is full again
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Token buckets kept in Redis, so limits hold across every gateway replica
+type Limiter struct {
+	client *redis.Client
+	config Config
+}
+
+func NewLimiter(client *redis.Client, config Config) *Limiter {
+	return &Limiter{
+		client: client,
+		config: config,
+	}
+}
+
+func (l *Limiter) Config() Config {
+	return l.config
+}
+
+// Takes a token from the caller's bucket, key identifies the caller such as "user:42" or "ip:10.0.0.1"
+func (l *Limiter) Allow(ctx context.Context, bucket string, key string, limit Limit) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+
+	values, err := tokenBucketScript.Run(ctx, l.client, []string{"ratelimit:" + bucket + ":" + key}, limit.Rate, limit.Burst).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit.Burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
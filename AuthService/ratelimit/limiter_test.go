@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestLimiter(t *testing.T) (*Limiter, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	server.SetTime(time.Unix(1760000000, 0))
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewLimiter(client, Config{}), server
+}
+
+func TestAllowTokenBucket(t *testing.T) {
+	limiter, server := newTestLimiter(t)
+	ctx := context.Background()
+	limit := Limit{Rate: 2, Burst: 3}
+
+	// A new bucket starts full
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, DefaultBucket, "user:42", limit)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if !result.Allowed || result.Remaining != 2-i || result.Limit != 3 {
+			t.Fatalf("request %d = %+v, want allowed with %d left", i+1, result, 2-i)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, DefaultBucket, "user:42", limit)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("request over the burst = %+v, want rejected", result)
+	}
+	if result.RetryAfter != 500*time.Millisecond || result.Reset != 1500*time.Millisecond {
+		t.Errorf("RetryAfter = %s and Reset = %s, want 500ms and 1.5s", result.RetryAfter, result.Reset)
+	}
+
+	// Another caller has a bucket of their own
+	if result, _ := limiter.Allow(ctx, DefaultBucket, "user:43", limit); !result.Allowed {
+		t.Error("another caller was limited")
+	}
+	// And so has the same caller on another route
+	if result, _ := limiter.Allow(ctx, "login", "user:42", limit); !result.Allowed {
+		t.Error("another bucket was limited")
+	}
+
+	// Half a second refills one token at 2 per second
+	server.SetTime(time.Unix(1760000000, 0).Add(500 * time.Millisecond))
+	result, _ = limiter.Allow(ctx, DefaultBucket, "user:42", limit)
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("after a refill = %+v, want allowed with 0 left", result)
+	}
+	if result, _ := limiter.Allow(ctx, DefaultBucket, "user:42", limit); result.Allowed {
+		t.Error("a second request was allowed with one token refilled")
+	}
+
+	// Never more than the burst, however long the bucket sat
+	server.SetTime(time.Unix(1760000000, 0).Add(time.Hour))
+	result, _ = limiter.Allow(ctx, DefaultBucket, "user:42", limit)
+	if !result.Allowed || result.Remaining != 2 {
+		t.Errorf("after an hour = %+v, want allowed with 2 left", result)
+	}
+}
+
+func TestAllowExpiresFullBuckets(t *testing.T) {
+	limiter, server := newTestLimiter(t)
+	if _, err := limiter.Allow(context.Background(), DefaultBucket, "ip:10.0.0.1", Limit{Rate: 10, Burst: 20}); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+
+	key := "ratelimit:" + DefaultBucket + ":ip:10.0.0.1"
+	if ttl := server.TTL(key); ttl <= 0 || ttl > time.Second {
+		t.Fatalf("TTL(%s) = %s, want up to the time until full", key, ttl)
+	}
+	server.FastForward(time.Second)
+	if server.Exists(key) {
+		t.Error("a full bucket was kept")
+	}
+}
+
+func TestAllowReportsRedisErrors(t *testing.T) {
+	limiter, server := newTestLimiter(t)
+	server.Close()
+
+	if _, err := limiter.Allow(context.Background(), DefaultBucket, "user:42", Limit{Rate: 1, Burst: 1}); err == nil {
+		t.Error("Allow() without Redis returned no error")
+	}
+}
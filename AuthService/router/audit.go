@@ -0,0 +1,23 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AuditRouter struct {
+	AuditController controllers.AuditController
+}
+
+func NewAuditRouter(_auditController controllers.AuditController) Router {
+	return &AuditRouter{
+		AuditController: _auditController,
+	}
+}
+
+func (r *AuditRouter) Register(router chi.Router) {
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/", r.AuditController.GetLogs)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/export", r.AuditController.ExportCsv)
+}
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type GuestRouter struct {
+	GuestController controllers.GuestController
+}
+
+func NewGuestRouter(_guestController controllers.GuestController) Router {
+	return &GuestRouter{
+		GuestController: _guestController,
+	}
+}
+
+func (r *GuestRouter) Register(router chi.Router) {
+	router.Post("/", r.GuestController.CreateGuest)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("guest"), middlewares.UpgradeGuestRequestValidator).Post("/upgrade", r.GuestController.UpgradeGuest)
+}
@@ -0,0 +1,25 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type HealthRouter struct {
+	HealthController controllers.HealthController
+}
+
+func NewHealthRouter(_healthController controllers.HealthController) Router {
+	return &HealthRouter{
+		HealthController: _healthController,
+	}
+}
+
+// Probes are served at the root, where orchestrators expect them, the detailed report under the api
+func (r *HealthRouter) Register(router chi.Router) {
+	router.Get("/healthz", r.HealthController.Live)
+	router.Get("/readyz", r.HealthController.Ready)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/api/v1/health", r.HealthController.GetHealth)
+}
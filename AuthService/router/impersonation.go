@@ -0,0 +1,23 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ImpersonationRouter struct {
+	ImpersonationController controllers.ImpersonationController
+}
+
+func NewImpersonationRouter(_impersonationController controllers.ImpersonationController) Router {
+	return &ImpersonationRouter{
+		ImpersonationController: _impersonationController,
+	}
+}
+
+func (r *ImpersonationRouter) Register(router chi.Router) {
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin"), middlewares.ImpersonateUserRequestValidator).Post("/{userId}", r.ImpersonationController.Impersonate)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/logs", r.ImpersonationController.GetLogs)
+}
@@ -0,0 +1,91 @@
+package router
+
+import (
+	dbConfig "AuthService/config/db"
+	"AuthService/controllers"
+	"AuthService/dto"
+	"AuthService/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+// Replaces the shared connection the middlewares use with a mock for the duration of the test
+func mockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	previous := dbConfig.DB
+	dbConfig.DB = conn
+	t.Cleanup(func() {
+		dbConfig.DB = previous
+		conn.Close()
+	})
+	return mock
+}
+
+func impersonationToken(t *testing.T, roles []string) string {
+	t.Helper()
+	t.Setenv("SECRET_KEY", "impersonation-test-secret")
+	token, err := utils.CreateJwtToken(dto.TokenClaimsDTO{
+		UserId:         42,
+		Email:          "ada@example.com",
+		Roles:          roles,
+		ImpersonatorId: 7,
+		ExpiresAt:      time.Now().Add(15 * time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("CreateJwtToken() error = %v", err)
+	}
+	return token
+}
+
+func TestImpersonationIsBlockedFromSensitiveRoutes(t *testing.T) {
+	router := chi.NewRouter()
+	router.Route("/api/v1/auth", NewUserRouter(controllers.UserController{}).Register)
+	router.Route("/api/v1/roles", NewRoleRouter(controllers.RoleController{}).Register)
+	router.Route("/api/v1/orgs", NewOrganizationRouter(controllers.OrganizationController{}).Register)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		roles  []string
+	}{
+		{name: "change password", method: http.MethodPut, path: "/api/v1/auth/password", body: `{"current_password":"old-password","new_password":"new-password"}`, roles: []string{"user"}},
+		// Admins cannot be impersonated, the token still must not assign roles should one carry the role
+		{name: "assign role", method: http.MethodPost, path: "/api/v1/roles/assign/42/1", roles: []string{"admin"}},
+		{name: "remove role", method: http.MethodDelete, path: "/api/v1/roles/remove/3", roles: []string{"admin"}},
+		{name: "accept org invitation", method: http.MethodPost, path: "/api/v1/orgs/invitations/accept", body: `{"token":"invitation-token"}`, roles: []string{"user"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockDB(t)
+			// The refused request is audited like any other the admin makes as the user
+			mock.ExpectExec("INSERT INTO impersonation_logs").
+				WithArgs(int64(7), int64(42), tt.method, tt.path, http.StatusForbidden, sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set("Authorization", "Bearer "+impersonationToken(t, tt.roles))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", recorder.Code, http.StatusForbidden)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("audit row: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type MagicLinkRouter struct {
+	MagicLinkController controllers.MagicLinkController
+}
+
+func NewMagicLinkRouter(_magicLinkController controllers.MagicLinkController) Router {
+	return &MagicLinkRouter{
+		MagicLinkController: _magicLinkController,
+	}
+}
+
+func (r *MagicLinkRouter) Register(router chi.Router) {
+	router.With(middlewares.MagicLinkRequestValidator).Post("/", r.MagicLinkController.RequestLink)
+	router.With(middlewares.MagicLinkLoginRequestValidator).Post("/callback", r.MagicLinkController.Callback)
+}
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type OrganizationRouter struct {
+	OrganizationController controllers.OrganizationController
+}
+
+func NewOrganizationRouter(_organizationController controllers.OrganizationController) Router {
+	return &OrganizationRouter{
+		OrganizationController: _organizationController,
+	}
+}
+
+// Org level roles are checked by the organization service, the role middlewares reject members removed from their active org
+func (r *OrganizationRouter) Register(router chi.Router) {
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAnyRole("user", "admin"), middlewares.OrganizationCreateRequestValidator).Post("/", r.OrganizationController.Create)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAnyRole("user", "admin")).Get("/", r.OrganizationController.GetUserOrganizations)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAnyRole("user", "admin"), middlewares.OrgInvitationAcceptRequestValidator).Post("/invitations/accept", r.OrganizationController.AcceptInvitation)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAnyRole("user", "admin")).Delete("/active", r.OrganizationController.ClearActiveOrganization)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAnyRole("user", "admin")).Get("/{id}", r.OrganizationController.GetById)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAnyRole("user", "admin")).Post("/{id}/switch", r.OrganizationController.SwitchOrganization)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAnyRole("user", "admin")).Get("/{id}/members", r.OrganizationController.GetMembers)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAnyRole("user", "admin"), middlewares.OrgMemberUpdateRequestValidator).Put("/{id}/members/{userId}", r.OrganizationController.UpdateMemberRole)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAnyRole("user", "admin")).Delete("/{id}/members/{userId}", r.OrganizationController.RemoveMember)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAnyRole("user", "admin"), middlewares.OrgInvitationCreateRequestValidator).Post("/{id}/invitations", r.OrganizationController.InviteMember)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAnyRole("user", "admin")).Get("/{id}/invitations", r.OrganizationController.GetPendingInvitations)
+}
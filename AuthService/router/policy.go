@@ -0,0 +1,23 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type PolicyRouter struct {
+	PolicyController controllers.PolicyController
+}
+
+func NewPolicyRouter(_policyController controllers.PolicyController) Router {
+	return &PolicyRouter{
+		PolicyController: _policyController,
+	}
+}
+
+func (r *PolicyRouter) Register(router chi.Router) {
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/", r.PolicyController.GetRoutes)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin"), middlewares.PolicyExplainRequestValidator).Post("/explain", r.PolicyController.Explain)
+}
@@ -0,0 +1,24 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type PreferencesRouter struct {
+	PreferencesController controllers.PreferencesController
+}
+
+func NewPreferencesRouter(_preferencesController controllers.PreferencesController) Router {
+	return &PreferencesRouter{
+		PreferencesController: _preferencesController,
+	}
+}
+
+func (r *PreferencesRouter) Register(router chi.Router) {
+	router.With(middlewares.JWTAuthMiddleware).Get("/", r.PreferencesController.GetPreferences)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.PreferencesRequestValidator).Put("/", r.PreferencesController.ReplacePreferences)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.PatchPreferencesRequestValidator).Patch("/", r.PreferencesController.PatchPreferences)
+}
@@ -0,0 +1,28 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ProfileRouter struct {
+	ProfileController controllers.ProfileController
+}
+
+func NewProfileRouter(_profileController controllers.ProfileController) Router {
+	return &ProfileRouter{
+		ProfileController: _profileController,
+	}
+}
+
+// /me is matched before /{username}, so it always means the caller's own profile
+func (r *ProfileRouter) Register(router chi.Router) {
+	router.With(middlewares.JWTAuthMiddleware).Get("/me", r.ProfileController.GetOwnProfile)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.UpdateProfileRequestValidator).Put("/me", r.ProfileController.UpdateOwnProfile)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation).Put("/me/avatar", r.ProfileController.UploadAvatar)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation).Delete("/me/avatar", r.ProfileController.RemoveAvatar)
+	router.Get("/avatars/{key}/{file}", r.ProfileController.GetAvatar)
+	router.Get("/{username}", r.ProfileController.GetPublicProfile)
+}
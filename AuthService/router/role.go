@@ -18,13 +18,16 @@ func NewRoleRouter(_roleController controllers.RoleController) Router {
 }
 
 func (r *RoleRouter) Register(router chi.Router) {
-	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin"), middlewares.RoleCreateRequestValidator).Post("/", r.RoleController.Create)
-	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin"), middlewares.RoleUpdateRequestValidator).Put("/{id}", r.RoleController.UpdateRole)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin"), middlewares.RoleCreateRequestValidator).Post("/", r.RoleController.Create)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin"), middlewares.RoleUpdateRequestValidator).Put("/{id}", r.RoleController.UpdateRole)
 	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/{id}", r.RoleController.GetById)
 	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/roles", r.RoleController.GetAllRoles)
 	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/name", r.RoleController.GetByName)
 	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/permissions", r.RoleController.GetAllRolePermissions)
 	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/permissions/{id}", r.RoleController.GetRolePermissions)
-	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Post("/assign/{userId}/{roleId}", r.RoleController.AssignRole)
-	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Delete("/remove/{userRoleId}", r.RoleController.RemoveRole)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin")).Post("/assign/{userId}/{roleId}", r.RoleController.AssignRole)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin")).Delete("/remove/{userRoleId}", r.RoleController.RemoveRole)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin")).Delete("/assign/{userId}/{roleId}", r.RoleController.RemoveUserRole)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin")).Delete("/{id}", r.RoleController.DeleteRole)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/{id}/members", r.RoleController.GetRoleMembers)
 }
@@ -1,9 +1,16 @@
 package router
 
 import (
+	"AuthService/assertion"
+	"AuthService/cache"
 	env "AuthService/config/env"
 	"AuthService/controllers"
+	"AuthService/metrics"
 	"AuthService/middlewares"
+	"AuthService/policy"
+	"AuthService/ratelimit"
+	"AuthService/tracing"
+	"AuthService/upstream"
 	"AuthService/utils"
 
 	"github.com/go-chi/chi/v5"
@@ -14,128 +21,81 @@ type Router interface {
 	Register(r chi.Router)
 }
 
-func SetupRouter(UserRouter Router, RoleRouter Router) *chi.Mux {
+func SetupRouter(UserRouter Router, RoleRouter Router, PolicyRouter Router, OrganizationRouter Router, ImpersonationRouter Router, SessionRouter Router, MagicLinkRouter Router, GuestRouter Router, SuspensionRouter Router, AuditRouter Router, ProfileRouter Router, PreferencesRouter Router, UpstreamRouter Router, HealthRouter Router, engine *policy.Engine, pools *upstream.Registry, signer *assertion.Signer, limiter *ratelimit.Limiter, store *cache.Store) *chi.Mux {
 	chiRouter := chi.NewRouter()
 
+	chiRouter.Use(tracing.Middleware)
 	chiRouter.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Requested-With"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Requested-With", "X-Request-ID", "X-Correlation-ID", "If-Match", "If-None-Match", "Cache-Control", "traceparent", "tracestate"},
+		ExposedHeaders:   []string{"X-Request-ID", "X-Correlation-ID", "ETag", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", "X-Cache", "Age"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	chiRouter.Use(middlewares.RateLimitMiddleware)
+	chiRouter.Use(middlewares.RequestMetadata)
+	chiRouter.Use(middlewares.AccessLog)
+	chiRouter.Use(middlewares.RateLimit(limiter))
 
 	chiRouter.Route("/api/v1/auth", func(r chi.Router) {
 		UserRouter.Register(r)
+		r.Route("/sessions", SessionRouter.Register)
+		r.Route("/magic-link", MagicLinkRouter.Register)
+		r.Route("/guest", GuestRouter.Register)
 	})
 
 	chiRouter.Route("/api/v1/roles", func(r chi.Router) {
 		RoleRouter.Register(r)
 	})
 
-	// Problem Service
-	// Problem routes
-	chiRouter.With(middlewares.JWTAuthMiddleware).Route("/api/v1/problem", func(r chi.Router) {
-		// GET - Anyone authenticated (user or admin)
-		r.With(middlewares.RequireAnyRole("user", "admin")).Get("/*",
-			utils.ProxyToService(
-				env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"),
-				"/api/v1/problem",
-			).ServeHTTP)
-
-		// POST - Admin only (create)
-		r.With(middlewares.RequireAnyRole("admin")).Post("/*",
-			utils.ProxyToService(
-				env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"),
-				"/api/v1/problem",
-			).ServeHTTP)
-
-		// PUT/PATCH - Admin only (update)
-		r.With(middlewares.RequireAnyRole("admin")).Put("/*",
-			utils.ProxyToService(
-				env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"),
-				"/api/v1/problem",
-			).ServeHTTP)
-
-		r.With(middlewares.RequireAnyRole("admin")).Patch("/*",
-			utils.ProxyToService(
-				env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"),
-				"/api/v1/problem",
-			).ServeHTTP)
-
-		// DELETE - Admin only
-		r.With(middlewares.RequireAnyRole("admin")).Delete("/*",
-			utils.ProxyToService(
-				env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"),
-				"/api/v1/problem",
-			).ServeHTTP)
+	chiRouter.Route("/api/v1/policy", func(r chi.Router) {
+		PolicyRouter.Register(r)
 	})
 
-	// /api/v1/company → SAME PROBLEM SERVICE
-	chiRouter.With(middlewares.JWTAuthMiddleware).Route("/api/v1/company", func(r chi.Router) {
-		r.With(middlewares.RequireAnyRole("user", "admin")).Get("/*",
-			utils.ProxyToService(env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"), "/api/v1/company").ServeHTTP)
+	chiRouter.Route("/api/v1/orgs", func(r chi.Router) {
+		OrganizationRouter.Register(r)
+	})
 
-		r.With(middlewares.RequireAnyRole("admin")).Post("/*",
-			utils.ProxyToService(env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"), "/api/v1/company").ServeHTTP)
+	chiRouter.Route("/api/v1/impersonation", func(r chi.Router) {
+		ImpersonationRouter.Register(r)
+	})
 
-		r.With(middlewares.RequireAnyRole("admin")).Put("/*",
-			utils.ProxyToService(env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"), "/api/v1/company").ServeHTTP)
+	chiRouter.Route("/api/v1/suspensions", func(r chi.Router) {
+		SuspensionRouter.Register(r)
+	})
 
-		r.With(middlewares.RequireAnyRole("admin")).Patch("/*",
-			utils.ProxyToService(env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"), "/api/v1/company").ServeHTTP)
+	chiRouter.Route("/api/v1/audit-logs", func(r chi.Router) {
+		AuditRouter.Register(r)
+	})
 
-		r.With(middlewares.RequireAnyRole("admin")).Delete("/*",
-			utils.ProxyToService(env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"), "/api/v1/company").ServeHTTP)
+	chiRouter.Route("/api/v1/profiles", func(r chi.Router) {
+		ProfileRouter.Register(r)
 	})
 
-	// /api/v1/explanation → SAME PROBLEM SERVICE
-	chiRouter.With(middlewares.JWTAuthMiddleware).Route("/api/v1/explanation", func(r chi.Router) {
-		r.With(middlewares.RequireAnyRole("user", "admin")).Get("/*",
-			utils.ProxyToService(env.GetString("PROBLEM_SERVICE", "http://localhost:3000/api/v1"), "/api/v1/explanation").ServeHTTP)
+	chiRouter.Route("/api/v1/preferences", func(r chi.Router) {
+		PreferencesRouter.Register(r)
 	})
 
-	// Submission Service
-	// Submission routes
-	chiRouter.With(middlewares.JWTAuthMiddleware).Route("/api/v1/submission", func(r chi.Router) {
-		// GET - Anyone authenticated (user or admin)
-		r.With(middlewares.RequireAnyRole("user", "admin")).Get("/*",
-			utils.ProxyToService(
-				env.GetString("SUBMISSION_SERVICE", "http://localhost:3002/api/v1"),
-				"/api/v1/submission",
-			).ServeHTTP)
-
-		// POST - Admin only (create)
-		r.With(middlewares.RequireAnyRole("admin")).Post("/*",
-			utils.ProxyToService(
-				env.GetString("SUBMISSION_SERVICE", "http://localhost:3002/api/v1"),
-				"/api/v1/submission",
-			).ServeHTTP)
-
-		// PUT/PATCH - Admin only (update)
-		r.With(middlewares.RequireAnyRole("admin")).Put("/*",
-			utils.ProxyToService(
-				env.GetString("SUBMISSION_SERVICE", "http://localhost:3002/api/v1"),
-				"/api/v1/submission",
-			).ServeHTTP)
-
-		r.With(middlewares.RequireAnyRole("admin")).Patch("/*",
-			utils.ProxyToService(
-				env.GetString("SUBMISSION_SERVICE", "http://localhost:3002/api/v1"),
-				"/api/v1/submission",
-			).ServeHTTP)
-
-		// DELETE - Admin only
-		r.With(middlewares.RequireAnyRole("admin")).Delete("/*",
-			utils.ProxyToService(
-				env.GetString("SUBMISSION_SERVICE", "http://localhost:3002/api/v1"),
-				"/api/v1/submission",
-			).ServeHTTP)
+	chiRouter.Route("/api/v1/upstreams", func(r chi.Router) {
+		UpstreamRouter.Register(r)
 	})
 
+	// Registers its own paths, the probes do not share a prefix with the api
+	HealthRouter.Register(chiRouter)
+
+	// Proxied upstream routes, access is decided by the gateway policy
+	for _, route := range engine.Routes() {
+		// The policy was validated, every route's upstream has a pool
+		pool, _ := pools.Get(route.Upstream)
+		proxy := utils.ProxyToService(pool, route.RouteConfig, route.Prefix, signer, store)
+		chiRouter.With(middlewares.JWTAuthMiddleware, middlewares.RequireNotSuspended(route.SuspensionScope), middlewares.RequirePolicy(engine)).Handle(route.Prefix, proxy)
+		chiRouter.With(middlewares.JWTAuthMiddleware, middlewares.RequireNotSuspended(route.SuspensionScope), middlewares.RequirePolicy(engine)).Handle(route.Prefix+"/*", proxy)
+	}
+
 	chiRouter.With(middlewares.JWTAuthMiddleware).Get("/ws", controllers.WsHandler)
 
+	chiRouter.With(middlewares.RequireMetricsToken(env.GetString("METRICS_TOKEN", ""))).Handle("/metrics", metrics.Handler())
+
 	return chiRouter
 }
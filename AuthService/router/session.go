@@ -0,0 +1,24 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SessionRouter struct {
+	SessionController controllers.SessionController
+}
+
+func NewSessionRouter(_sessionController controllers.SessionController) Router {
+	return &SessionRouter{
+		SessionController: _sessionController,
+	}
+}
+
+func (r *SessionRouter) Register(router chi.Router) {
+	router.With(middlewares.JWTAuthMiddleware).Get("/", r.SessionController.GetSessions)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation).Delete("/{id}", r.SessionController.RevokeSession)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation).Delete("/", r.SessionController.RevokeOtherSessions)
+}
@@ -0,0 +1,24 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SuspensionRouter struct {
+	SuspensionController controllers.SuspensionController
+}
+
+func NewSuspensionRouter(_suspensionController controllers.SuspensionController) Router {
+	return &SuspensionRouter{
+		SuspensionController: _suspensionController,
+	}
+}
+
+func (r *SuspensionRouter) Register(router chi.Router) {
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/", r.SuspensionController.GetSuspensions)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin"), middlewares.CreateSuspensionRequestValidator).Post("/", r.SuspensionController.Suspend)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin")).Delete("/{id}", r.SuspensionController.Lift)
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"AuthService/controllers"
+	"AuthService/middlewares"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type UpstreamRouter struct {
+	UpstreamController controllers.UpstreamController
+}
+
+func NewUpstreamRouter(_upstreamController controllers.UpstreamController) Router {
+	return &UpstreamRouter{
+		UpstreamController: _upstreamController,
+	}
+}
+
+func (r *UpstreamRouter) Register(router chi.Router) {
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/", r.UpstreamController.GetUpstreams)
+}
@@ -24,4 +24,10 @@ func (r *UserRouter) Register(router chi.Router) {
 	router.With(middlewares.JWTAuthMiddleware).Get("/logout", r.UserController.LogoutUser)
 	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireSelfOrAdmin()).Get("/user/{id}", r.UserController.GetById)
 	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/users", r.UserController.GetAll)
+	router.With(middlewares.JWTAuthMiddleware).Get("/login-history", r.UserController.GetLoginHistory)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/users/{id}/login-history", r.UserController.GetUserLoginHistory)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.ChangePasswordRequestValidator).Put("/password", r.UserController.ChangePassword)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.BlockImpersonation, middlewares.RequireAllRoles("admin")).Post("/users/import", r.UserController.ImportUsers)
+	router.With(middlewares.JWTAuthMiddleware, middlewares.RequireAllRoles("admin")).Get("/users/import/{jobId}", r.UserController.GetImportJob)
+	router.With(middlewares.AcceptInviteRequestValidator).Post("/invite/accept", r.UserController.AcceptInvite)
 }
@@ -0,0 +1,165 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/models"
+	"AuthService/utils"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	AuditRoleCreate       = "role.create"
+	AuditRoleUpdate       = "role.update"
+	AuditRoleDelete       = "role.delete"
+	AuditUserRoleAssign   = "user_role.assign"
+	AuditUserRoleRemove   = "user_role.remove"
+	AuditUserDelete       = "user.delete"
+	AuditUserImport       = "user.import"
+	AuditSuspensionCreate = "suspension.create"
+	AuditSuspensionLift   = "suspension.lift"
+	AuditImpersonation    = "impersonation.start"
+
+	auditExportMaxRows = 50000
+)
+
+type AuditService interface {
+	Record(ctx context.Context, action string, targetType string, targetId any, before any, after any)
+	GetLogs(ctx context.Context, filter models.AuditLogFilter, page int, limit int) (*models.Page[*models.AuditLog], error)
+	ExportCsv(ctx context.Context, filter models.AuditLogFilter, w io.Writer) error
+}
+
+type AuditServiceImpl struct {
+	auditLogRepository db.AuditLogRepository
+}
+
+func NewAuditService(auditLogRepo db.AuditLogRepository) AuditService {
+	return &AuditServiceImpl{
+		auditLogRepository: auditLogRepo,
+	}
+}
+
+// Writes an entry for an action that already happened, the actor and request come from the context.
+// Like the login history, a failed write is logged instead of failing an action that cannot be undone.
+func (s *AuditServiceImpl) Record(ctx context.Context, action string, targetType string, targetId any, before any, after any) {
+	meta := utils.GetRequestMeta(ctx)
+	entry := &models.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   fmt.Sprint(targetId),
+		RequestId:  meta.RequestId,
+		IpAddress:  meta.IpAddress,
+	}
+	if tokenClaims, ok := ctx.Value(utils.ClaimsKey).(dto.TokenClaimsDTO); ok {
+		entry.ActorId = int64(tokenClaims.UserId)
+		entry.ImpersonatorId = int64(tokenClaims.ImpersonatorId)
+	}
+
+	changes := auditDiff(before, after)
+	if len(changes) > 0 {
+		entry.Changes, _ = json.Marshal(changes)
+	}
+
+	if err := s.auditLogRepository.Create(ctx, entry); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":        err,
+			"action":     action,
+			"target":     entry.TargetType + ":" + entry.TargetId,
+			"actor_id":   entry.ActorId,
+			"request_id": entry.RequestId,
+			"type":       "audit_error",
+		}).Error("Audit log entry could not be written")
+	}
+}
+
+func (s *AuditServiceImpl) GetLogs(ctx context.Context, filter models.AuditLogFilter, page int, limit int) (*models.Page[*models.AuditLog], error) {
+	entries, total, err := s.auditLogRepository.GetAll(ctx, filter, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Page[*models.AuditLog]{
+		Items: entries,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}, nil
+}
+
+// Streams the matching entries as CSV, newest first and capped so a wide filter cannot tie up the database
+func (s *AuditServiceImpl) ExportCsv(ctx context.Context, filter models.AuditLogFilter, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := []string{"id", "created_at", "actor_id", "impersonator_id", "action", "target_type", "target_id", "changes", "request_id", "ip_address"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	err := s.auditLogRepository.Each(ctx, filter, auditExportMaxRows, func(entry *models.AuditLog) error {
+		return writer.Write([]string{
+			strconv.FormatInt(entry.Id, 10),
+			entry.CreatedAt,
+			strconv.FormatInt(entry.ActorId, 10),
+			strconv.FormatInt(entry.ImpersonatorId, 10),
+			entry.Action,
+			entry.TargetType,
+			entry.TargetId,
+			string(entry.Changes),
+			entry.RequestId,
+			entry.IpAddress,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+type auditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Compares the JSON form of two states field by field, either side may be nil for a creation or a removal
+func auditDiff(before any, after any) map[string]auditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	changes := map[string]auditChange{}
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = auditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, seen := beforeFields[field]; !seen {
+			changes[field] = auditChange{Before: nil, After: value}
+		}
+	}
+	return changes
+}
+
+func auditFields(state any) map[string]any {
+	fields := map[string]any{}
+	if state == nil {
+		return fields
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return fields
+	}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return map[string]any{}
+	}
+	// Hashes never belong in the audit trail
+	delete(fields, "password")
+	return fields
+}
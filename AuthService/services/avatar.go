@@ -0,0 +1,211 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/models"
+	"AuthService/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	AvatarMaxBytes = 5 << 20
+	// Checked before decoding, so a tiny file cannot claim a huge canvas
+	avatarMaxDimension = 4096
+	avatarMinDimension = 32
+	avatarJpegQuality  = 85
+	avatarDefaultSize  = 256
+)
+
+// Every upload is stored in these square sizes
+var AvatarSizes = []int{512, 256, 128, 64}
+
+var (
+	ErrAvatarTooLarge         = errors.New("avatar must be at most 5 MB")
+	ErrUnsupportedAvatar      = errors.New("avatar must be a PNG, JPEG or WebP image")
+	ErrInvalidAvatarDimension = fmt.Errorf("avatar must be between %d and %d pixels on each side", avatarMinDimension, avatarMaxDimension)
+	ErrTooManyAvatarUploads   = errors.New("too many avatar uploads, try again later")
+	ErrAvatarNotFound         = errors.New("avatar not found")
+)
+
+var (
+	avatarKeyPattern  = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	avatarContentType = map[string]bool{"image/png": true, "image/jpeg": true, "image/webp": true}
+)
+
+type AvatarService interface {
+	Upload(ctx context.Context, userId int64, file io.Reader) (*models.UserProfile, error)
+	Remove(ctx context.Context, userId int64) (*models.UserProfile, error)
+	Open(ctx context.Context, avatarKey string, size int) (io.ReadCloser, *BlobInfo, error)
+}
+
+type AvatarServiceImpl struct {
+	profileRepository db.ProfileRepository
+	profileService    ProfileService
+	blobStore         BlobStore
+
+	uploadLimiter *utils.KeyedLimiter
+}
+
+func NewAvatarService(profileRepo db.ProfileRepository, profileService ProfileService, blobStore BlobStore) AvatarService {
+	return &AvatarServiceImpl{
+		profileRepository: profileRepo,
+		profileService:    profileService,
+		blobStore:         blobStore,
+		// 5 uploads per user, then one a minute
+		uploadLimiter: utils.NewKeyedLimiter(time.Minute, 5),
+	}
+}
+
+// Where a stored thumbnail is served from
+func AvatarUrl(avatarKey string, size int) string {
+	return fmt.Sprintf("/api/v1/profiles/avatars/%s/%d.jpg", avatarKey, size)
+}
+
+func avatarBlobKey(avatarKey string, size int) string {
+	return fmt.Sprintf("avatars/%s/%d.jpg", avatarKey, size)
+}
+
+// Decodes the upload, crops it to a centered square and stores every thumbnail size under a fresh random key.
+// The key never changes once written, so the files can be cached forever, a new upload gets a new key.
+func (s *AvatarServiceImpl) Upload(ctx context.Context, userId int64, file io.Reader) (*models.UserProfile, error) {
+	if !s.uploadLimiter.Allow(strconv.FormatInt(userId, 10)) {
+		return nil, ErrTooManyAvatarUploads
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, AvatarMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > AvatarMaxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+	thumbnails, err := processAvatar(data)
+	if err != nil {
+		return nil, err
+	}
+
+	avatarKey, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, db.ErrInternalServerError
+	}
+	for _, size := range AvatarSizes {
+		if err := s.blobStore.Put(ctx, avatarBlobKey(avatarKey, size), "image/jpeg", thumbnails[size]); err != nil {
+			s.deleteAvatar(ctx, avatarKey)
+			return nil, err
+		}
+	}
+
+	previous, err := s.profileRepository.SetAvatarKey(ctx, userId, avatarKey)
+	if err != nil {
+		s.deleteAvatar(ctx, avatarKey)
+		return nil, err
+	}
+	s.deleteAvatar(ctx, previous)
+
+	return s.profileService.GetOwnProfile(ctx, userId)
+}
+
+func (s *AvatarServiceImpl) Remove(ctx context.Context, userId int64) (*models.UserProfile, error) {
+	previous, err := s.profileRepository.SetAvatarKey(ctx, userId, "")
+	if err != nil {
+		return nil, err
+	}
+	s.deleteAvatar(ctx, previous)
+
+	return s.profileService.GetOwnProfile(ctx, userId)
+}
+
+// Only keys and sizes we generate are looked up, anything else is simply not found
+func (s *AvatarServiceImpl) Open(ctx context.Context, avatarKey string, size int) (io.ReadCloser, *BlobInfo, error) {
+	if !avatarKeyPattern.MatchString(avatarKey) || !isAvatarSize(size) {
+		return nil, nil, ErrAvatarNotFound
+	}
+	reader, info, err := s.blobStore.Get(ctx, avatarBlobKey(avatarKey, size))
+	if err != nil {
+		if errors.Is(err, ErrBlobNotFound) {
+			return nil, nil, ErrAvatarNotFound
+		}
+		return nil, nil, err
+	}
+	return reader, info, nil
+}
+
+// Old thumbnails are only garbage once the profile moved on, failing to delete them is logged and left behind
+func (s *AvatarServiceImpl) deleteAvatar(ctx context.Context, avatarKey string) {
+	if avatarKey == "" {
+		return
+	}
+	for _, size := range AvatarSizes {
+		if err := s.blobStore.Delete(ctx, avatarBlobKey(avatarKey, size)); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"err":        err,
+				"avatar_key": avatarKey,
+				"size":       size,
+				"type":       "blob_error",
+			}).Warn("Avatar could not be deleted")
+		}
+	}
+}
+
+func isAvatarSize(size int) bool {
+	for _, avatarSize := range AvatarSizes {
+		if size == avatarSize {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a JPEG of every avatar size, transparent areas are flattened onto white
+func processAvatar(data []byte) (map[int][]byte, error) {
+	if !avatarContentType[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedAvatar
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedAvatar
+	}
+	if config.Width < avatarMinDimension || config.Height < avatarMinDimension || config.Width > avatarMaxDimension || config.Height > avatarMaxDimension {
+		return nil, ErrInvalidAvatarDimension
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedAvatar
+	}
+
+	bounds := source.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	crop := image.Rect(x, y, x+side, y+side)
+
+	thumbnails := make(map[int][]byte, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		thumbnail := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.Draw(thumbnail, thumbnail.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), source, crop, draw.Over, nil)
+
+		var encoded bytes.Buffer
+		if err := jpeg.Encode(&encoded, thumbnail, &jpeg.Options{Quality: avatarJpegQuality}); err != nil {
+			return nil, err
+		}
+		thumbnails[size] = encoded.Bytes()
+	}
+	return thumbnails, nil
+}
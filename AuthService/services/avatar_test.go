@@ -0,0 +1,157 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePng(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func filledImage(width int, height int, fill func(x int, y int) color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, fill(x, y))
+		}
+	}
+	return img
+}
+
+// A valid 1x1 PNG whose header claims width x height, the pixels it would need are never there
+func pngClaiming(t *testing.T, width uint32, height uint32) []byte {
+	t.Helper()
+	data := encodePng(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	// Signature (8), IHDR length (4) and type (4), then width and height
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func isColor(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	near := func(got uint32, want uint8) bool {
+		diff := int(got>>8) - int(want)
+		return diff > -24 && diff < 24
+	}
+	return near(r, want.R) && near(g, want.G) && near(b, want.B)
+}
+
+func TestProcessAvatar(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, filledImage(64, 64, func(x, y int) color.Color { return blue }), nil); err != nil {
+		t.Fatalf("jpeg.Encode() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+		// Checked on every thumbnail
+		check func(t *testing.T, size int, thumbnail image.Image)
+	}{
+		{name: "plain text", data: []byte("definitely not an image"), wantErr: ErrUnsupportedAvatar},
+		{name: "gif", data: []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), wantErr: ErrUnsupportedAvatar},
+		{name: "png that does not decode", data: append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...), wantErr: ErrUnsupportedAvatar},
+		{name: "too small", data: encodePng(t, filledImage(16, 64, func(x, y int) color.Color { return blue })), wantErr: ErrInvalidAvatarDimension},
+		{name: "too wide", data: encodePng(t, image.NewGray(image.Rect(0, 0, avatarMaxDimension+1, 40))), wantErr: ErrInvalidAvatarDimension},
+		{name: "tiny file claiming a huge canvas", data: pngClaiming(t, 50000, 50000), wantErr: ErrInvalidAvatarDimension},
+		{
+			name: "wide image is cropped to its center",
+			// Red thirds left and right of a blue center third
+			data: encodePng(t, filledImage(300, 100, func(x, y int) color.Color {
+				if x >= 100 && x < 200 {
+					return blue
+				}
+				return red
+			})),
+			check: func(t *testing.T, size int, thumbnail image.Image) {
+				for _, point := range []image.Point{{1, 1}, {size / 2, size / 2}, {size - 2, size - 2}} {
+					if c := thumbnail.At(point.X, point.Y); !isColor(c, blue) {
+						t.Errorf("size %d pixel %v = %v, want the blue center", size, point, c)
+					}
+				}
+			},
+		},
+		{
+			name: "tall image is cropped to its center",
+			data: encodePng(t, filledImage(100, 300, func(x, y int) color.Color {
+				if y >= 100 && y < 200 {
+					return blue
+				}
+				return red
+			})),
+			check: func(t *testing.T, size int, thumbnail image.Image) {
+				if c := thumbnail.At(size/2, 1); !isColor(c, blue) {
+					t.Errorf("size %d top edge = %v, want the blue center", size, c)
+				}
+			},
+		},
+		{
+			name: "transparency is flattened onto white",
+			data: encodePng(t, filledImage(64, 64, func(x, y int) color.Color {
+				if x < 32 {
+					return color.NRGBA{}
+				}
+				return color.NRGBA{B: 255, A: 255}
+			})),
+			check: func(t *testing.T, size int, thumbnail image.Image) {
+				if c := thumbnail.At(2, size/2); !isColor(c, white) {
+					t.Errorf("size %d transparent half = %v, want white", size, c)
+				}
+				if c := thumbnail.At(size-3, size/2); !isColor(c, blue) {
+					t.Errorf("size %d opaque half = %v, want blue", size, c)
+				}
+			},
+		},
+		{name: "jpeg", data: jpegData.Bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumbnails, err := processAvatar(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("processAvatar() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if len(thumbnails) != len(AvatarSizes) {
+				t.Fatalf("got %d thumbnails, want %d", len(thumbnails), len(AvatarSizes))
+			}
+			for _, size := range AvatarSizes {
+				thumbnail, format, err := image.Decode(bytes.NewReader(thumbnails[size]))
+				if err != nil {
+					t.Fatalf("size %d does not decode: %v", size, err)
+				}
+				if format != "jpeg" {
+					t.Errorf("size %d format = %s, want jpeg", size, format)
+				}
+				if bounds := thumbnail.Bounds(); bounds.Dx() != size || bounds.Dy() != size {
+					t.Errorf("size %d is %dx%d", size, bounds.Dx(), bounds.Dy())
+				}
+				if tt.check != nil {
+					tt.check(t, size, thumbnail)
+				}
+			}
+		})
+	}
+}
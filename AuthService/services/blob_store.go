@@ -0,0 +1,214 @@
+package services
+
+import (
+	config "AuthService/config/env"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+var (
+	ErrBlobNotFound   = errors.New("blob not found")
+	ErrInvalidBlobKey = errors.New("invalid blob key")
+)
+
+type BlobInfo struct {
+	ContentType  string
+	Size         int64
+	LastModified time.Time
+}
+
+// Stores immutable files under slash separated keys
+type BlobStore interface {
+	Put(ctx context.Context, key string, contentType string, data []byte) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Picks the S3 store when BLOB_STORE is s3, otherwise files go to BLOB_DIR on the local disk
+func NewBlobStore(ctx context.Context) (BlobStore, error) {
+	if config.GetString("BLOB_STORE", "local") != "s3" {
+		return NewLocalBlobStore(config.GetString("BLOB_DIR", "uploads"))
+	}
+
+	client, err := minio.New(config.GetString("S3_ENDPOINT", "localhost:9000"), &minio.Options{
+		Creds:  credentials.NewStaticV4(config.GetString("S3_ACCESS_KEY", ""), config.GetString("S3_SECRET_KEY", ""), ""),
+		Secure: config.GetBool("S3_USE_SSL", false),
+		Region: config.GetString("S3_REGION", ""),
+	})
+	if err != nil {
+		return nil, err
+	}
+	store := &S3BlobStore{
+		Client: client,
+		Bucket: config.GetString("S3_BUCKET", "avatars"),
+	}
+	if err := store.ensureBucket(ctx); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Keys are always generated by us, anything that could leave the store's root is refused anyway
+func validBlobKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+type LocalBlobStore struct {
+	Root string
+}
+
+func NewLocalBlobStore(root string) (BlobStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{Root: root}, nil
+}
+
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if !validBlobKey(key) {
+		return "", ErrInvalidBlobKey
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(key)), nil
+}
+
+// Writes through a temporary file, so readers never see a half written blob
+func (s *LocalBlobStore) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(target)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, ErrBlobNotFound
+		}
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	// The local disk keeps no metadata, the extension decides the content type
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return file, &BlobInfo{ContentType: contentType, Size: stat.Size(), LastModified: stat.ModTime()}, nil
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	// Drop the directory once its last file is gone, a non empty one stays
+	os.Remove(filepath.Dir(target))
+	return nil
+}
+
+// Any S3 compatible store, MinIO included
+type S3BlobStore struct {
+	Client *minio.Client
+	Bucket string
+}
+
+func (s *S3BlobStore) ensureBucket(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	exists, err := s.Client.BucketExists(ctx, s.Bucket)
+	if err != nil {
+		return fmt.Errorf("checking bucket %s: %w", s.Bucket, err)
+	}
+	if exists {
+		return nil
+	}
+	if err := s.Client.MakeBucket(ctx, s.Bucket, minio.MakeBucketOptions{}); err != nil {
+		return fmt.Errorf("creating bucket %s: %w", s.Bucket, err)
+	}
+	return nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	if !validBlobKey(key) {
+		return ErrInvalidBlobKey
+	}
+	_, err := s.Client.PutObject(ctx, s.Bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error) {
+	if !validBlobKey(key) {
+		return nil, nil, ErrInvalidBlobKey
+	}
+	// GetObject is lazy, Stat is what reaches the store and reports a missing key
+	object, err := s.Client.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := object.Stat()
+	if err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil, ErrBlobNotFound
+		}
+		return nil, nil, err
+	}
+	return object, &BlobInfo{ContentType: stat.ContentType, Size: stat.Size, LastModified: stat.LastModified}, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	if !validBlobKey(key) {
+		return ErrInvalidBlobKey
+	}
+	return s.Client.RemoveObject(ctx, s.Bucket, key, minio.RemoveObjectOptions{})
+}
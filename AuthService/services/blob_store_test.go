@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Put, Get and Delete of one blob, the same for every store
+func testBlobStoreRoundTrip(t *testing.T, store BlobStore) {
+	t.Helper()
+	ctx := context.Background()
+	key := "avatars/0123456789abcdef0123456789abcdef/64.jpg"
+	data := []byte("\xff\xd8\xff thumbnail")
+
+	if err := store.Put(ctx, key, "image/jpeg", data); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	reader, info, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("reading the blob: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("Get() = %q, want %q", got, data)
+	}
+	if info.ContentType != "image/jpeg" || info.Size != int64(len(data)) || info.LastModified.IsZero() {
+		t.Errorf("info = %+v, want image/jpeg of %d bytes with a modification time", info, len(data))
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrBlobNotFound)
+	}
+	// Deleting what is gone is not an error, old thumbnails may already have been cleaned up
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("second Delete() error = %v", err)
+	}
+
+	for _, invalid := range []string{"", "/avatars/a.jpg", "avatars/../a.jpg", "avatars//a.jpg", "avatars\\a.jpg"} {
+		if err := store.Put(ctx, invalid, "image/jpeg", data); !errors.Is(err, ErrInvalidBlobKey) {
+			t.Errorf("Put(%q) error = %v, want %v", invalid, err, ErrInvalidBlobKey)
+		}
+		if _, _, err := store.Get(ctx, invalid); !errors.Is(err, ErrInvalidBlobKey) {
+			t.Errorf("Get(%q) error = %v, want %v", invalid, err, ErrInvalidBlobKey)
+		}
+	}
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "uploads")
+	store, err := NewLocalBlobStore(root)
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore() error = %v", err)
+	}
+	testBlobStoreRoundTrip(t, store)
+
+	// Neither the emptied directory nor a temporary file is left behind
+	entries, err := os.ReadDir(filepath.Join(root, "avatars"))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("left behind %d entries in the avatars directory", len(entries))
+	}
+}
+
+// Runs against a real S3 compatible store, such as the MinIO of docker-compose, only when S3_ENDPOINT is set
+func TestS3BlobStore(t *testing.T) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_ENDPOINT is not set")
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY"), ""),
+		Secure: os.Getenv("S3_USE_SSL") == "true",
+	})
+	if err != nil {
+		t.Fatalf("minio.New() error = %v", err)
+	}
+	store := &S3BlobStore{Client: client, Bucket: "avatars-test-" + strings.ToLower(strings.ReplaceAll(time.Now().UTC().Format("20060102t150405.000000"), ".", "-"))}
+	if err := store.ensureBucket(context.Background()); err != nil {
+		t.Fatalf("ensureBucket() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := client.RemoveBucket(context.Background(), store.Bucket); err != nil {
+			t.Errorf("removing bucket %s: %v", store.Bucket, err)
+		}
+	})
+	// A second call finds the bucket it made
+	if err := store.ensureBucket(context.Background()); err != nil {
+		t.Fatalf("second ensureBucket() error = %v", err)
+	}
+
+	testBlobStoreRoundTrip(t, store)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/models"
+	"AuthService/utils"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTooManyGuestAccounts = errors.New("too many guest accounts, try again later")
+)
+
+type GuestService interface {
+	CreateGuest(ctx context.Context, userAgent string, ipAddress string) (string, *models.User, error)
+	UpgradeGuest(ctx context.Context, current dto.TokenClaimsDTO, username string, email string, password string) (string, *models.User, error)
+}
+
+type GuestServiceImpl struct {
+	userService     UserService
+	guestRepository db.GuestRepository
+
+	ipLimiter *utils.KeyedLimiter
+}
+
+func NewGuestService(userService UserService, guestRepo db.GuestRepository) GuestService {
+	return &GuestServiceImpl{
+		userService:     userService,
+		guestRepository: guestRepo,
+		// 5 guest accounts per client every 10 minutes
+		ipLimiter: utils.NewKeyedLimiter(2*time.Minute, 5),
+	}
+}
+
+// Creates an anonymous account with only the guest role and opens a session for it.
+// The email is a placeholder nobody receives mail on, and the random password is never handed out.
+func (s *GuestServiceImpl) CreateGuest(ctx context.Context, userAgent string, ipAddress string) (string, *models.User, error) {
+	if !s.ipLimiter.Allow(ipAddress) {
+		return "", nil, ErrTooManyGuestAccounts
+	}
+
+	handle, err := utils.RandomToken(6)
+	if err != nil {
+		return "", nil, db.ErrInternalServerError
+	}
+	password, err := utils.RandomToken(32)
+	if err != nil {
+		return "", nil, db.ErrInternalServerError
+	}
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return "", nil, db.ErrInternalServerError
+	}
+
+	user, err := s.guestRepository.Create(ctx, "guest-"+handle, "guest-"+handle+"@guest.problembattles.local", hashedPassword)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := s.userService.StartSession(ctx, user, userAgent, ipAddress)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, user, nil
+}
+
+// Converts the guest into a full account under the same user id and re-issues the token within the same session
+func (s *GuestServiceImpl) UpgradeGuest(ctx context.Context, current dto.TokenClaimsDTO, username string, email string, password string) (string, *models.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return "", nil, db.ErrInternalServerError
+	}
+
+	if err := s.guestRepository.Upgrade(ctx, int64(current.UserId), username, email, hashedPassword); err != nil {
+		return "", nil, err
+	}
+
+	user, err := s.userService.GetById(ctx, strconv.Itoa(current.UserId))
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := s.userService.ValidateUserSession(ctx, user.Email, current.UserId, current)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, user, nil
+}
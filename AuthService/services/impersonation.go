@@ -0,0 +1,71 @@
+package services
+
+import (
+	config "AuthService/config/env"
+	db "AuthService/db/repositories"
+	"AuthService/models"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCannotImpersonateAdmin = errors.New("admins cannot be impersonated")
+)
+
+type ImpersonationService interface {
+	Impersonate(ctx context.Context, impersonatorId int64, userId int64, reason string, ipAddress string) (string, time.Time, error)
+	GetLogs(ctx context.Context, impersonatorId int64, userId int64, limit int) ([]*models.ImpersonationLog, error)
+}
+
+type ImpersonationServiceImpl struct {
+	userService             UserService
+	userRoleRepository      db.UserRoleRepository
+	impersonationRepository db.ImpersonationRepository
+	auditService            AuditService
+}
+
+func NewImpersonationService(userService UserService, userRoleRepo db.UserRoleRepository, impersonationRepo db.ImpersonationRepository, auditService AuditService) ImpersonationService {
+	return &ImpersonationServiceImpl{
+		userService:             userService,
+		userRoleRepository:      userRoleRepo,
+		impersonationRepository: impersonationRepo,
+		auditService:            auditService,
+	}
+}
+
+// Issues a token acting as the user, admins are never impersonated so the token cannot escalate privileges
+func (s *ImpersonationServiceImpl) Impersonate(ctx context.Context, impersonatorId int64, userId int64, reason string, ipAddress string) (string, time.Time, error) {
+	user, err := s.userService.GetById(ctx, strconv.FormatInt(userId, 10))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	roles, err := s.userRoleRepository.GetUserRoles(ctx, userId)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	for _, role := range roles {
+		if strings.EqualFold(role.Name, "admin") {
+			return "", time.Time{}, ErrCannotImpersonateAdmin
+		}
+	}
+
+	// The start is recorded before the token exists, so every impersonation is accounted for
+	if err := s.impersonationRepository.LogStart(ctx, impersonatorId, userId, reason, ipAddress); err != nil {
+		return "", time.Time{}, err
+	}
+	s.auditService.Record(ctx, AuditImpersonation, "user", userId, nil, map[string]any{"reason": reason})
+
+	ttl := time.Duration(config.GetInt("IMPERSONATION_TTL_MINUTES", 15)) * time.Minute
+	return s.userService.IssueImpersonationToken(ctx, user, impersonatorId, ttl)
+}
+
+func (s *ImpersonationServiceImpl) GetLogs(ctx context.Context, impersonatorId int64, userId int64, limit int) ([]*models.ImpersonationLog, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.impersonationRepository.GetLogs(ctx, impersonatorId, userId, limit)
+}
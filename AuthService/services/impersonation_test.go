@@ -0,0 +1,89 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeImpersonationUsers struct {
+	UserService
+	issued bool
+}
+
+func (f *fakeImpersonationUsers) GetById(ctx context.Context, id string) (*models.User, error) {
+	return &models.User{Id: 42, Email: "ada@example.com"}, nil
+}
+
+func (f *fakeImpersonationUsers) IssueImpersonationToken(ctx context.Context, user *models.User, impersonatorId int64, ttl time.Duration) (string, time.Time, error) {
+	f.issued = true
+	return "impersonation-token", time.Now().Add(ttl), nil
+}
+
+type fakeImpersonationRoles struct {
+	db.UserRoleRepository
+	roles []*models.Role
+}
+
+func (f *fakeImpersonationRoles) GetUserRoles(ctx context.Context, userId int64) ([]*models.Role, error) {
+	return f.roles, nil
+}
+
+type fakeImpersonationLog struct {
+	db.ImpersonationRepository
+	started bool
+}
+
+func (f *fakeImpersonationLog) LogStart(ctx context.Context, impersonatorId int64, userId int64, reason string, ipAddress string) error {
+	f.started = true
+	return nil
+}
+
+type fakeAudit struct {
+	AuditService
+	actions []string
+}
+
+func (f *fakeAudit) Record(ctx context.Context, action string, targetType string, targetId any, before any, after any) {
+	f.actions = append(f.actions, action)
+}
+
+func TestImpersonate(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   []string
+		wantErr error
+	}{
+		{name: "user", roles: []string{"user"}},
+		{name: "admin is refused", roles: []string{"user", "admin"}, wantErr: ErrCannotImpersonateAdmin},
+		{name: "admin in any case is refused", roles: []string{"Admin"}, wantErr: ErrCannotImpersonateAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles := &fakeImpersonationRoles{}
+			for _, name := range tt.roles {
+				roles.roles = append(roles.roles, &models.Role{Name: name})
+			}
+			users, log, audit := &fakeImpersonationUsers{}, &fakeImpersonationLog{}, &fakeAudit{}
+			service := NewImpersonationService(users, roles, log, audit)
+
+			token, _, err := service.Impersonate(context.Background(), 7, 42, "support ticket", "203.0.113.9")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Impersonate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				// A refused impersonation leaves no trace of a start and issues nothing
+				if token != "" || users.issued || log.started || len(audit.actions) != 0 {
+					t.Errorf("refused impersonation issued %q, started %v, audited %v", token, log.started, audit.actions)
+				}
+				return
+			}
+			if token == "" || !log.started || len(audit.actions) != 1 || audit.actions[0] != AuditImpersonation {
+				t.Errorf("token %q, started %v, audited %v, want a token after the start was logged and audited", token, log.started, audit.actions)
+			}
+		})
+	}
+}
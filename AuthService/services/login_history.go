@@ -0,0 +1,113 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/models"
+	"AuthService/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	LoginOutcomeSuccess         = "success"
+	LoginOutcomeInvalidPassword = "invalid_password"
+	LoginOutcomeUnknownUser     = "unknown_user"
+)
+
+type LoginHistoryService interface {
+	RecordAttempt(ctx context.Context, userId int64, email string, outcome string, userAgent string, ipAddress string)
+	GetHistory(ctx context.Context, userId int64, page int, limit int) (*models.Page[*models.LoginAttempt], error)
+}
+
+type LoginHistoryServiceImpl struct {
+	loginAttemptRepository db.LoginAttemptRepository
+	mailer                 Mailer
+}
+
+func NewLoginHistoryService(loginAttemptRepo db.LoginAttemptRepository, mailer Mailer) LoginHistoryService {
+	return &LoginHistoryServiceImpl{
+		loginAttemptRepository: loginAttemptRepo,
+		mailer:                 mailer,
+	}
+}
+
+// Stores the attempt and alerts the user when a successful login comes from a device and network they never used.
+// Failures are only logged, a login must not fail because its history could not be written.
+func (s *LoginHistoryServiceImpl) RecordAttempt(ctx context.Context, userId int64, email string, outcome string, userAgent string, ipAddress string) {
+	attempt := &models.LoginAttempt{
+		UserId:      userId,
+		Email:       email,
+		Outcome:     outcome,
+		IpAddress:   ipAddress,
+		IpRange:     utils.IPRange(ipAddress),
+		UserAgent:   userAgent,
+		DeviceLabel: utils.DeviceLabel(userAgent),
+	}
+
+	if outcome == LoginOutcomeSuccess {
+		// The first login of an account has nothing to compare against
+		hasLoggedIn, known, err := s.loginAttemptRepository.IsKnownDevice(ctx, userId, attempt.DeviceLabel, attempt.IpRange)
+		if err == nil {
+			attempt.NewDevice = hasLoggedIn && !known
+		}
+	}
+
+	if err := s.loginAttemptRepository.Create(ctx, attempt); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":     err,
+			"user_id": userId,
+			"outcome": outcome,
+			"type":    "login_history_error",
+		}).Error("Login attempt could not be recorded")
+	}
+
+	if attempt.NewDevice {
+		go s.alertNewDevice(attempt, utils.GetRequestMeta(ctx).CorrelationId)
+	}
+}
+
+func (s *LoginHistoryServiceImpl) GetHistory(ctx context.Context, userId int64, page int, limit int) (*models.Page[*models.LoginAttempt], error) {
+	attempts, total, err := s.loginAttemptRepository.GetByUserId(ctx, userId, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Page[*models.LoginAttempt]{
+		Items: attempts,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}, nil
+}
+
+func (s *LoginHistoryServiceImpl) alertNewDevice(attempt *models.LoginAttempt, correlationId string) {
+	message, err := json.Marshal(map[string]any{
+		"type":           "new_device_login",
+		"device_label":   attempt.DeviceLabel,
+		"ip_address":     attempt.IpAddress,
+		"at":             time.Now().Format(time.RFC3339),
+		"correlation_id": correlationId,
+	})
+	if err == nil {
+		SendToUser(int(attempt.UserId), message)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = s.mailer.Send(ctx, MailMessage{
+		To:      attempt.Email,
+		Subject: "New sign-in to your Problem Battles account",
+		Body: fmt.Sprintf("We noticed a sign-in to your account from a new device.\n\nDevice: %s\nIP address: %s\nTime: %s\n\nIf this was you, you can ignore this email. Otherwise change your password and revoke the session from your account settings.\n",
+			attempt.DeviceLabel, attempt.IpAddress, time.Now().UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "mail_error",
+		}).Error("New device alert mail failed")
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	config "AuthService/config/env"
+	db "AuthService/db/repositories"
+	"AuthService/models"
+	"AuthService/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrTooManyLoginLinkRequests = errors.New("too many login link requests, try again later")
+)
+
+type MagicLinkService interface {
+	RequestLink(ctx context.Context, email string, ipAddress string) error
+	Login(ctx context.Context, token string, userAgent string, ipAddress string) (string, *models.User, error)
+}
+
+type MagicLinkServiceImpl struct {
+	userService         UserService
+	userRepository      db.UserRepository
+	magicLinkRepository db.MagicLinkRepository
+	mailer              Mailer
+
+	emailLimiter *utils.KeyedLimiter
+	ipLimiter    *utils.KeyedLimiter
+}
+
+func NewMagicLinkService(userService UserService, userRepo db.UserRepository, magicLinkRepo db.MagicLinkRepository, mailer Mailer) MagicLinkService {
+	return &MagicLinkServiceImpl{
+		userService:         userService,
+		userRepository:      userRepo,
+		magicLinkRepository: magicLinkRepo,
+		mailer:              mailer,
+		// 3 links per address every 15 minutes, 10 per client every 10 minutes
+		emailLimiter: utils.NewKeyedLimiter(5*time.Minute, 3),
+		ipLimiter:    utils.NewKeyedLimiter(time.Minute, 10),
+	}
+}
+
+// Emails a single-use login link. Unknown addresses get the same answer, so the endpoint cannot be used to find accounts.
+func (s *MagicLinkServiceImpl) RequestLink(ctx context.Context, email string, ipAddress string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !s.ipLimiter.Allow(ipAddress) || !s.emailLimiter.Allow(email) {
+		return ErrTooManyLoginLinkRequests
+	}
+
+	user, err := s.userRepository.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	tokenId, err := utils.RandomToken(16)
+	if err != nil {
+		return db.ErrInternalServerError
+	}
+	ttl := time.Duration(config.GetInt("MAGIC_LINK_TTL_MINUTES", 15)) * time.Minute
+	if err := s.magicLinkRepository.Create(ctx, tokenId, user.Id, ipAddress, ttl); err != nil {
+		return err
+	}
+	token, err := utils.CreateMagicLinkToken(user.Id, tokenId, ttl)
+	if err != nil {
+		return db.ErrInternalServerError
+	}
+
+	// Sent in the background so the response time does not reveal whether the account exists
+	go s.sendLink(user, token, ttl)
+
+	return nil
+}
+
+// Exchanges a login link for a session, the same way a password login does
+func (s *MagicLinkServiceImpl) Login(ctx context.Context, token string, userAgent string, ipAddress string) (string, *models.User, error) {
+	userId, tokenId, err := utils.ParseMagicLinkToken(token)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.magicLinkRepository.Consume(ctx, tokenId, userId); err != nil {
+		return "", nil, err
+	}
+
+	user, err := s.userRepository.GetById(ctx, strconv.FormatInt(userId, 10))
+	if err != nil {
+		return "", nil, err
+	}
+	// Following the link proves the user owns the address
+	if _, err := s.userRepository.MarkVerified(ctx, userId); err != nil {
+		return "", nil, err
+	}
+
+	accessToken, err := s.userService.StartSession(ctx, user, userAgent, ipAddress)
+	if err != nil {
+		return "", nil, err
+	}
+	return accessToken, user, nil
+}
+
+func (s *MagicLinkServiceImpl) sendLink(user *models.User, token string, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := s.mailer.Send(ctx, MailMessage{
+		To:      user.Email,
+		Subject: "Your Problem Battles login link",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this link to sign in to Problem Battles. It works once and expires in %d minutes:\n\n%s/magic-link?token=%s\n\nIf you did not ask for it, you can ignore this email.\n",
+			user.Username, int(ttl.Minutes()), config.GetString("APP_URL", "http://localhost:3005"), token),
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "mail_error",
+		}).Error("Login link mail failed")
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	config "AuthService/config/env"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message MailMessage) error
+}
+
+// Picks the SMTP mailer when SMTP_ADDR is set, otherwise mails are only logged
+func NewMailer() Mailer {
+	addr := config.GetString("SMTP_ADDR", "")
+	from := config.GetString("SMTP_FROM", "no-reply@problembattles.local")
+	if addr == "" {
+		return &LogMailer{From: from}
+	}
+
+	var auth smtp.Auth
+	if user := config.GetString("SMTP_USER", ""); user != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", user, config.GetString("SMTP_PASSWORD", ""), host)
+	}
+
+	return &SMTPMailer{
+		Addr: addr,
+		From: from,
+		Auth: auth,
+	}
+}
+
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message MailMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	body := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + message.To,
+		"Subject: " + message.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		message.Body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(m.Addr, m.Auth, m.From, []string{message.To}, []byte(body)); err != nil {
+		return fmt.Errorf("sending mail to %s: %w", message.To, err)
+	}
+	return nil
+}
+
+type LogMailer struct {
+	From string
+}
+
+func (m *LogMailer) Send(ctx context.Context, message MailMessage) error {
+	logrus.WithFields(logrus.Fields{
+		"to":      message.To,
+		"subject": message.Subject,
+		"body":    message.Body,
+		"type":    "mail_log",
+	}).Info("Mail not sent, SMTP_ADDR is not configured")
+	return nil
+}
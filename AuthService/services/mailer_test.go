@@ -0,0 +1,267 @@
+package services
+
+import (
+	"AuthService/models"
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Records what would have been mailed
+type fakeMailer struct {
+	mu       sync.Mutex
+	messages []MailMessage
+	sent     chan MailMessage
+	err      error
+}
+
+func newFakeMailer() *fakeMailer {
+	return &fakeMailer{sent: make(chan MailMessage, 16)}
+}
+
+func (m *fakeMailer) Send(ctx context.Context, message MailMessage) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.mu.Lock()
+	m.messages = append(m.messages, message)
+	m.mu.Unlock()
+	m.sent <- message
+	return nil
+}
+
+func (m *fakeMailer) wait(t *testing.T) MailMessage {
+	t.Helper()
+	select {
+	case message := <-m.sent:
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatal("no mail was sent")
+		return MailMessage{}
+	}
+}
+
+func assertMail(t *testing.T, message MailMessage, to string, subject string, bodyParts ...string) {
+	t.Helper()
+	if message.To != to {
+		t.Errorf("To = %q, want %q", message.To, to)
+	}
+	if message.Subject != subject {
+		t.Errorf("Subject = %q, want %q", message.Subject, subject)
+	}
+	for _, part := range bodyParts {
+		if !strings.Contains(message.Body, part) {
+			t.Errorf("Body does not contain %q:\n%s", part, message.Body)
+		}
+	}
+}
+
+func TestInviteMail(t *testing.T) {
+	t.Setenv("APP_URL", "http://app.test")
+	entries := []*models.UserImportEntry{
+		{Row: 1, Email: "ada@example.com", Username: "ada", HashedPassword: "hash-1"},
+		{Row: 2, Email: "bob@example.com", Username: "bob", HashedPassword: "hash-2"},
+	}
+
+	t.Run("sent inline", func(t *testing.T) {
+		mailer := newFakeMailer()
+		service := &UserImportServiceImpl{mailer: mailer}
+		results := []*models.UserImportResult{
+			{Row: 1, Email: "ada@example.com", Username: "ada", Status: "created", UserId: 7},
+			{Row: 2, Email: "bob@example.com", Username: "bob", Status: "failed"},
+		}
+
+		service.sendInvites(context.Background(), entries, results, false)
+
+		if len(mailer.messages) != 1 {
+			t.Fatalf("sent %d mails, want 1", len(mailer.messages))
+		}
+		assertMail(t, mailer.messages[0], "ada@example.com", "You have been invited to Problem Battles",
+			"Hi ada,", "http://app.test/invite?token=")
+		if !results[0].InviteSent || results[0].InviteQueued {
+			t.Errorf("result = %+v, want the invite sent", results[0])
+		}
+		if results[1].InviteSent {
+			t.Error("a failed row got an invite")
+		}
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		mailer := newFakeMailer()
+		service := &UserImportServiceImpl{mailer: mailer}
+		results := []*models.UserImportResult{
+			{Row: 1, Email: "ada@example.com", Username: "ada", Status: "created", UserId: 7},
+			{Row: 2, Email: "bob@example.com", Username: "bob", Status: "created", UserId: 8},
+		}
+
+		service.sendInvites(context.Background(), entries, results, true)
+
+		for _, result := range results {
+			if !result.InviteQueued || result.InviteSent {
+				t.Errorf("result = %+v, want the invite queued", result)
+			}
+		}
+		assertMail(t, mailer.wait(t), "ada@example.com", "You have been invited to Problem Battles", "Hi ada,")
+		assertMail(t, mailer.wait(t), "bob@example.com", "You have been invited to Problem Battles", "Hi bob,")
+	})
+
+	t.Run("failure is reported", func(t *testing.T) {
+		mailer := newFakeMailer()
+		mailer.err = errors.New("smtp down")
+		service := &UserImportServiceImpl{mailer: mailer}
+		results := []*models.UserImportResult{
+			{Row: 1, Email: "ada@example.com", Username: "ada", Status: "created", UserId: 7},
+		}
+
+		service.sendInvites(context.Background(), entries, results, false)
+
+		if results[0].InviteSent {
+			t.Error("InviteSent is set although sending failed")
+		}
+	})
+}
+
+func TestNewDeviceAlertMail(t *testing.T) {
+	mailer := newFakeMailer()
+	service := &LoginHistoryServiceImpl{mailer: mailer}
+
+	service.alertNewDevice(&models.LoginAttempt{
+		UserId:      7,
+		Email:       "ada@example.com",
+		IpAddress:   "203.0.113.9",
+		DeviceLabel: "Firefox on Linux",
+	}, "correlation-1")
+
+	assertMail(t, mailer.wait(t), "ada@example.com", "New sign-in to your Problem Battles account",
+		"Device: Firefox on Linux", "IP address: 203.0.113.9")
+}
+
+func TestMagicLinkMail(t *testing.T) {
+	t.Setenv("APP_URL", "http://app.test")
+	mailer := newFakeMailer()
+	service := &MagicLinkServiceImpl{mailer: mailer}
+
+	service.sendLink(&models.User{Id: 7, Username: "ada", Email: "ada@example.com"}, "link-token", 15*time.Minute)
+
+	assertMail(t, mailer.wait(t), "ada@example.com", "Your Problem Battles login link",
+		"Hi ada,", "expires in 15 minutes", "http://app.test/magic-link?token=link-token")
+}
+
+// Enough of an SMTP server to take one mail, like the local sink does
+type smtpSink struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func startSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	sink := &smtpSink{listener: listener, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		defer close(sink.done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 sink ready")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 sink")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				sink.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+				reply("250 ok")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				sink.to = append(sink.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+				reply("250 ok")
+			case command == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				sink.data = data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return sink
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	sink := startSMTPSink(t)
+	mailer := &SMTPMailer{Addr: sink.listener.Addr().String(), From: "no-reply@problembattles.local"}
+
+	err := mailer.Send(context.Background(), MailMessage{
+		To:      "ada@example.com",
+		Subject: "Your Problem Battles login link",
+		Body:    "Hi ada,\r\n\r\nfollow the link",
+	})
+	if err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+	<-sink.done
+
+	if sink.from != "no-reply@problembattles.local" {
+		t.Errorf("MAIL FROM = %q", sink.from)
+	}
+	if len(sink.to) != 1 || sink.to[0] != "ada@example.com" {
+		t.Errorf("RCPT TO = %v, want [ada@example.com]", sink.to)
+	}
+	for _, part := range []string{
+		"From: no-reply@problembattles.local\r\n",
+		"To: ada@example.com\r\n",
+		"Subject: Your Problem Battles login link\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+		"\r\n\r\nHi ada,\r\n\r\nfollow the link",
+	} {
+		if !strings.Contains(sink.data, part) {
+			t.Errorf("mail data does not contain %q:\n%s", part, sink.data)
+		}
+	}
+}
+
+func TestSMTPMailerSendCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mailer := &SMTPMailer{Addr: "127.0.0.1:1", From: "no-reply@problembattles.local"}
+	if err := mailer.Send(ctx, MailMessage{To: "ada@example.com"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Send() = %v, want %v", err, context.Canceled)
+	}
+}
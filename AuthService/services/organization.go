@@ -0,0 +1,193 @@
+package services
+
+import (
+	config "AuthService/config/env"
+	db "AuthService/db/repositories"
+	"AuthService/models"
+	"AuthService/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidOrgSlug = errors.New("slug may only contain lowercase letters, digits and dashes")
+	ErrOrgForbidden   = errors.New("you do not have the required organization role")
+)
+
+const orgInvitationTTL = 7 * 24 * time.Hour
+
+var orgSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, actorId int64, name string, slug string) (*models.Organization, error)
+	GetUserOrganizations(ctx context.Context, userId int64) ([]*models.Organization, error)
+	GetOrganization(ctx context.Context, actorId int64, orgId int64) (*models.Organization, error)
+	GetMembers(ctx context.Context, actorId int64, orgId int64) ([]*models.OrgMember, error)
+	UpdateMemberRole(ctx context.Context, actorId int64, orgId int64, userId int64, role string) (bool, error)
+	RemoveMember(ctx context.Context, actorId int64, orgId int64, userId int64) (bool, error)
+	InviteMember(ctx context.Context, actorId int64, orgId int64, email string, role string) (*models.OrgInvitation, error)
+	GetPendingInvitations(ctx context.Context, actorId int64, orgId int64) ([]*models.OrgInvitation, error)
+	AcceptInvitation(ctx context.Context, userId int64, email string, token string) (*models.OrgInvitation, error)
+}
+
+type OrganizationServiceImpl struct {
+	organizationRepository db.OrganizationRepository
+	mailer                 Mailer
+}
+
+func NewOrganizationService(organizationRepo db.OrganizationRepository, mailer Mailer) OrganizationService {
+	return &OrganizationServiceImpl{
+		organizationRepository: organizationRepo,
+		mailer:                 mailer,
+	}
+}
+
+func (s *OrganizationServiceImpl) CreateOrganization(ctx context.Context, actorId int64, name string, slug string) (*models.Organization, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if !orgSlugPattern.MatchString(slug) {
+		return nil, ErrInvalidOrgSlug
+	}
+	return s.organizationRepository.CreateOrganization(ctx, strings.TrimSpace(name), slug, actorId)
+}
+
+func (s *OrganizationServiceImpl) GetUserOrganizations(ctx context.Context, userId int64) ([]*models.Organization, error) {
+	return s.organizationRepository.GetUserOrganizations(ctx, userId)
+}
+
+func (s *OrganizationServiceImpl) GetOrganization(ctx context.Context, actorId int64, orgId int64) (*models.Organization, error) {
+	role, err := s.requireOrgRole(ctx, orgId, actorId, "owner", "admin", "member")
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.organizationRepository.GetOrganizationById(ctx, orgId)
+	if err != nil {
+		return nil, err
+	}
+	org.Role = role
+	return org, nil
+}
+
+func (s *OrganizationServiceImpl) GetMembers(ctx context.Context, actorId int64, orgId int64) ([]*models.OrgMember, error) {
+	if _, err := s.requireOrgRole(ctx, orgId, actorId, "owner", "admin", "member"); err != nil {
+		return nil, err
+	}
+	return s.organizationRepository.GetMembers(ctx, orgId)
+}
+
+// Admins manage members and admins, owners are only granted or changed by owners
+func (s *OrganizationServiceImpl) UpdateMemberRole(ctx context.Context, actorId int64, orgId int64, userId int64, role string) (bool, error) {
+	actorRole, err := s.requireOrgRole(ctx, orgId, actorId, "owner", "admin")
+	if err != nil {
+		return false, err
+	}
+
+	targetRole, err := s.organizationRepository.GetMembershipRole(ctx, orgId, userId)
+	if err != nil {
+		return false, err
+	}
+	if (role == "owner" || targetRole == "owner") && actorRole != "owner" {
+		return false, ErrOrgForbidden
+	}
+
+	return s.organizationRepository.UpdateMemberRole(ctx, orgId, userId, role)
+}
+
+// Members may always leave, removing someone else needs admin and owners can only be removed by owners
+func (s *OrganizationServiceImpl) RemoveMember(ctx context.Context, actorId int64, orgId int64, userId int64) (bool, error) {
+	if actorId != userId {
+		actorRole, err := s.requireOrgRole(ctx, orgId, actorId, "owner", "admin")
+		if err != nil {
+			return false, err
+		}
+
+		targetRole, err := s.organizationRepository.GetMembershipRole(ctx, orgId, userId)
+		if err != nil {
+			return false, err
+		}
+		if targetRole == "owner" && actorRole != "owner" {
+			return false, ErrOrgForbidden
+		}
+	}
+
+	return s.organizationRepository.RemoveMember(ctx, orgId, userId)
+}
+
+func (s *OrganizationServiceImpl) InviteMember(ctx context.Context, actorId int64, orgId int64, email string, role string) (*models.OrgInvitation, error) {
+	if _, err := s.requireOrgRole(ctx, orgId, actorId, "owner", "admin"); err != nil {
+		return nil, err
+	}
+
+	org, err := s.organizationRepository.GetOrganizationById(ctx, orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, db.ErrInternalServerError
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	invitation, err := s.organizationRepository.CreateInvitation(ctx, orgId, email, role, hashOrgInvitationToken(token), actorId, orgInvitationTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mailer.Send(ctx, MailMessage{
+		To:      email,
+		Subject: fmt.Sprintf("You have been invited to join %s on Problem Battles", org.Name),
+		Body: fmt.Sprintf("You have been invited to join %s as %s.\nAccept the invitation within 7 days:\n\n%s/orgs/invitations/accept?token=%s\n",
+			org.Name, role, config.GetString("APP_URL", "http://localhost:3005"), token),
+	})
+	if err != nil {
+		// The invitation stays valid, an admin can resend it
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "mail_error",
+		}).Error("Organization invitation mail failed")
+	}
+
+	return invitation, nil
+}
+
+func (s *OrganizationServiceImpl) GetPendingInvitations(ctx context.Context, actorId int64, orgId int64) ([]*models.OrgInvitation, error) {
+	if _, err := s.requireOrgRole(ctx, orgId, actorId, "owner", "admin"); err != nil {
+		return nil, err
+	}
+	return s.organizationRepository.GetPendingInvitations(ctx, orgId)
+}
+
+func (s *OrganizationServiceImpl) AcceptInvitation(ctx context.Context, userId int64, email string, token string) (*models.OrgInvitation, error) {
+	return s.organizationRepository.AcceptInvitation(ctx, hashOrgInvitationToken(token), userId, email)
+}
+
+// Returns the actor's role in the organization when it is one of the allowed roles
+func (s *OrganizationServiceImpl) requireOrgRole(ctx context.Context, orgId int64, actorId int64, allowed ...string) (string, error) {
+	role, err := s.organizationRepository.GetMembershipRole(ctx, orgId, actorId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotOrgMember) {
+			return "", ErrOrgForbidden
+		}
+		return "", err
+	}
+	if !slices.Contains(allowed, role) {
+		return "", ErrOrgForbidden
+	}
+	return role, nil
+}
+
+// Only the hash of an invitation token is stored
+func hashOrgInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
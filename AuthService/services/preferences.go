@@ -0,0 +1,153 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/models"
+	"AuthService/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// A write that loses a race against another session is retried this many times
+const preferencesMaxAttempts = 3
+
+var defaultPreferences = models.Preferences{
+	EditorTheme: "vs",
+	Language:    "cpp",
+	FontSize:    14,
+	Keybindings: "default",
+}
+
+type PreferencesService interface {
+	Get(ctx context.Context, userId int64) (*models.UserPreferences, error)
+	Replace(ctx context.Context, userId int64, sessionId string, update dto.PreferencesDTO, expectedVersion *int) (*models.UserPreferences, error)
+	Patch(ctx context.Context, userId int64, sessionId string, patch dto.PatchPreferencesDTO, expectedVersion *int) (*models.UserPreferences, error)
+}
+
+type PreferencesServiceImpl struct {
+	preferencesRepository db.PreferencesRepository
+}
+
+func NewPreferencesService(preferencesRepo db.PreferencesRepository) PreferencesService {
+	return &PreferencesServiceImpl{
+		preferencesRepository: preferencesRepo,
+	}
+}
+
+// Users who never saved anything get the defaults at version 0
+func (s *PreferencesServiceImpl) Get(ctx context.Context, userId int64) (*models.UserPreferences, error) {
+	preferences, err := s.preferencesRepository.GetByUserId(ctx, userId)
+	if err != nil {
+		if !errors.Is(err, db.ErrPreferencesNotFound) {
+			return nil, err
+		}
+		preferences = &models.UserPreferences{}
+	}
+
+	// Documents written before a setting existed get its default
+	current := &preferences.Preferences
+	if current.EditorTheme == "" {
+		current.EditorTheme = defaultPreferences.EditorTheme
+	}
+	if current.Language == "" {
+		current.Language = defaultPreferences.Language
+	}
+	if current.FontSize == 0 {
+		current.FontSize = defaultPreferences.FontSize
+	}
+	if current.Keybindings == "" {
+		current.Keybindings = defaultPreferences.Keybindings
+	}
+	preferences.Hash = preferencesHash(preferences.Preferences)
+	return preferences, nil
+}
+
+func (s *PreferencesServiceImpl) Replace(ctx context.Context, userId int64, sessionId string, update dto.PreferencesDTO, expectedVersion *int) (*models.UserPreferences, error) {
+	return s.update(ctx, userId, sessionId, expectedVersion, func(preferences *models.Preferences) {
+		*preferences = models.Preferences{
+			EditorTheme: update.EditorTheme,
+			Language:    update.Language,
+			FontSize:    update.FontSize,
+			Keybindings: update.Keybindings,
+		}
+	})
+}
+
+func (s *PreferencesServiceImpl) Patch(ctx context.Context, userId int64, sessionId string, patch dto.PatchPreferencesDTO, expectedVersion *int) (*models.UserPreferences, error) {
+	return s.update(ctx, userId, sessionId, expectedVersion, func(preferences *models.Preferences) {
+		if patch.EditorTheme != nil {
+			preferences.EditorTheme = *patch.EditorTheme
+		}
+		if patch.Language != nil {
+			preferences.Language = *patch.Language
+		}
+		if patch.FontSize != nil {
+			preferences.FontSize = *patch.FontSize
+		}
+		if patch.Keybindings != nil {
+			preferences.Keybindings = *patch.Keybindings
+		}
+	})
+}
+
+// Applies the change on top of the stored version and tells the user's other sessions.
+// With an expected version the write fails as soon as someone else got there first,
+// without one a lost race is retried against the newer version.
+func (s *PreferencesServiceImpl) update(ctx context.Context, userId int64, sessionId string, expectedVersion *int, apply func(*models.Preferences)) (*models.UserPreferences, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := s.Get(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		if expectedVersion != nil && *expectedVersion != current.Version {
+			return nil, db.ErrPreferencesVersionConflict
+		}
+
+		updated := current.Preferences
+		apply(&updated)
+		if updated == current.Preferences {
+			return current, nil
+		}
+
+		err = s.preferencesRepository.Save(ctx, userId, updated, current.Version)
+		if errors.Is(err, db.ErrPreferencesVersionConflict) && expectedVersion == nil && attempt < preferencesMaxAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	saved, err := s.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	notifyPreferences(ctx, userId, sessionId, saved)
+	return saved, nil
+}
+
+// Short fingerprint of the settings, clients compare it with their cached copy instead of refetching
+func preferencesHash(preferences models.Preferences) string {
+	encoded, _ := json.Marshal(preferences)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8])
+}
+
+func notifyPreferences(ctx context.Context, userId int64, sessionId string, preferences *models.UserPreferences) {
+	message, err := json.Marshal(map[string]any{
+		"type":           "preferences_updated",
+		"version":        preferences.Version,
+		"hash":           preferences.Hash,
+		"preferences":    preferences.Preferences,
+		"correlation_id": utils.GetRequestMeta(ctx).CorrelationId,
+	})
+	if err != nil {
+		return
+	}
+	SendToUserExcept(int(userId), sessionId, message)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/models"
+	"context"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ProfileService interface {
+	GetPublicProfile(ctx context.Context, username string) (*models.PublicProfile, error)
+	GetOwnProfile(ctx context.Context, userId int64) (*models.UserProfile, error)
+	UpdateOwnProfile(ctx context.Context, userId int64, update dto.UpdateProfileDTO) (*models.UserProfile, error)
+}
+
+type ProfileServiceImpl struct {
+	profileRepository db.ProfileRepository
+	statsClient       SubmissionStatsClient
+}
+
+func NewProfileService(profileRepo db.ProfileRepository, statsClient SubmissionStatsClient) ProfileService {
+	return &ProfileServiceImpl{
+		profileRepository: profileRepo,
+		statsClient:       statsClient,
+	}
+}
+
+// Builds what anyone may see of a profile, private fields are dropped before they leave the service.
+// Stats come from the SubmissionService; when it is unreachable the profile is served without them.
+func (s *ProfileServiceImpl) GetPublicProfile(ctx context.Context, username string) (*models.PublicProfile, error) {
+	profile, err := s.profileRepository.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	public := &models.PublicProfile{Username: profile.Username}
+	if profile.Privacy.DisplayName {
+		public.DisplayName = profile.DisplayName
+	}
+	if profile.Privacy.Bio {
+		public.Bio = profile.Bio
+	}
+	if profile.Privacy.Avatar && profile.AvatarKey != "" {
+		public.AvatarUrl, public.AvatarUrls = avatarUrls(profile.AvatarKey)
+	}
+	if profile.Privacy.JoinDate {
+		public.JoinedAt = profile.JoinedAt
+	}
+	if profile.Privacy.Stats {
+		stats, err := s.statsClient.GetStats(ctx, profile.UserId)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"err":      err,
+				"username": profile.Username,
+				"type":     "profile_stats_error",
+			}).Warn("Submission stats unavailable")
+		} else {
+			public.Stats = stats
+		}
+	}
+
+	return public, nil
+}
+
+func (s *ProfileServiceImpl) GetOwnProfile(ctx context.Context, userId int64) (*models.UserProfile, error) {
+	profile, err := s.profileRepository.GetByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if profile.AvatarKey != "" {
+		profile.AvatarUrl, profile.AvatarUrls = avatarUrls(profile.AvatarKey)
+	}
+	return profile, nil
+}
+
+func (s *ProfileServiceImpl) UpdateOwnProfile(ctx context.Context, userId int64, update dto.UpdateProfileDTO) (*models.UserProfile, error) {
+	profile := &models.UserProfile{
+		UserId:      userId,
+		DisplayName: update.DisplayName,
+		Bio:         update.Bio,
+		Privacy: models.ProfilePrivacy{
+			DisplayName: update.Privacy.DisplayName,
+			Bio:         update.Privacy.Bio,
+			Avatar:      update.Privacy.Avatar,
+			JoinDate:    update.Privacy.JoinDate,
+			Stats:       update.Privacy.Stats,
+		},
+	}
+	if err := s.profileRepository.Upsert(ctx, profile); err != nil {
+		return nil, err
+	}
+	return s.GetOwnProfile(ctx, userId)
+}
+
+// The default size and every thumbnail size of an uploaded avatar
+func avatarUrls(avatarKey string) (string, models.AvatarUrls) {
+	urls := models.AvatarUrls{}
+	for _, size := range AvatarSizes {
+		urls[strconv.Itoa(size)] = AvatarUrl(avatarKey, size)
+	}
+	return AvatarUrl(avatarKey, avatarDefaultSize), urls
+}
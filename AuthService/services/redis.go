@@ -2,20 +2,30 @@ package services
 
 import (
 	config "AuthService/config/env"
+	"AuthService/metrics"
+	"AuthService/tracing"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync/atomic"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Published by the submission service once a submission is judged, the correlation id is the one of its request
 type EvaluatedSubmission struct {
-	Status       string `json="status,omitempty"`
-	SubmissionId string `json="submissionId,omitempty"`
-	ProblemId    string `json="problemId,omitempty"`
-	UserId       string `json="userId,omitempty"`
+	Status        string `json:"status,omitempty"`
+	SubmissionId  string `json:"submissionId,omitempty"`
+	ProblemId     string `json:"problemId,omitempty"`
+	UserId        string `json:"userId,omitempty"`
+	CorrelationId string `json:"correlationId,omitempty"`
 }
 
 func RedisConn() *redis.Client {
@@ -23,6 +33,13 @@ func RedisConn() *redis.Client {
 		Addr: config.GetString("REDIS_URL", "localhost:6379"),
 	})
 
+	if err := redisotel.InstrumentTracing(conn); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "redis_error",
+		}).Error("Redis tracing could not be enabled")
+	}
+
 	if err := conn.Ping(context.Background()).Err(); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"err":  fmt.Sprintf("Error in redis connection: %s", err),
@@ -38,30 +55,75 @@ func RedisConn() *redis.Client {
 	return conn
 }
 
+// The worker's subscription, nil while it is not running
+var evaluatedSub atomic.Pointer[redis.PubSub]
+
+var ErrEvaluationWorkerStopped = errors.New("evaluated subscription is not running")
+
 func StartEvaluationWorker(conn *redis.Client) {
 	ctx := context.Background()
 
 	sub := conn.Subscribe(ctx, "evaluated")
 	defer sub.Close()
+	evaluatedSub.Store(sub)
+	defer evaluatedSub.Store(nil)
 
 	for msg := range sub.Channel() {
-		var payload EvaluatedSubmission
-		err := json.Unmarshal([]byte(msg.Payload), &payload)
-		if err != nil {
-			logrus.Error("failed to read evaluated job data:", err)
-			continue
-		}
-
-		logrus.Info("Evaluated job payload:", payload)
-
-		data, err := json.Marshal(payload)
-		if err != nil {
-			logrus.WithError(err).Error("Failed to marshal websocket payload")
-			continue
-		}
-		userIdInt, err := strconv.Atoi(payload.UserId)
-		if err == nil {
-			SendToUser(userIdInt, data)
-		}
+		handleEvaluated(ctx, msg)
+	}
+}
+
+// Pings Redis over the subscription's own connection. A lost connection is dialled
+// and subscribed again on the way, so nil means the subscription is connected.
+func CheckEvaluationWorker(ctx context.Context) error {
+	sub := evaluatedSub.Load()
+	if sub == nil {
+		return ErrEvaluationWorkerStopped
+	}
+	return sub.Ping(ctx)
+}
+
+// Delivers one evaluated event to the user's websockets, under its own span
+func handleEvaluated(ctx context.Context, msg *redis.Message) {
+	_, span := tracing.Tracer().Start(ctx, "evaluated receive", trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(
+		attribute.String("messaging.system", "redis"),
+		attribute.String("messaging.destination.name", msg.Channel),
+	))
+	defer span.End()
+
+	var payload EvaluatedSubmission
+	err := json.Unmarshal([]byte(msg.Payload), &payload)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		metrics.EvaluatedEvent(false)
+		logrus.Error("failed to read evaluated job data:", err)
+		return
+	}
+	span.SetAttributes(
+		attribute.String("submission.id", payload.SubmissionId),
+		attribute.String("user.id", payload.UserId),
+		attribute.String("correlation_id", payload.CorrelationId),
+	)
+
+	logrus.WithFields(logrus.Fields{
+		"submission_id":  payload.SubmissionId,
+		"user_id":        payload.UserId,
+		"status":         payload.Status,
+		"correlation_id": payload.CorrelationId,
+		"type":           "submission_evaluated",
+	}).Info("Evaluated submission received")
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		metrics.EvaluatedEvent(false)
+		logrus.WithError(err).Error("Failed to marshal websocket payload")
+		return
+	}
+	delivered := 0
+	userIdInt, err := strconv.Atoi(payload.UserId)
+	if err == nil {
+		delivered = SendToUser(userIdInt, data)
 	}
+	span.SetAttributes(attribute.Int("websocket.delivered", delivered))
+	metrics.EvaluatedEvent(delivered > 0)
 }
@@ -8,6 +8,7 @@ import (
 
 type RoleService interface {
 	GetUserRoles(ctx context.Context, userId int64) ([]*models.Role, error)
+	GetUserPermissions(ctx context.Context, userId int64) ([]*models.Permission, error)
 	CreateRole(ctx context.Context, name string, description string) (*models.Role, error)
 	UpdateRole(ctx context.Context, id int, name string, description string) (*models.Role, error)
 	GetRoleById(ctx context.Context, id int) (*models.Role, error)
@@ -17,19 +18,24 @@ type RoleService interface {
 	GetAllRolePermissions(ctx context.Context) ([]*models.RolePermission, error)
 	AssignRole(ctx context.Context, userId int, roleId int) (bool, error)
 	RemoveRole(ctx context.Context, userRoleId int) (bool, error)
+	RemoveUserRole(ctx context.Context, userId int, roleId int) (bool, error)
+	DeleteRole(ctx context.Context, id int, reassignToId int) (bool, error)
+	GetRoleMembers(ctx context.Context, id int) ([]*models.RoleMember, error)
 }
 
 type RoleServiceImpl struct {
 	roleRepository           db.RoleRepository
 	rolePermissionRepository db.RolePermissionRepository
 	userRoleRepository       db.UserRoleRepository
+	auditService             AuditService
 }
 
-func NewRoleService(roleRepo db.RoleRepository, rolePermissionRepo db.RolePermissionRepository, userRoleRepo db.UserRoleRepository) RoleService {
+func NewRoleService(roleRepo db.RoleRepository, rolePermissionRepo db.RolePermissionRepository, userRoleRepo db.UserRoleRepository, auditService AuditService) RoleService {
 	return &RoleServiceImpl{
 		roleRepository:           roleRepo,
 		rolePermissionRepository: rolePermissionRepo,
 		userRoleRepository:       userRoleRepo,
+		auditService:             auditService,
 	}
 }
 
@@ -37,12 +43,30 @@ func (s *RoleServiceImpl) GetUserRoles(ctx context.Context, userId int64) ([]*mo
 	return s.userRoleRepository.GetUserRoles(ctx, userId)
 }
 
+func (s *RoleServiceImpl) GetUserPermissions(ctx context.Context, userId int64) ([]*models.Permission, error) {
+	return s.userRoleRepository.GetUserPermissions(ctx, userId)
+}
+
 func (s *RoleServiceImpl) CreateRole(ctx context.Context, name string, description string) (*models.Role, error) {
-	return s.roleRepository.CreateRole(ctx, name, description)
+	role, err := s.roleRepository.CreateRole(ctx, name, description)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, AuditRoleCreate, "role", role.Id, nil, role)
+	return role, nil
 }
 
 func (s *RoleServiceImpl) UpdateRole(ctx context.Context, id int, name string, description string) (*models.Role, error) {
-	return s.roleRepository.UpdateRoleById(ctx, id, name, description)
+	before, err := s.roleRepository.GetRoleById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.roleRepository.UpdateRoleById(ctx, id, name, description)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, AuditRoleUpdate, "role", id, before, role)
+	return role, nil
 }
 
 func (s *RoleServiceImpl) GetRoleById(ctx context.Context, id int) (*models.Role, error) {
@@ -66,9 +90,46 @@ func (s *RoleServiceImpl) GetAllRolePermissions(ctx context.Context) ([]*models.
 }
 
 func (s *RoleServiceImpl) AssignRole(ctx context.Context, userId int, roleId int) (bool, error) {
-	return s.userRoleRepository.AssignRole(ctx, userId, roleId)
+	assigned, err := s.userRoleRepository.AssignRole(ctx, userId, roleId)
+	if err != nil {
+		return false, err
+	}
+	s.auditService.Record(ctx, AuditUserRoleAssign, "user", userId, nil, map[string]any{"role_id": roleId})
+	return assigned, nil
 }
 
 func (s *RoleServiceImpl) RemoveRole(ctx context.Context, userRoleId int) (bool, error) {
-	return s.userRoleRepository.RemoveRole(ctx, userRoleId)
+	removed, err := s.userRoleRepository.RemoveRole(ctx, userRoleId)
+	if err != nil {
+		return false, err
+	}
+	s.auditService.Record(ctx, AuditUserRoleRemove, "user_role", userRoleId, map[string]any{"user_role_id": userRoleId}, nil)
+	return removed, nil
+}
+
+func (s *RoleServiceImpl) RemoveUserRole(ctx context.Context, userId int, roleId int) (bool, error) {
+	removed, err := s.userRoleRepository.RemoveUserRole(ctx, userId, roleId)
+	if err != nil {
+		return false, err
+	}
+	s.auditService.Record(ctx, AuditUserRoleRemove, "user", userId, map[string]any{"role_id": roleId}, nil)
+	return removed, nil
+}
+
+// Members of the deleted role are moved to reassignToId, which the entry keeps next to the removed role
+func (s *RoleServiceImpl) DeleteRole(ctx context.Context, id int, reassignToId int) (bool, error) {
+	before, err := s.roleRepository.GetRoleById(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := s.roleRepository.DeleteRoleById(ctx, id, reassignToId)
+	if err != nil {
+		return false, err
+	}
+	s.auditService.Record(ctx, AuditRoleDelete, "role", id, before, map[string]any{"reassigned_to": reassignToId})
+	return deleted, nil
+}
+
+func (s *RoleServiceImpl) GetRoleMembers(ctx context.Context, id int) ([]*models.RoleMember, error) {
+	return s.roleRepository.GetRoleMembers(ctx, id)
 }
@@ -0,0 +1,43 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/models"
+	"context"
+)
+
+type SessionService interface {
+	GetSessions(ctx context.Context, userId int64, currentTokenId string) ([]*models.UserSession, error)
+	RevokeSession(ctx context.Context, userId int64, sessionId int64) (bool, error)
+	RevokeOtherSessions(ctx context.Context, userId int64, currentTokenId string) (int64, error)
+}
+
+type SessionServiceImpl struct {
+	sessionRepository db.SessionRepository
+}
+
+func NewSessionService(sessionRepo db.SessionRepository) SessionService {
+	return &SessionServiceImpl{
+		sessionRepository: sessionRepo,
+	}
+}
+
+// Lists the user's active sessions, flagging the one making the request
+func (s *SessionServiceImpl) GetSessions(ctx context.Context, userId int64, currentTokenId string) ([]*models.UserSession, error) {
+	sessions, err := s.sessionRepository.GetActiveByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = currentTokenId != "" && session.TokenId == currentTokenId
+	}
+	return sessions, nil
+}
+
+func (s *SessionServiceImpl) RevokeSession(ctx context.Context, userId int64, sessionId int64) (bool, error) {
+	return s.sessionRepository.Revoke(ctx, userId, sessionId)
+}
+
+func (s *SessionServiceImpl) RevokeOtherSessions(ctx context.Context, userId int64, currentTokenId string) (int64, error) {
+	return s.sessionRepository.RevokeAllExcept(ctx, userId, currentTokenId)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"AuthService/metrics"
+	"AuthService/models"
+	"AuthService/tracing"
+	"AuthService/upstream"
+	"AuthService/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SubmissionStatsClient interface {
+	GetStats(ctx context.Context, userId int64) (*models.ProfileStats, error)
+}
+
+// Reads a user's submissions from a SubmissionService replica and sums them up
+type HTTPSubmissionStatsClient struct {
+	Pool   *upstream.Pool
+	Client *http.Client
+}
+
+func NewSubmissionStatsClient(pool *upstream.Pool) SubmissionStatsClient {
+	return &HTTPSubmissionStatsClient{
+		Pool:   pool,
+		Client: &http.Client{Timeout: 2 * time.Second, Transport: tracing.Transport(http.DefaultTransport)},
+	}
+}
+
+type submissionListResponse struct {
+	Data []struct {
+		ProblemId string `json:"problemId"`
+		Status    string `json:"status"`
+	} `json:"data"`
+	Success bool `json:"success"`
+}
+
+func (c *HTTPSubmissionStatsClient) GetStats(ctx context.Context, userId int64) (*models.ProfileStats, error) {
+	target, err := c.Pool.Pick()
+	if err != nil {
+		return nil, err
+	}
+	target.Begin()
+	started := time.Now()
+	failed := true
+	defer func() {
+		// A caller that went away says nothing about the target
+		if failed && errors.Is(ctx.Err(), context.Canceled) {
+			target.Abandon()
+			metrics.ObserveUpstreamCancelled(c.Pool.Name(), time.Since(started))
+			return
+		}
+		target.End(failed)
+		metrics.ObserveUpstream(c.Pool.Name(), failed, time.Since(started))
+	}()
+
+	endpoint := strings.TrimRight(target.URL.String(), "/") + "/submission/user/" + url.PathEscape(strconv.FormatInt(userId, 10))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	meta := utils.GetRequestMeta(ctx)
+	if meta.RequestId != "" {
+		req.Header.Set("X-Request-ID", meta.RequestId)
+	}
+	if meta.CorrelationId != "" {
+		req.Header.Set("X-Correlation-ID", meta.CorrelationId)
+	}
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	failed = res.StatusCode >= 500
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("submission service responded with %d", res.StatusCode)
+	}
+
+	var body submissionListResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	// Pending submissions count towards attempts but not towards the acceptance rate
+	stats := &models.ProfileStats{}
+	attempted := map[string]bool{}
+	solved := map[string]bool{}
+	judged, correct := 0, 0
+	for _, submission := range body.Data {
+		stats.Submissions++
+		attempted[submission.ProblemId] = true
+		if submission.Status == "pending" {
+			continue
+		}
+		judged++
+		if submission.Status == "correct_answer" {
+			correct++
+			solved[submission.ProblemId] = true
+		}
+	}
+	stats.Attempted = len(attempted)
+	stats.Solved = len(solved)
+	if judged > 0 {
+		stats.AcceptanceRate = math.Round(float64(correct)/float64(judged)*1000) / 10
+	}
+	return stats, nil
+}
@@ -2,10 +2,12 @@ package services
 
 import (
 	db "AuthService/db/repositories"
+	"AuthService/dto"
 	"AuthService/models"
 	"AuthService/utils"
 	"context"
 	"errors"
+	"time"
 )
 
 var (
@@ -16,19 +18,35 @@ type UserService interface {
 	GetById(ctx context.Context, id string) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	Create(ctx context.Context, username string, email string, password string) (*models.User, error)
-	GetAll(ctx context.Context) ([]*models.User, error)
+	ListUsers(ctx context.Context, filter models.UserListFilter, cursor string, limit int) (*models.CursorPage[*models.UserSummary], error)
 	DeleteById(ctx context.Context, id string) (bool, error)
-	LoginUser(ctx context.Context, email string, password string) (string, error)
-	ValidateUserSession(ctx context.Context, email string, id int) (string, error)
+	LoginUser(ctx context.Context, email string, password string, userAgent string, ipAddress string) (string, error)
+	StartSession(ctx context.Context, user *models.User, userAgent string, ipAddress string) (string, error)
+	LogoutUser(ctx context.Context, current dto.TokenClaimsDTO) error
+	ValidateUserSession(ctx context.Context, email string, id int, current dto.TokenClaimsDTO) (string, error)
+	SwitchOrganization(ctx context.Context, current dto.TokenClaimsDTO, orgId int64) (string, error)
+	AcceptInvite(ctx context.Context, token string, password string) error
+	ChangePassword(ctx context.Context, current dto.TokenClaimsDTO, currentPassword string, newPassword string) error
+	IssueImpersonationToken(ctx context.Context, user *models.User, impersonatorId int64, ttl time.Duration) (string, time.Time, error)
 }
 
 type UserServiceImpl struct {
-	UserRepository db.UserRepository
+	UserRepository         db.UserRepository
+	UserRoleRepository     db.UserRoleRepository
+	OrganizationRepository db.OrganizationRepository
+	SessionRepository      db.SessionRepository
+	LoginHistoryService    LoginHistoryService
+	AuditService           AuditService
 }
 
-func NewUserService(_userRepository db.UserRepository) UserService {
+func NewUserService(_userRepository db.UserRepository, _userRoleRepository db.UserRoleRepository, _organizationRepository db.OrganizationRepository, _sessionRepository db.SessionRepository, _loginHistoryService LoginHistoryService, _auditService AuditService) UserService {
 	return &UserServiceImpl{
-		UserRepository: _userRepository,
+		UserRepository:         _userRepository,
+		UserRoleRepository:     _userRoleRepository,
+		OrganizationRepository: _organizationRepository,
+		SessionRepository:      _sessionRepository,
+		LoginHistoryService:    _loginHistoryService,
+		AuditService:           _auditService,
 	}
 }
 
@@ -58,40 +76,212 @@ func (s *UserServiceImpl) Create(ctx context.Context, username string, email str
 	return user, err
 }
 
-func (s *UserServiceImpl) GetAll(ctx context.Context) ([]*models.User, error) {
-	users, err := s.UserRepository.GetAll(ctx)
+func (s *UserServiceImpl) DeleteById(ctx context.Context, id string) (bool, error) {
+	before, err := s.UserRepository.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	return users, nil
-}
-
-func (s *UserServiceImpl) DeleteById(ctx context.Context, id string) (bool, error) {
 	isDeleted, err := s.UserRepository.DeleteById(ctx, id)
-	return isDeleted, err
+	if err != nil {
+		return false, err
+	}
+	s.AuditService.Record(ctx, AuditUserDelete, "user", id, before, nil)
+	return isDeleted, nil
 }
 
-func (s *UserServiceImpl) LoginUser(ctx context.Context, email string, password string) (string, error) {
+// Checks the credentials and opens a new session for the device, the session id travels in the token
+func (s *UserServiceImpl) LoginUser(ctx context.Context, email string, password string, userAgent string, ipAddress string) (string, error) {
 	user, err := s.UserRepository.GetByEmail(ctx, email)
 	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			s.LoginHistoryService.RecordAttempt(ctx, 0, email, LoginOutcomeUnknownUser, userAgent, ipAddress)
+		}
 		return "", err
 	}
 
 	isPasswordMatched := utils.CheckPassword(user.Password, password)
 	if !isPasswordMatched {
+		s.LoginHistoryService.RecordAttempt(ctx, user.Id, user.Email, LoginOutcomeInvalidPassword, userAgent, ipAddress)
 		return "", ErrInvalidCredentials
 	}
 
-	token, err := utils.CreateJwtToken(int(user.Id), user.Email)
+	return s.StartSession(ctx, user, userAgent, ipAddress)
+}
+
+// Opens a session for a user whose identity was already proven, by password or by a login link
+func (s *UserServiceImpl) StartSession(ctx context.Context, user *models.User, userAgent string, ipAddress string) (string, error) {
+	claims, err := s.buildClaims(ctx, int(user.Id), user.Email, 0)
+	if err != nil {
+		return "", err
+	}
+
+	claims.SessionId, err = utils.RandomToken(16)
 	if err != nil {
 		return "", db.ErrInternalServerError
 	}
+	if err := s.SessionRepository.Create(ctx, user.Id, claims.SessionId, utils.DeviceLabel(userAgent), userAgent, ipAddress); err != nil {
+		return "", err
+	}
+	s.LoginHistoryService.RecordAttempt(ctx, user.Id, user.Email, LoginOutcomeSuccess, userAgent, ipAddress)
 
-	return token, nil
+	return signClaims(claims)
+}
+
+// Revokes the session behind the token, impersonation tokens have no session and simply expire
+func (s *UserServiceImpl) LogoutUser(ctx context.Context, current dto.TokenClaimsDTO) error {
+	if current.SessionId == "" {
+		return nil
+	}
+	return s.SessionRepository.RevokeByTokenId(ctx, current.SessionId)
+}
+
+// Re-issues the token, keeping the active organization only while the user is still a member of it.
+// Impersonation tokens keep their impersonator and expiry, so refreshing never extends them.
+func (s *UserServiceImpl) ValidateUserSession(ctx context.Context, email string, id int, current dto.TokenClaimsDTO) (string, error) {
+	claims, err := s.buildClaims(ctx, id, email, current.OrgId)
+	if errors.Is(err, db.ErrNotOrgMember) {
+		claims, err = s.buildClaims(ctx, id, email, 0)
+	}
+	if err != nil {
+		return "", err
+	}
+	claims.SessionId = current.SessionId
+	claims.ImpersonatorId = current.ImpersonatorId
+	claims.ExpiresAt = current.ExpiresAt
+
+	return signClaims(claims)
+}
+
+// Issues a token scoped to the given organization within the same session, an orgId of 0 clears the active organization
+func (s *UserServiceImpl) SwitchOrganization(ctx context.Context, current dto.TokenClaimsDTO, orgId int64) (string, error) {
+	claims, err := s.buildClaims(ctx, current.UserId, current.Email, orgId)
+	if err != nil {
+		return "", err
+	}
+	claims.SessionId = current.SessionId
+
+	return signClaims(claims)
+}
+
+// Sets the password of an invited user and marks their email as verified
+func (s *UserServiceImpl) AcceptInvite(ctx context.Context, token string, password string) error {
+	userId, err := utils.ParseInviteToken(token, func(userId int64) (string, error) {
+		return s.UserRepository.GetPasswordHashById(ctx, userId)
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return utils.ErrInvalidInviteToken
+		}
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return db.ErrInternalServerError
+	}
+	if _, err := s.UserRepository.UpdatePassword(ctx, userId, hashedPassword); err != nil {
+		return err
+	}
+	if _, err := s.UserRepository.MarkVerified(ctx, userId); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Changes the password and signs out every other session of the user
+func (s *UserServiceImpl) ChangePassword(ctx context.Context, current dto.TokenClaimsDTO, currentPassword string, newPassword string) error {
+	id := int64(current.UserId)
+	hashedPassword, err := s.UserRepository.GetPasswordHashById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPassword(hashedPassword, currentPassword) {
+		return ErrInvalidCredentials
+	}
+
+	newHashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return db.ErrInternalServerError
+	}
+	if _, err := s.UserRepository.UpdatePassword(ctx, id, newHashedPassword); err != nil {
+		return err
+	}
+
+	_, err = s.SessionRepository.RevokeAllExcept(ctx, id, current.SessionId)
+	return err
+}
+
+// Issues a short-lived token for the user that also names the admin acting as them
+func (s *UserServiceImpl) IssueImpersonationToken(ctx context.Context, user *models.User, impersonatorId int64, ttl time.Duration) (string, time.Time, error) {
+	claims, err := s.buildClaims(ctx, int(user.Id), user.Email, 0)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	claims.ImpersonatorId = int(impersonatorId)
+	claims.ExpiresAt = expiresAt.Unix()
+
+	token, err := signClaims(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// Builds the claims carrying the user's current roles, permissions, authz version and active organization
+func (s *UserServiceImpl) buildClaims(ctx context.Context, id int, email string, orgId int64) (dto.TokenClaimsDTO, error) {
+	// Read the version first so a concurrent role change can only make the token look stale
+	version, err := s.UserRoleRepository.GetAuthzVersion(ctx, id)
+	if err != nil {
+		return dto.TokenClaimsDTO{}, err
+	}
+
+	roles, err := s.UserRoleRepository.GetUserRoles(ctx, int64(id))
+	if err != nil {
+		return dto.TokenClaimsDTO{}, err
+	}
+
+	permissions, err := s.UserRoleRepository.GetUserPermissions(ctx, int64(id))
+	if err != nil {
+		return dto.TokenClaimsDTO{}, err
+	}
+
+	claims := dto.TokenClaimsDTO{
+		UserId:       id,
+		Email:        email,
+		Roles:        []string{},
+		Permissions:  []string{},
+		AuthzVersion: version,
+	}
+	for _, role := range roles {
+		claims.Roles = append(claims.Roles, role.Name)
+	}
+	seen := map[string]bool{}
+	for _, permission := range permissions {
+		// A permission granted through several roles is listed once
+		if seen[permission.Name] {
+			continue
+		}
+		seen[permission.Name] = true
+		claims.Permissions = append(claims.Permissions, permission.Name)
+	}
+
+	if orgId != 0 {
+		orgRole, err := s.OrganizationRepository.GetMembershipRole(ctx, orgId, int64(id))
+		if err != nil {
+			return dto.TokenClaimsDTO{}, err
+		}
+		claims.OrgId = orgId
+		claims.OrgRole = orgRole
+	}
+
+	return claims, nil
 }
 
-func (s *UserServiceImpl) ValidateUserSession(ctx context.Context, email string, id int) (string, error) {
-	token, err := utils.CreateJwtToken(id, email)
+func signClaims(claims dto.TokenClaimsDTO) (string, error) {
+	token, err := utils.CreateJwtToken(claims)
 	if err != nil {
 		return "", db.ErrInternalServerError
 	}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	config "AuthService/config/env"
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/models"
+	"AuthService/utils"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnsupportedImportFormat = errors.New("unsupported import format, use text/csv or application/x-ndjson")
+	ErrInvalidImportFile       = errors.New("invalid import file")
+	ErrImportJobNotFound       = errors.New("import job not found")
+)
+
+const (
+	importBatchSize    = 50
+	importJobRetention = 24 * time.Hour
+	inviteTokenTTL     = 7 * 24 * time.Hour
+)
+
+type UserImportService interface {
+	ParseRows(contentType string, body io.Reader) ([]dto.UserImportRowDTO, error)
+	Import(ctx context.Context, rows []dto.UserImportRowDTO) *models.UserImportJob
+	StartImportJob(ctx context.Context, rows []dto.UserImportRowDTO) (*models.UserImportJob, error)
+	GetImportJob(ctx context.Context, id string) (*models.UserImportJob, error)
+}
+
+type UserImportServiceImpl struct {
+	userImportRepository db.UserImportRepository
+	roleRepository       db.RoleRepository
+	mailer               Mailer
+	auditService         AuditService
+}
+
+func NewUserImportService(userImportRepo db.UserImportRepository, roleRepo db.RoleRepository, mailer Mailer, auditService AuditService) UserImportService {
+	return &UserImportServiceImpl{
+		userImportRepository: userImportRepo,
+		roleRepository:       roleRepo,
+		mailer:               mailer,
+		auditService:         auditService,
+	}
+}
+
+// Reads a CSV file with an email,username,roles header, or one JSON object per line.
+// Multiple roles in a CSV cell are separated by ';'.
+func (s *UserImportServiceImpl) ParseRows(contentType string, body io.Reader) ([]dto.UserImportRowDTO, error) {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	switch mediaType {
+	case "text/csv":
+		return parseCsvRows(body)
+	case "application/x-ndjson", "application/jsonl":
+		return parseJsonlRows(body)
+	}
+	return nil, ErrUnsupportedImportFormat
+}
+
+func parseCsvRows(body io.Reader) ([]dto.UserImportRowDTO, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidImportFile)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	emailCol, okEmail := columns["email"]
+	usernameCol, okUsername := columns["username"]
+	rolesCol, okRoles := columns["roles"]
+	if !okEmail || !okUsername {
+		return nil, fmt.Errorf("%w: header must contain email and username", ErrInvalidImportFile)
+	}
+
+	rows := []dto.UserImportRowDTO{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err)
+		}
+
+		row := dto.UserImportRowDTO{
+			Email:    strings.TrimSpace(record[emailCol]),
+			Username: strings.TrimSpace(record[usernameCol]),
+			Roles:    []string{},
+		}
+		if okRoles {
+			for _, role := range strings.Split(record[rolesCol], ";") {
+				if role = strings.TrimSpace(role); role != "" {
+					row.Roles = append(row.Roles, role)
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseJsonlRows(body io.Reader) ([]dto.UserImportRowDTO, error) {
+	scanner := bufio.NewScanner(body)
+	rows := []dto.UserImportRowDTO{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var row dto.UserImportRowDTO
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidImportFile, line, err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, err)
+	}
+
+	return rows, nil
+}
+
+// Imports the rows right away and returns the finished job with its per-row report.
+// The invites are queued, mailing them inline would hold the response past the server's write timeout.
+func (s *UserImportServiceImpl) Import(ctx context.Context, rows []dto.UserImportRowDTO) *models.UserImportJob {
+	job := newImportJob(len(rows))
+	s.run(ctx, job, rows, false)
+	return job
+}
+
+// Stores the job and imports the rows on a background goroutine, progress is polled with GetImportJob.
+// The job lives in the database, so any replica can answer and finished jobs are kept for importJobRetention.
+// The goroutine outlives the request but keeps its values, so the audit entry still names the admin.
+func (s *UserImportServiceImpl) StartImportJob(ctx context.Context, rows []dto.UserImportRowDTO) (*models.UserImportJob, error) {
+	if err := s.userImportRepository.DeleteJobsFinishedBefore(ctx, importJobRetention); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "user_import",
+		}).Warn("Old import jobs could not be deleted")
+	}
+
+	job := newImportJob(len(rows))
+	if err := s.userImportRepository.CreateJob(ctx, job.Id, job.Total); err != nil {
+		return nil, err
+	}
+	stored, err := s.userImportRepository.GetJob(ctx, job.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.run(context.WithoutCancel(ctx), job, rows, true)
+
+	return stored, nil
+}
+
+func (s *UserImportServiceImpl) GetImportJob(ctx context.Context, id string) (*models.UserImportJob, error) {
+	job, err := s.userImportRepository.GetJob(ctx, id)
+	if errors.Is(err, db.ErrUserImportJobNotFound) {
+		return nil, ErrImportJobNotFound
+	}
+	return job, err
+}
+
+// Imports the rows into job. A stored job has every step written through, so polls see its progress,
+// synchronous imports mail their invites from a queue instead of inline.
+func (s *UserImportServiceImpl) run(ctx context.Context, job *models.UserImportJob, rows []dto.UserImportRowDTO, stored bool) {
+	queueInvites := !stored
+	s.setStatus(ctx, job, "running", stored)
+
+	roleIds, err := s.roleIdsByName(ctx)
+	if err != nil {
+		s.setStatus(ctx, job, "failed", stored)
+		return
+	}
+
+	seenEmails := map[string]bool{}
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := min(start+importBatchSize, len(rows))
+
+		var entries []*models.UserImportEntry
+		var results []*models.UserImportResult
+		for i, row := range rows[start:end] {
+			// Rows are reported 1-based, as they appear in the file
+			entry, result := prepareImportEntry(start+i+1, row, roleIds, seenEmails)
+			if result != nil {
+				results = append(results, result)
+				continue
+			}
+			entries = append(entries, entry)
+		}
+
+		if len(entries) > 0 {
+			created, err := s.userImportRepository.ImportBatch(ctx, entries)
+			if err != nil {
+				for _, entry := range entries {
+					results = append(results, failedImportResult(entry.Row, entry.Email, entry.Username, err))
+				}
+			} else {
+				s.sendInvites(ctx, entries, created, queueInvites)
+				results = append(results, created...)
+			}
+		}
+
+		sort.Slice(results, func(i, j int) bool { return results[i].Row < results[j].Row })
+
+		for _, result := range results {
+			job.Results = append(job.Results, result)
+			if result.Status == "created" {
+				job.Created++
+			} else {
+				job.Failed++
+			}
+		}
+		job.Processed = end
+		if stored {
+			// The users exist either way, a lost progress update only shows up in the report
+			if err := s.userImportRepository.AddJobResults(ctx, job.Id, end, results); err != nil {
+				logImportJobError(job, err)
+			}
+		}
+	}
+
+	s.setStatus(ctx, job, "completed", stored)
+
+	logrus.WithFields(logrus.Fields{
+		"job_id":  job.Id,
+		"created": job.Created,
+		"failed":  job.Failed,
+		"type":    "user_import",
+	}).Info("User import finished")
+
+	userIds := []int64{}
+	for _, result := range job.Results {
+		if result.Status == "created" {
+			userIds = append(userIds, result.UserId)
+		}
+	}
+	s.auditService.Record(ctx, AuditUserImport, "import_job", job.Id, nil, map[string]any{
+		"total":    job.Total,
+		"created":  job.Created,
+		"failed":   job.Failed,
+		"user_ids": userIds,
+	})
+}
+
+// Validates a row and hashes a random password for it, returning a failed result when the row is rejected
+func prepareImportEntry(rowNumber int, row dto.UserImportRowDTO, roleIds map[string]int, seenEmails map[string]bool) (*models.UserImportEntry, *models.UserImportResult) {
+	row.Email = strings.ToLower(strings.TrimSpace(row.Email))
+	if err := utils.Validator.Struct(row); err != nil {
+		return nil, failedImportResult(rowNumber, row.Email, row.Username, err)
+	}
+	if seenEmails[row.Email] {
+		return nil, failedImportResult(rowNumber, row.Email, row.Username, errors.New("duplicate email in file"))
+	}
+	seenEmails[row.Email] = true
+
+	roles := row.Roles
+	if len(roles) == 0 {
+		roles = []string{"user"}
+	}
+	entry := &models.UserImportEntry{
+		Row:      rowNumber,
+		Email:    row.Email,
+		Username: row.Username,
+	}
+	for _, role := range roles {
+		roleId, ok := roleIds[strings.ToLower(role)]
+		if !ok {
+			return nil, failedImportResult(rowNumber, row.Email, row.Username, fmt.Errorf("unknown role %q", role))
+		}
+		entry.RoleIds = append(entry.RoleIds, roleId)
+	}
+
+	// Imported users choose their own password through the invite link
+	password, err := utils.RandomToken(24)
+	if err != nil {
+		return nil, failedImportResult(rowNumber, row.Email, row.Username, db.ErrInternalServerError)
+	}
+	entry.HashedPassword, err = utils.HashPassword(password)
+	if err != nil {
+		return nil, failedImportResult(rowNumber, row.Email, row.Username, db.ErrInternalServerError)
+	}
+
+	return entry, nil
+}
+
+// Mails an invite to every created user. Queued invites go out on a goroutine that outlives the request.
+func (s *UserImportServiceImpl) sendInvites(ctx context.Context, entries []*models.UserImportEntry, results []*models.UserImportResult, queue bool) {
+	appUrl := config.GetString("APP_URL", "http://localhost:3005")
+
+	var queued []MailMessage
+	for i, result := range results {
+		if result.Status != "created" {
+			continue
+		}
+		token, err := utils.CreateInviteToken(result.UserId, entries[i].HashedPassword, inviteTokenTTL)
+		if err != nil {
+			continue
+		}
+
+		message := MailMessage{
+			To:      result.Email,
+			Subject: "You have been invited to Problem Battles",
+			Body: fmt.Sprintf("Hi %s,\n\nAn account has been created for you on Problem Battles.\nSet your password and verify your email within 7 days:\n\n%s/invite?token=%s\n",
+				result.Username, appUrl, token),
+		}
+		if queue {
+			queued = append(queued, message)
+			result.InviteQueued = true
+			continue
+		}
+		if s.sendInvite(ctx, message) {
+			result.InviteSent = true
+		}
+	}
+
+	if len(queued) > 0 {
+		go func(ctx context.Context) {
+			for _, message := range queued {
+				s.sendInvite(ctx, message)
+			}
+		}(context.WithoutCancel(ctx))
+	}
+}
+
+func (s *UserImportServiceImpl) sendInvite(ctx context.Context, message MailMessage) bool {
+	if err := s.mailer.Send(ctx, message); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"type": "mail_error",
+		}).Error("Invite mail failed")
+		return false
+	}
+	return true
+}
+
+func (s *UserImportServiceImpl) roleIdsByName(ctx context.Context) (map[string]int, error) {
+	roles, err := s.roleRepository.GetAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roleIds := make(map[string]int, len(roles))
+	for _, role := range roles {
+		roleIds[strings.ToLower(role.Name)] = role.Id
+	}
+	return roleIds, nil
+}
+
+func (s *UserImportServiceImpl) setStatus(ctx context.Context, job *models.UserImportJob, status string, stored bool) {
+	job.Status = status
+	if status == "completed" || status == "failed" {
+		job.FinishedAt = time.Now().Format(time.RFC3339)
+	}
+	if !stored {
+		return
+	}
+	if err := s.userImportRepository.SetJobStatus(ctx, job.Id, status); err != nil {
+		logImportJobError(job, err)
+	}
+}
+
+func logImportJobError(job *models.UserImportJob, err error) {
+	logrus.WithFields(logrus.Fields{
+		"err":    err,
+		"job_id": job.Id,
+		"type":   "user_import",
+	}).Error("Import job could not be updated")
+}
+
+func newImportJob(total int) *models.UserImportJob {
+	id, _ := utils.RandomToken(8)
+	return &models.UserImportJob{
+		Id:        id,
+		Status:    "queued",
+		Total:     total,
+		Results:   []*models.UserImportResult{},
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+}
+
+func failedImportResult(row int, email string, username string, err error) *models.UserImportResult {
+	return &models.UserImportResult{
+		Row:      row,
+		Email:    email,
+		Username: username,
+		Status:   "failed",
+		Error:    err.Error(),
+	}
+}
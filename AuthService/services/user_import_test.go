@@ -0,0 +1,157 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/dto"
+	"AuthService/models"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Keeps jobs the way the user_import_jobs table does, shared by every service built on it
+type fakeImportStore struct {
+	db.UserImportRepository
+	mu     sync.Mutex
+	jobs   map[string]*models.UserImportJob
+	nextId int64
+}
+
+func newFakeImportStore() *fakeImportStore {
+	return &fakeImportStore{jobs: map[string]*models.UserImportJob{}}
+}
+
+func (f *fakeImportStore) ImportBatch(ctx context.Context, entries []*models.UserImportEntry) ([]*models.UserImportResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	results := make([]*models.UserImportResult, 0, len(entries))
+	for _, entry := range entries {
+		f.nextId++
+		results = append(results, &models.UserImportResult{Row: entry.Row, Email: entry.Email, Username: entry.Username, Status: "created", UserId: f.nextId})
+	}
+	return results, nil
+}
+
+func (f *fakeImportStore) CreateJob(ctx context.Context, id string, total int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.jobs[id] = &models.UserImportJob{Id: id, Status: "queued", Total: total, Results: []*models.UserImportResult{}, CreatedAt: "2026-10-19 12:00:00"}
+	return nil
+}
+
+func (f *fakeImportStore) SetJobStatus(ctx context.Context, id string, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.jobs[id].Status = status
+	if status == "completed" || status == "failed" {
+		f.jobs[id].FinishedAt = "2026-10-19 12:01:00"
+	}
+	return nil
+}
+
+func (f *fakeImportStore) AddJobResults(ctx context.Context, id string, processed int, results []*models.UserImportResult) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job := f.jobs[id]
+	job.Processed = processed
+	for _, result := range results {
+		job.Results = append(job.Results, result)
+		if result.Status == "created" {
+			job.Created++
+		} else {
+			job.Failed++
+		}
+	}
+	return nil
+}
+
+func (f *fakeImportStore) GetJob(ctx context.Context, id string) (*models.UserImportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job, ok := f.jobs[id]
+	if !ok {
+		return nil, db.ErrUserImportJobNotFound
+	}
+	copied := *job
+	copied.Results = append([]*models.UserImportResult{}, job.Results...)
+	return &copied, nil
+}
+
+func (f *fakeImportStore) DeleteJobsFinishedBefore(ctx context.Context, age time.Duration) error {
+	return nil
+}
+
+type fakeImportRoles struct {
+	db.RoleRepository
+}
+
+func (f *fakeImportRoles) GetAllRoles(ctx context.Context) ([]*models.Role, error) {
+	return []*models.Role{{Id: 1, Name: "user"}, {Id: 2, Name: "admin"}}, nil
+}
+
+// Invites of background jobs go out inline, nothing waits for them here
+type discardMailer struct{}
+
+func (discardMailer) Send(ctx context.Context, message MailMessage) error {
+	return nil
+}
+
+func TestImportJobIsPolledFromTheStore(t *testing.T) {
+	t.Setenv("SECRET_KEY", "import-test-secret")
+	store := newFakeImportStore()
+	rows := []dto.UserImportRowDTO{}
+	for i := range 3 {
+		rows = append(rows, dto.UserImportRowDTO{Email: fmt.Sprintf("user%d@example.com", i), Username: fmt.Sprintf("user%d", i)})
+	}
+	// Reported as failed, the rest of its batch is still imported
+	rows = append(rows, dto.UserImportRowDTO{Email: "user0@example.com", Username: "again"})
+
+	started := NewUserImportService(store, &fakeImportRoles{}, discardMailer{}, &fakeAudit{})
+	job, err := started.StartImportJob(context.Background(), rows)
+	if err != nil {
+		t.Fatalf("StartImportJob() error = %v", err)
+	}
+	if job.Total != len(rows) {
+		t.Errorf("total = %d, want %d", job.Total, len(rows))
+	}
+
+	// Another replica, or this one after a restart, shares nothing with the one that started the job
+	polling := NewUserImportService(store, &fakeImportRoles{}, discardMailer{}, &fakeAudit{})
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		job, err = polling.GetImportJob(context.Background(), job.Id)
+		if err != nil {
+			t.Fatalf("GetImportJob() error = %v", err)
+		}
+		if job.Status == "completed" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if job.Status != "completed" || job.FinishedAt == "" {
+		t.Fatalf("job = %s finished at %q, want completed", job.Status, job.FinishedAt)
+	}
+	if job.Processed != len(rows) || job.Created != len(rows)-1 || job.Failed != 1 || len(job.Results) != len(rows) {
+		t.Errorf("job processed %d, created %d, failed %d with %d results, want %d, %d, 1 and %d",
+			job.Processed, job.Created, job.Failed, len(job.Results), len(rows), len(rows)-1, len(rows))
+	}
+	if last := job.Results[len(job.Results)-1]; last.Row != len(rows) || last.Status != "failed" {
+		t.Errorf("last result = %+v, want row %d failed", last, len(rows))
+	}
+}
+
+func TestGetImportJobNotFound(t *testing.T) {
+	service := NewUserImportService(newFakeImportStore(), &fakeImportRoles{}, discardMailer{}, &fakeAudit{})
+	if _, err := service.GetImportJob(context.Background(), "missing"); !errors.Is(err, ErrImportJobNotFound) {
+		t.Errorf("GetImportJob() error = %v, want %v", err, ErrImportJobNotFound)
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	db "AuthService/db/repositories"
+	"AuthService/models"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrInvalidUserListCursor = errors.New("invalid cursor, it must come from next_cursor of the same query")
+	ErrInvalidUserListSort   = errors.New("sort must be one of id, created_at, username or email, prefixed with - for descending order")
+)
+
+// Reads a page of users after the cursor, an empty cursor starts at the first page.
+// One extra row is read to tell whether another page follows.
+func (s *UserServiceImpl) ListUsers(ctx context.Context, filter models.UserListFilter, cursor string, limit int) (*models.CursorPage[*models.UserSummary], error) {
+	if !db.IsUserListSortColumn(filter.SortBy) {
+		return nil, ErrInvalidUserListSort
+	}
+
+	var after *models.UserListCursor
+	if cursor != "" {
+		decoded, err := decodeUserListCursor(cursor)
+		if err != nil || decoded.SortBy != userListSortKey(filter) {
+			return nil, ErrInvalidUserListCursor
+		}
+		after = decoded
+	}
+
+	users, err := s.UserRepository.List(ctx, filter, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.CursorPage[*models.UserSummary]{
+		Items: users,
+		Limit: limit,
+	}
+	if len(users) > limit {
+		page.Items = users[:limit]
+		page.HasMore = true
+		page.NextCursor = encodeUserListCursor(filter, page.Items[limit-1])
+	}
+	return page, nil
+}
+
+// The cursor remembers its order, so it cannot be replayed against a different sort
+func userListSortKey(filter models.UserListFilter) string {
+	if filter.Descending {
+		return "-" + filter.SortBy
+	}
+	return filter.SortBy
+}
+
+func encodeUserListCursor(filter models.UserListFilter, last *models.UserSummary) string {
+	cursor := models.UserListCursor{
+		SortBy: userListSortKey(filter),
+		Id:     last.Id,
+	}
+	switch filter.SortBy {
+	case "created_at":
+		cursor.SortValue = last.CreatedAt
+	case "username":
+		cursor.SortValue = last.Username
+	case "email":
+		cursor.SortValue = last.Email
+	default:
+		cursor.SortValue = strconv.FormatInt(last.Id, 10)
+	}
+
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeUserListCursor(value string) (*models.UserListCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	cursor := &models.UserListCursor{}
+	if err := json.Unmarshal(decoded, cursor); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
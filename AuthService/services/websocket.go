@@ -1,24 +1,38 @@
 package services
 
 import (
+	"AuthService/metrics"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// How long a write may take before the connection is given up as dead
+const websocketWriteWait = 5 * time.Second
+
+// An open connection and the session it was opened from. A connection allows only one
+// concurrent writer, writeMu serializes the sends to it.
+type websocketConnection struct {
+	sessionId string
+	writeMu   sync.Mutex
+}
+
 var (
-	userConnections = make(map[int]map[*websocket.Conn]bool)
+	// Open connections of each user
+	userConnections = make(map[int]map[*websocket.Conn]*websocketConnection)
 	userConnMu      sync.RWMutex
 )
 
-func RegisterConnection(userId int, conn *websocket.Conn) {
+func RegisterConnection(userId int, sessionId string, conn *websocket.Conn) {
 	userConnMu.Lock()
 	defer userConnMu.Unlock()
 
 	if _, exists := userConnections[userId]; !exists {
-		userConnections[userId] = make(map[*websocket.Conn]bool)
+		userConnections[userId] = make(map[*websocket.Conn]*websocketConnection)
 	}
-	userConnections[userId][conn] = true
+	userConnections[userId][conn] = &websocketConnection{sessionId: sessionId}
+	metrics.WebsocketConnections.Inc()
 }
 
 func UnregisterConnection(userId int, conn *websocket.Conn) {
@@ -26,7 +40,11 @@ func UnregisterConnection(userId int, conn *websocket.Conn) {
 	defer userConnMu.Unlock()
 
 	if conns, exists := userConnections[userId]; exists {
-		delete(conns, conn)
+		// A connection found dead while sending is already gone
+		if _, open := conns[conn]; open {
+			delete(conns, conn)
+			metrics.WebsocketConnections.Dec()
+		}
 		if len(conns) == 0 {
 			delete(userConnections, userId)
 		}
@@ -35,20 +53,36 @@ func UnregisterConnection(userId int, conn *websocket.Conn) {
 	conn.Close()
 }
 
-func SendToUser(userId int, message []byte) {
-	userConnMu.RLock()
-	defer userConnMu.RUnlock()
+// Returns how many connections got the message
+func SendToUser(userId int, message []byte) int {
+	return SendToUserExcept(userId, "", message)
+}
 
-	conns, exists := userConnections[userId]
-	if !exists {
-		return
+// Sends to every connection of the user except those of one session, an empty session id skips none.
+// The connections are picked under the lock and written to after releasing it, so a slow client only
+// holds up its own sends. One that cannot take the message within websocketWriteWait is dropped.
+func SendToUserExcept(userId int, sessionId string, message []byte) int {
+	userConnMu.RLock()
+	targets := make(map[*websocket.Conn]*websocketConnection, len(userConnections[userId]))
+	for conn, connection := range userConnections[userId] {
+		if sessionId != "" && connection.sessionId == sessionId {
+			continue
+		}
+		targets[conn] = connection
 	}
+	userConnMu.RUnlock()
 
-	for conn := range conns {
+	sent := 0
+	for conn, connection := range targets {
+		connection.writeMu.Lock()
+		conn.SetWriteDeadline(time.Now().Add(websocketWriteWait))
 		err := conn.WriteMessage(websocket.TextMessage, message)
+		connection.writeMu.Unlock()
 		if err != nil {
-			conn.Close()
-			delete(conns, conn)
+			UnregisterConnection(userId, conn)
+			continue
 		}
+		sent++
 	}
+	return sent
 }
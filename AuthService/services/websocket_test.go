@@ -0,0 +1,80 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Opens a client connection and returns it with the server side of it
+func dialWebsocket(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+	serverConns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		serverConns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, <-serverConns
+}
+
+func TestSendToUserExcept(t *testing.T) {
+	const userId = 41
+	first, firstServer := dialWebsocket(t)
+	second, secondServer := dialWebsocket(t)
+	RegisterConnection(userId, "session-a", firstServer)
+	RegisterConnection(userId, "session-b", secondServer)
+	t.Cleanup(func() {
+		UnregisterConnection(userId, firstServer)
+		UnregisterConnection(userId, secondServer)
+	})
+
+	if sent := SendToUserExcept(userId, "session-a", []byte("hello")); sent != 1 {
+		t.Fatalf("SendToUserExcept() = %d, want 1", sent)
+	}
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, message, err := second.ReadMessage(); err != nil || string(message) != "hello" {
+		t.Fatalf("ReadMessage() = %q, %v", message, err)
+	}
+	first.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, message, err := first.ReadMessage(); err == nil {
+		t.Fatalf("the skipped session got %q", message)
+	}
+
+	if sent := SendToUser(userId, []byte("all")); sent != 2 {
+		t.Errorf("SendToUser() = %d, want 2", sent)
+	}
+}
+
+func TestSendToUserDropsDeadConnections(t *testing.T) {
+	const userId = 42
+	_, server := dialWebsocket(t)
+	RegisterConnection(userId, "session-a", server)
+	t.Cleanup(func() { UnregisterConnection(userId, server) })
+	server.Close()
+
+	if sent := SendToUser(userId, []byte("hello")); sent != 0 {
+		t.Errorf("SendToUser() = %d, want 0", sent)
+	}
+	userConnMu.RLock()
+	_, open := userConnections[userId]
+	userConnMu.RUnlock()
+	if open {
+		t.Error("the dead connection is still registered")
+	}
+}
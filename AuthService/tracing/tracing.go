@@ -0,0 +1,142 @@
+package tracing
+
+import (
+	config "AuthService/config/env"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+
+	instrumentationName = "AuthService"
+)
+
+var (
+	ErrUnknownExporter = errors.New("unknown tracing exporter")
+)
+
+// Tracer for spans the gateway starts itself, the instrumented libraries bring their own
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Installs the global tracer provider and W3C trace context propagation, picked by TRACING_EXPORTER:
+// otlp sends to OTEL_EXPORTER_OTLP_ENDPOINT, stdout and file write JSON spans for local use without
+// a collector, none keeps only propagation. TRACING_SAMPLE_RATIO samples new traces, a caller's
+// sampling decision is kept. The returned function flushes what is buffered.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporterName := config.GetString("TRACING_EXPORTER", ExporterNone)
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	switch exporterName {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		otlp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("creating otlp exporter: %w", err)
+		}
+		exporter = otlp
+	case ExporterStdout:
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		exporter = stdout
+	case ExporterFile:
+		file, err := os.OpenFile(config.GetString("TRACING_FILE", "traces.json"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("opening trace file: %w", err)
+		}
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		exporter, closer = stdout, file
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownExporter, exporterName)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(config.GetString("OTEL_SERVICE_NAME", "auth-gateway")),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	ratio, err := strconv.ParseFloat(config.GetString("TRACING_SAMPLE_RATIO", "1"), 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("TRACING_SAMPLE_RATIO must be a number between 0 and 1")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// Starts a server span per request, continuing the caller's trace when it sent a traceparent.
+// Spans are named after chi's route pattern once the router has matched one.
+func Middleware(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if routeContext := chi.RouteContext(r.Context()); routeContext != nil {
+			if route := routeContext.RoutePattern(); route != "" {
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(r.Method + " " + route)
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+		}
+	})
+	return otelhttp.NewHandler(named, "gateway", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method
+	}))
+}
+
+// Client spans for outgoing requests, with the trace context injected into their headers
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
+
+// The id of the trace ctx belongs to, empty when it is not traced
+func TraceId(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
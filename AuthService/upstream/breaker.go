@@ -0,0 +1,100 @@
+package upstream
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+var (
+	ErrCircuitOpen = errors.New("upstream circuit is open")
+)
+
+// State of a pool's circuit breaker, guarded by the pool's lock
+type circuitBreaker struct {
+	config CircuitBreakerConfig
+
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+	probes              int
+	probeSuccesses      int
+}
+
+func newCircuitBreaker(config CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{config: config, state: CircuitClosed}
+}
+
+// Whether a request may go out now. Once the open time is over, only a limited number of probes may.
+func (b *circuitBreaker) allow(now time.Time) error {
+	switch b.state {
+	case CircuitOpen:
+		if now.Before(b.openedAt.Add(time.Duration(b.config.OpenTime))) {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.probes = 0
+		b.probeSuccesses = 0
+		fallthrough
+	case CircuitHalfOpen:
+		if b.probes >= b.config.HalfOpenRequests {
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+func (b *circuitBreaker) record(failed bool, now time.Time) {
+	if b.config.FailureThreshold < 0 {
+		return
+	}
+
+	switch b.state {
+	case CircuitHalfOpen:
+		if failed {
+			b.open(now)
+			return
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.config.HalfOpenRequests {
+			b.state = CircuitClosed
+			b.consecutiveFailures = 0
+		}
+	case CircuitClosed:
+		if !failed {
+			b.consecutiveFailures = 0
+			return
+		}
+		b.consecutiveFailures++
+		if b.consecutiveFailures >= b.config.FailureThreshold {
+			b.open(now)
+		}
+	}
+}
+
+// A probe that ended without an answer, the client went away, so another one may go out in its place
+func (b *circuitBreaker) abandon() {
+	if b.state == CircuitHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *circuitBreaker) open(now time.Time) {
+	b.state = CircuitOpen
+	b.openedAt = now
+	b.consecutiveFailures = 0
+}
+
+// When the circuit lets the next probe through, zero while it is not open
+func (b *circuitBreaker) retryAt() time.Time {
+	if b.state != CircuitOpen {
+		return time.Time{}
+	}
+	return b.openedAt.Add(time.Duration(b.config.OpenTime))
+}
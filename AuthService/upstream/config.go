@@ -0,0 +1,183 @@
+package upstream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	RoundRobin       = "round_robin"
+	LeastConnections = "least_connections"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid upstream config")
+)
+
+// How requests are spread over the targets of an upstream and when a target is taken out of rotation
+type Config struct {
+	Balancer       string               `json:"balancer"`
+	HealthCheck    HealthCheckConfig    `json:"healthCheck"`
+	Outlier        OutlierConfig        `json:"outlier"`
+	CircuitBreaker CircuitBreakerConfig `json:"circuitBreaker"`
+}
+
+// Active checks, disabled when Path is empty. Path is relative to the target's base url.
+type HealthCheckConfig struct {
+	Path               string   `json:"path"`
+	Interval           Duration `json:"interval"`
+	Timeout            Duration `json:"timeout"`
+	HealthyThreshold   int      `json:"healthyThreshold"`
+	UnhealthyThreshold int      `json:"unhealthyThreshold"`
+}
+
+// Passive ejection after consecutive failed requests, disabled when ConsecutiveFailures is negative.
+// Each further ejection of the same target lasts one EjectionTime longer, up to MaxEjectionTime.
+type OutlierConfig struct {
+	ConsecutiveFailures int      `json:"consecutiveFailures"`
+	EjectionTime        Duration `json:"ejectionTime"`
+	MaxEjectionTime     Duration `json:"maxEjectionTime"`
+}
+
+// Stops sending requests to the whole upstream after FailureThreshold failed requests in a row.
+// After OpenTime, HalfOpenRequests probes are let through, the circuit closes once all of them succeed.
+// Disabled when FailureThreshold is negative.
+type CircuitBreakerConfig struct {
+	FailureThreshold int      `json:"failureThreshold"`
+	OpenTime         Duration `json:"openTime"`
+	HalfOpenRequests int      `json:"halfOpenRequests"`
+}
+
+// Deadline, retries and caching of the requests proxied for one route. Timeout covers every attempt and the response body.
+type RouteConfig struct {
+	Timeout Duration    `json:"timeout"`
+	Retry   RetryConfig `json:"retry"`
+	Cache   CacheConfig `json:"cache"`
+}
+
+// Retries after a transport error, a timed out try or a 502, 503 or 504, only for methods that are safe to repeat.
+// The wait before retry n is random up to Backoff * 2^n, capped at MaxBackoff. Disabled when Attempts is negative.
+type RetryConfig struct {
+	Attempts      int      `json:"attempts"`
+	PerTryTimeout Duration `json:"perTryTimeout"`
+	Backoff       Duration `json:"backoff"`
+	MaxBackoff    Duration `json:"maxBackoff"`
+}
+
+// Keeps successful GET answers shared by every caller for TTL, unless the upstream's Cache-Control says
+// otherwise. Answers larger than MaxBodyBytes are not kept. Disabled while TTL is zero.
+type CacheConfig struct {
+	TTL          Duration `json:"ttl"`
+	MaxBodyBytes int      `json:"maxBodyBytes"`
+}
+
+// A duration written as "10s" or "1m30s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\"")
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Fills in the defaults for everything left out and rejects values that make no sense
+func (c Config) withDefaults() (Config, error) {
+	switch c.Balancer {
+	case "":
+		c.Balancer = RoundRobin
+	case RoundRobin, LeastConnections:
+	default:
+		return c, fmt.Errorf("%w: unknown balancer %q", ErrInvalidConfig, c.Balancer)
+	}
+
+	check := &c.HealthCheck
+	if check.Interval == 0 {
+		check.Interval = Duration(10 * time.Second)
+	}
+	if check.Timeout == 0 {
+		check.Timeout = Duration(2 * time.Second)
+	}
+	if check.HealthyThreshold == 0 {
+		check.HealthyThreshold = 2
+	}
+	if check.UnhealthyThreshold == 0 {
+		check.UnhealthyThreshold = 3
+	}
+	if check.Interval < 0 || check.Timeout < 0 || check.Timeout > check.Interval || check.HealthyThreshold < 0 || check.UnhealthyThreshold < 0 {
+		return c, fmt.Errorf("%w: health check needs a timeout no longer than its interval and positive thresholds", ErrInvalidConfig)
+	}
+
+	outlier := &c.Outlier
+	if outlier.ConsecutiveFailures == 0 {
+		outlier.ConsecutiveFailures = 5
+	}
+	if outlier.EjectionTime == 0 {
+		outlier.EjectionTime = Duration(30 * time.Second)
+	}
+	if outlier.MaxEjectionTime == 0 {
+		outlier.MaxEjectionTime = Duration(5 * time.Minute)
+	}
+	if outlier.EjectionTime < 0 || outlier.MaxEjectionTime < outlier.EjectionTime {
+		return c, fmt.Errorf("%w: ejection time must be positive and at most maxEjectionTime", ErrInvalidConfig)
+	}
+
+	breaker := &c.CircuitBreaker
+	if breaker.FailureThreshold == 0 {
+		breaker.FailureThreshold = 10
+	}
+	if breaker.OpenTime == 0 {
+		breaker.OpenTime = Duration(30 * time.Second)
+	}
+	if breaker.HalfOpenRequests == 0 {
+		breaker.HalfOpenRequests = 1
+	}
+	if breaker.OpenTime < 0 || breaker.HalfOpenRequests < 0 {
+		return c, fmt.Errorf("%w: circuit breaker needs a positive openTime and halfOpenRequests", ErrInvalidConfig)
+	}
+	return c, nil
+}
+
+func (c Config) Validate() error {
+	_, err := c.withDefaults()
+	return err
+}
+
+// Fills in the defaults for everything left out: a 10 second timeout and two retries
+func (c RouteConfig) WithDefaults() (RouteConfig, error) {
+	if c.Timeout == 0 {
+		c.Timeout = Duration(10 * time.Second)
+	}
+	retry := &c.Retry
+	if retry.Attempts == 0 {
+		retry.Attempts = 2
+	}
+	if retry.Backoff == 0 {
+		retry.Backoff = Duration(100 * time.Millisecond)
+	}
+	if retry.MaxBackoff == 0 {
+		retry.MaxBackoff = Duration(time.Second)
+	}
+	if c.Cache.TTL > 0 && c.Cache.MaxBodyBytes == 0 {
+		c.Cache.MaxBodyBytes = 1 << 20
+	}
+	if c.Cache.TTL < 0 || c.Cache.MaxBodyBytes < 0 {
+		return c, fmt.Errorf("%w: cache ttl and maxBodyBytes must be positive", ErrInvalidConfig)
+	}
+	if c.Timeout < 0 || retry.PerTryTimeout < 0 || retry.PerTryTimeout > c.Timeout || retry.Backoff < 0 || retry.MaxBackoff < retry.Backoff {
+		return c, fmt.Errorf("%w: timeouts and backoffs must be positive, perTryTimeout at most timeout and backoff at most maxBackoff", ErrInvalidConfig)
+	}
+	return c, nil
+}
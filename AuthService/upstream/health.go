@@ -0,0 +1,108 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var healthCheckClient = &http.Client{
+	// Health checks report what the target itself answers
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func (p *Pool) runHealthChecks(ctx context.Context) {
+	if p.config.HealthCheck.Path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(p.config.HealthCheck.Interval))
+	defer ticker.Stop()
+	for {
+		p.checkAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, target := range p.targets {
+		wg.Add(1)
+		go func(target *Target) {
+			defer wg.Done()
+			p.recordCheck(target, p.check(ctx, target))
+		}(target)
+	}
+	wg.Wait()
+}
+
+// A check passes on any 2xx answer within the timeout
+func (p *Pool) check(ctx context.Context, target *Target) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(p.config.HealthCheck.Timeout))
+	defer cancel()
+
+	endpoint := strings.TrimRight(target.URL.String(), "/") + "/" + strings.TrimLeft(p.config.HealthCheck.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	res, err := healthCheckClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("health check answered %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Flips the target only after enough checks in a row agree, one slow answer does not take it out
+func (p *Pool) recordCheck(target *Target, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target.lastCheckAt = time.Now()
+	if err == nil {
+		target.lastCheckError = ""
+		target.checkFailures = 0
+		target.checkSuccesses++
+		if !target.healthy && target.checkSuccesses >= p.config.HealthCheck.HealthyThreshold {
+			target.healthy = true
+			p.logTransition(target, "healthy", nil)
+		}
+		return
+	}
+
+	target.lastCheckError = err.Error()
+	target.checkSuccesses = 0
+	target.checkFailures++
+	if target.healthy && target.checkFailures >= p.config.HealthCheck.UnhealthyThreshold {
+		target.healthy = false
+		p.logTransition(target, "unhealthy", err)
+	}
+}
+
+func (p *Pool) logTransition(target *Target, state string, err error) {
+	logrus.WithFields(logrus.Fields{
+		"err":      err,
+		"upstream": p.name,
+		"target":   target.URL.String(),
+		"state":    state,
+		"type":     "upstream_health",
+	}).Warn("Upstream target is " + state)
+}
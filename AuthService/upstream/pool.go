@@ -0,0 +1,295 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNoHealthyTarget = errors.New("no healthy upstream target")
+)
+
+// The replicas of one upstream service
+type Pool struct {
+	name    string
+	config  Config
+	targets []*Target
+	breaker *circuitBreaker
+
+	next atomic.Uint64
+	mu   sync.Mutex
+}
+
+// One replica. Its health and ejection state is guarded by the pool's lock.
+type Target struct {
+	URL  *url.URL
+	pool *Pool
+
+	active   atomic.Int64
+	requests atomic.Uint64
+	failures atomic.Uint64
+
+	healthy             bool
+	checkSuccesses      int
+	checkFailures       int
+	lastCheckAt         time.Time
+	lastCheckError      string
+	consecutiveFailures int
+	ejections           int
+	ejectedUntil        time.Time
+}
+
+// Targets start out healthy, so traffic flows before the first health check finished
+func NewPool(name string, baseUrls []string, config Config) (*Pool, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, fmt.Errorf("upstream %s: %w", name, err)
+	}
+	if len(baseUrls) == 0 {
+		return nil, fmt.Errorf("upstream %s: %w: no targets", name, ErrInvalidConfig)
+	}
+
+	pool := &Pool{name: name, config: config, breaker: newCircuitBreaker(config.CircuitBreaker)}
+	for _, baseUrl := range baseUrls {
+		parsed, err := url.Parse(strings.TrimSpace(baseUrl))
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, fmt.Errorf("upstream %s: %w: invalid target %q", name, ErrInvalidConfig, baseUrl)
+		}
+		pool.targets = append(pool.targets, &Target{URL: parsed, pool: pool, healthy: true})
+	}
+	return pool, nil
+}
+
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Picks a healthy target that is not ejected, using the pool's balancer.
+// Fails with ErrCircuitOpen while the pool's circuit breaker holds requests back.
+func (p *Pool) Pick() (*Target, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	start := int(p.next.Add(1) - 1)
+	var picked *Target
+	for i := range p.targets {
+		target := p.targets[(start+i)%len(p.targets)]
+		if !target.availableAt(now) {
+			continue
+		}
+		if p.config.Balancer == RoundRobin {
+			picked = target
+			break
+		}
+		if picked == nil || target.active.Load() < picked.active.Load() {
+			picked = target
+		}
+	}
+	if picked == nil {
+		return nil, fmt.Errorf("%w for %s", ErrNoHealthyTarget, p.name)
+	}
+	if err := p.breaker.allow(now); err != nil {
+		return nil, fmt.Errorf("%w for %s", err, p.name)
+	}
+	return picked, nil
+}
+
+// When an open circuit lets the next request through, zero while it is not open
+func (p *Pool) RetryAt() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.breaker.retryAt()
+}
+
+// Nil while the pool has a target to send to and its circuit is not holding requests back.
+// Reads the state the health checks and past requests left, it sends nothing itself.
+func (p *Pool) Check() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.availableCount(now) == 0 {
+		return fmt.Errorf("%w for %s", ErrNoHealthyTarget, p.name)
+	}
+	if now.Before(p.breaker.retryAt()) {
+		return fmt.Errorf("%w for %s", ErrCircuitOpen, p.name)
+	}
+	return nil
+}
+
+func (t *Target) availableAt(now time.Time) bool {
+	return t.healthy && !now.Before(t.ejectedUntil)
+}
+
+// Counts a request as in flight until End is called with its outcome, or Abandon when it has none
+func (t *Target) Begin() {
+	t.active.Add(1)
+	t.requests.Add(1)
+}
+
+// A failed request is a transport error or a 5xx from the target. Enough of them in a row eject the target,
+// but never the last one still available, a service that is down for good is for the health check to find.
+func (t *Target) End(failed bool) {
+	t.active.Add(-1)
+
+	p := t.pool
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.breaker.record(failed, time.Now())
+	if !failed {
+		t.consecutiveFailures = 0
+		return
+	}
+	t.failures.Add(1)
+	t.consecutiveFailures++
+
+	outlier := p.config.Outlier
+	if outlier.ConsecutiveFailures < 0 || t.consecutiveFailures < outlier.ConsecutiveFailures {
+		return
+	}
+	now := time.Now()
+	if !t.availableAt(now) || p.availableCount(now) <= 1 {
+		return
+	}
+
+	t.ejections++
+	ejection := time.Duration(outlier.EjectionTime) * time.Duration(t.ejections)
+	ejection = min(ejection, time.Duration(outlier.MaxEjectionTime))
+	t.ejectedUntil = now.Add(ejection)
+	t.consecutiveFailures = 0
+}
+
+// Ends a request the client cancelled before the target answered. That says nothing about the target,
+// neither the circuit breaker nor the outlier detection count it.
+func (t *Target) Abandon() {
+	t.active.Add(-1)
+
+	p := t.pool
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.breaker.abandon()
+}
+
+func (p *Pool) availableCount(now time.Time) int {
+	count := 0
+	for _, target := range p.targets {
+		if target.availableAt(now) {
+			count++
+		}
+	}
+	return count
+}
+
+type TargetStatus struct {
+	Url                 string `json:"url"`
+	Healthy             bool   `json:"healthy"`
+	Ejected             bool   `json:"ejected"`
+	EjectedUntil        string `json:"ejected_until,omitempty"`
+	Ejections           int    `json:"ejections"`
+	ActiveRequests      int64  `json:"active_requests"`
+	Requests            uint64 `json:"requests"`
+	Failures            uint64 `json:"failures"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastCheckAt         string `json:"last_check_at,omitempty"`
+	LastCheckError      string `json:"last_check_error,omitempty"`
+}
+
+type PoolStatus struct {
+	Name           string               `json:"name"`
+	Balancer       string               `json:"balancer"`
+	HealthCheck    HealthCheckConfig    `json:"health_check"`
+	Outlier        OutlierConfig        `json:"outlier"`
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"`
+	Circuit        string               `json:"circuit"`
+	CircuitRetryAt string               `json:"circuit_retry_at,omitempty"`
+	Available      int                  `json:"available"`
+	Targets        []TargetStatus       `json:"targets"`
+}
+
+func (p *Pool) Status() PoolStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	status := PoolStatus{
+		Name:           p.name,
+		Balancer:       p.config.Balancer,
+		HealthCheck:    p.config.HealthCheck,
+		Outlier:        p.config.Outlier,
+		CircuitBreaker: p.config.CircuitBreaker,
+		Circuit:        p.breaker.state,
+		Available:      p.availableCount(now),
+		Targets:        make([]TargetStatus, 0, len(p.targets)),
+	}
+	if retryAt := p.breaker.retryAt(); !retryAt.IsZero() {
+		status.CircuitRetryAt = retryAt.UTC().Format(time.RFC3339)
+	}
+	for _, target := range p.targets {
+		targetStatus := TargetStatus{
+			Url:                 target.URL.String(),
+			Healthy:             target.healthy,
+			Ejected:             now.Before(target.ejectedUntil),
+			Ejections:           target.ejections,
+			ActiveRequests:      target.active.Load(),
+			Requests:            target.requests.Load(),
+			Failures:            target.failures.Load(),
+			ConsecutiveFailures: target.consecutiveFailures,
+			LastCheckError:      target.lastCheckError,
+		}
+		if targetStatus.Ejected {
+			targetStatus.EjectedUntil = target.ejectedUntil.UTC().Format(time.RFC3339)
+		}
+		if !target.lastCheckAt.IsZero() {
+			targetStatus.LastCheckAt = target.lastCheckAt.UTC().Format(time.RFC3339)
+		}
+		status.Targets = append(status.Targets, targetStatus)
+	}
+	return status
+}
+
+// Pools by upstream name, in the order they were added
+type Registry struct {
+	pools []*Pool
+}
+
+func NewRegistry(pools ...*Pool) *Registry {
+	return &Registry{pools: pools}
+}
+
+func (r *Registry) Get(name string) (*Pool, bool) {
+	for _, pool := range r.pools {
+		if pool.name == name {
+			return pool, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Registry) Pools() []*Pool {
+	return r.pools
+}
+
+func (r *Registry) Status() []PoolStatus {
+	statuses := make([]PoolStatus, 0, len(r.pools))
+	for _, pool := range r.pools {
+		statuses = append(statuses, pool.Status())
+	}
+	return statuses
+}
+
+// Runs the health checks of every pool until ctx is done
+func (r *Registry) Start(ctx context.Context) {
+	for _, pool := range r.pools {
+		go pool.runHealthChecks(ctx)
+	}
+}
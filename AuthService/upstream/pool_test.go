@@ -0,0 +1,282 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestPool(t *testing.T, urls []string, config Config) *Pool {
+	t.Helper()
+	pool, err := NewPool("submission", urls, config)
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	return pool
+}
+
+func pickHosts(t *testing.T, pool *Pool, n int) []string {
+	t.Helper()
+	hosts := make([]string, 0, n)
+	for range n {
+		target, err := pool.Pick()
+		if err != nil {
+			t.Fatalf("Pick() error = %v", err)
+		}
+		hosts = append(hosts, target.URL.Host)
+	}
+	return hosts
+}
+
+func TestPickBalancers(t *testing.T) {
+	urls := []string{"http://a.test", "http://b.test", "http://c.test"}
+
+	t.Run("round robin takes turns", func(t *testing.T) {
+		pool := newTestPool(t, urls, Config{Balancer: RoundRobin})
+		got := pickHosts(t, pool, 4)
+		want := []string{"a.test", "b.test", "c.test", "a.test"}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("picks = %v, want %v", got, want)
+			}
+		}
+	})
+
+	t.Run("least connections takes the least busy", func(t *testing.T) {
+		pool := newTestPool(t, urls, Config{Balancer: LeastConnections})
+		a, b, c := pool.targets[0], pool.targets[1], pool.targets[2]
+		a.Begin()
+		b.Begin()
+		for _, host := range pickHosts(t, pool, 3) {
+			if host != "c.test" {
+				t.Fatalf("picked %s, want c.test while a and b are busy", host)
+			}
+		}
+
+		c.Begin()
+		c.Begin()
+		a.End(false)
+		if host := pickHosts(t, pool, 1)[0]; host != "a.test" {
+			t.Errorf("picked %s, want a.test with no request in flight", host)
+		}
+	})
+}
+
+func TestPickSkipsUnavailableTargets(t *testing.T) {
+	for _, balancer := range []string{RoundRobin, LeastConnections} {
+		t.Run(balancer, func(t *testing.T) {
+			pool := newTestPool(t, []string{"http://a.test", "http://b.test", "http://c.test"}, Config{Balancer: balancer})
+			pool.targets[0].healthy = false
+			pool.targets[2].ejectedUntil = time.Now().Add(time.Minute)
+
+			for _, host := range pickHosts(t, pool, 4) {
+				if host != "b.test" {
+					t.Fatalf("picked %s, want only b.test", host)
+				}
+			}
+
+			pool.targets[1].healthy = false
+			if _, err := pool.Pick(); !errors.Is(err, ErrNoHealthyTarget) {
+				t.Errorf("Pick() error = %v, want %v", err, ErrNoHealthyTarget)
+			}
+			if err := pool.Check(); !errors.Is(err, ErrNoHealthyTarget) {
+				t.Errorf("Check() error = %v, want %v", err, ErrNoHealthyTarget)
+			}
+
+			pool.targets[2].ejectedUntil = time.Now().Add(-time.Second)
+			if host := pickHosts(t, pool, 1)[0]; host != "c.test" {
+				t.Errorf("picked %s, want c.test once its ejection ended", host)
+			}
+		})
+	}
+}
+
+func TestEjectionBackoffIsCapped(t *testing.T) {
+	pool := newTestPool(t, []string{"http://a.test", "http://b.test"}, Config{
+		Outlier:        OutlierConfig{ConsecutiveFailures: 2, EjectionTime: Duration(time.Minute), MaxEjectionTime: Duration(150 * time.Second)},
+		CircuitBreaker: CircuitBreakerConfig{FailureThreshold: -1},
+	})
+	target := pool.targets[0]
+
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 150 * time.Second, 150 * time.Second} {
+		// Let the previous ejection end, the target must be back in rotation to be ejected again
+		target.ejectedUntil = time.Time{}
+
+		before := time.Now()
+		target.Begin()
+		target.End(true)
+		if !target.ejectedUntil.IsZero() {
+			t.Fatalf("ejection %d: ejected after one failure, want %d in a row", i+1, 2)
+		}
+		target.Begin()
+		target.End(true)
+		after := time.Now()
+
+		if target.ejectedUntil.Before(before.Add(want)) || target.ejectedUntil.After(after.Add(want)) {
+			t.Errorf("ejection %d lasts until %s, want %s from now", i+1, target.ejectedUntil.Sub(before), want)
+		}
+		if target.ejections != i+1 {
+			t.Errorf("ejections = %d, want %d", target.ejections, i+1)
+		}
+	}
+
+	// A success in between starts the count of failures in a row over
+	target.ejectedUntil = time.Time{}
+	target.Begin()
+	target.End(true)
+	target.Begin()
+	target.End(false)
+	target.Begin()
+	target.End(true)
+	if !target.ejectedUntil.IsZero() {
+		t.Errorf("ejected after failures that were not in a row")
+	}
+}
+
+func TestLastAvailableTargetIsNeverEjected(t *testing.T) {
+	pool := newTestPool(t, []string{"http://a.test", "http://b.test", "http://c.test"}, Config{
+		Outlier:        OutlierConfig{ConsecutiveFailures: 1},
+		CircuitBreaker: CircuitBreakerConfig{FailureThreshold: -1},
+	})
+	a, b, c := pool.targets[0], pool.targets[1], pool.targets[2]
+	c.healthy = false
+
+	a.Begin()
+	a.End(true)
+	if status := pool.Status(); !status.Targets[0].Ejected || status.Available != 1 {
+		t.Fatalf("status = %+v, want a ejected and only b available", status)
+	}
+
+	for range 3 {
+		b.Begin()
+		b.End(true)
+	}
+	status := pool.Status()
+	if status.Targets[1].Ejected || status.Available != 1 {
+		t.Errorf("status = %+v, want b kept as the last available target", status)
+	}
+	if status.Targets[1].Failures != 3 {
+		t.Errorf("b failures = %d, want 3 counted even though it was kept", status.Targets[1].Failures)
+	}
+	if _, err := pool.Pick(); err != nil {
+		t.Errorf("Pick() error = %v, want the last target", err)
+	}
+}
+
+func TestHealthCheckTransitions(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	var path atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path.Store(r.URL.Path)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	pool := newTestPool(t, []string{server.URL + "/api/"}, Config{
+		HealthCheck: HealthCheckConfig{Path: "/health", Timeout: Duration(time.Second), HealthyThreshold: 2, UnhealthyThreshold: 2},
+	})
+	ctx := context.Background()
+
+	steps := []struct {
+		name        string
+		status      int
+		wantHealthy bool
+		wantError   bool
+	}{
+		{name: "healthy target answers", status: http.StatusOK, wantHealthy: true},
+		{name: "one failed check keeps it", status: http.StatusServiceUnavailable, wantHealthy: true, wantError: true},
+		{name: "second failed check in a row takes it out", status: http.StatusServiceUnavailable, wantHealthy: false, wantError: true},
+		{name: "a redirect is no answer of the target", status: http.StatusFound, wantHealthy: false, wantError: true},
+		{name: "one passed check does not bring it back", status: http.StatusOK, wantHealthy: false},
+		{name: "second passed check in a row brings it back", status: http.StatusOK, wantHealthy: true},
+	}
+	for _, step := range steps {
+		status.Store(int32(step.status))
+		pool.checkAll(ctx)
+
+		target := pool.Status().Targets[0]
+		if target.Healthy != step.wantHealthy {
+			t.Errorf("%s: healthy = %v, want %v", step.name, target.Healthy, step.wantHealthy)
+		}
+		if (target.LastCheckError != "") != step.wantError {
+			t.Errorf("%s: last check error = %q, want error %v", step.name, target.LastCheckError, step.wantError)
+		}
+		if target.LastCheckAt == "" {
+			t.Errorf("%s: last check time not recorded", step.name)
+		}
+		if _, err := pool.Pick(); (err == nil) != step.wantHealthy {
+			t.Errorf("%s: Pick() error = %v, want available %v", step.name, err, step.wantHealthy)
+		}
+	}
+	if got := path.Load(); got != "/api/health" {
+		t.Errorf("checked path = %v, want /api/health", got)
+	}
+
+	// A target that is gone fails its checks like one answering with an error status
+	server.Close()
+	pool.checkAll(ctx)
+	pool.checkAll(ctx)
+	if target := pool.Status().Targets[0]; target.Healthy {
+		t.Errorf("target is healthy after it stopped answering")
+	}
+}
+
+func TestAbandonRecordsNoOutcome(t *testing.T) {
+	pool := newTestPool(t, []string{"http://a.test", "http://b.test"}, Config{
+		Outlier:        OutlierConfig{ConsecutiveFailures: 1},
+		CircuitBreaker: CircuitBreakerConfig{FailureThreshold: 1},
+	})
+
+	target, err := pool.Pick()
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	target.Begin()
+	target.Abandon()
+
+	status := pool.Status()
+	if status.Circuit != CircuitClosed || status.Available != 2 {
+		t.Errorf("circuit = %s with %d available, want closed with 2", status.Circuit, status.Available)
+	}
+	for _, target := range status.Targets {
+		if target.ActiveRequests != 0 || target.Failures != 0 {
+			t.Errorf("target %s = %+v, want nothing in flight or failed", target.Url, target)
+		}
+	}
+}
+
+func TestAbandonedProbeFreesItsSlot(t *testing.T) {
+	pool := newTestPool(t, []string{"http://a.test"}, Config{
+		CircuitBreaker: CircuitBreakerConfig{FailureThreshold: 1, OpenTime: Duration(time.Millisecond), HalfOpenRequests: 1},
+	})
+
+	target, _ := pool.Pick()
+	target.Begin()
+	target.End(true)
+	time.Sleep(2 * time.Millisecond)
+
+	probe, err := pool.Pick()
+	if err != nil {
+		t.Fatalf("Pick() after the open time error = %v", err)
+	}
+	probe.Begin()
+	if _, err := pool.Pick(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second probe error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	probe.Abandon()
+	probe, err = pool.Pick()
+	if err != nil {
+		t.Fatalf("Pick() after the probe was abandoned error = %v", err)
+	}
+	probe.Begin()
+	probe.End(false)
+	if circuit := pool.Status().Circuit; circuit != CircuitClosed {
+		t.Errorf("circuit = %s, want %s", circuit, CircuitClosed)
+	}
+}
@@ -2,7 +2,13 @@ package utils
 
 import (
 	env "AuthService/config/env"
+	"AuthService/dto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
@@ -14,6 +20,11 @@ type contextKey string
 const (
 	UserIDKey contextKey = "userId"
 	EmailKey  contextKey = "email"
+	ClaimsKey contextKey = "claims"
+	// Active suspensions of the caller, loaded once per request by the auth middleware
+	SuspensionsKey contextKey = "suspensions"
+	RequestMetaKey contextKey = "requestMeta"
+	AccessLogKey   contextKey = "accessLog"
 )
 
 func HashPassword(password string) (string, error) {
@@ -30,11 +41,26 @@ func CheckPassword(hashedPassword string, password string) bool {
 	return err == nil
 }
 
-func CreateJwtToken(id int, email string) (string, error) {
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":    id,
-		"email": email,
-	})
+func CreateJwtToken(payload dto.TokenClaimsDTO) (string, error) {
+	mapClaims := jwt.MapClaims{
+		"id":            payload.UserId,
+		"email":         payload.Email,
+		"roles":         payload.Roles,
+		"permissions":   payload.Permissions,
+		"authz_version": payload.AuthzVersion,
+		"org_id":        payload.OrgId,
+		"org_role":      payload.OrgRole,
+	}
+	if payload.SessionId != "" {
+		mapClaims["sid"] = payload.SessionId
+	}
+	if payload.ImpersonatorId != 0 {
+		mapClaims["act"] = payload.ImpersonatorId
+	}
+	if payload.ExpiresAt != 0 {
+		mapClaims["exp"] = payload.ExpiresAt
+	}
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims)
 	tokenString, err := claims.SignedString([]byte(env.GetString("SECRET_KEY", "TOKEN")))
 	if err != nil {
 		fmt.Println("Token string creation error:", err)
@@ -43,3 +69,103 @@ func CreateJwtToken(id int, email string) (string, error) {
 
 	return tokenString, nil
 }
+
+var (
+	ErrInvalidInviteToken = errors.New("invalid or expired invite token")
+)
+
+// Invite tokens are bound to the password hash at issue time, so they stop working once a password is set
+func CreateInviteToken(userId int64, hashedPassword string, ttl time.Duration) (string, error) {
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose": "invite",
+		"id":      userId,
+		"pwv":     passwordFingerprint(hashedPassword),
+		"exp":     time.Now().Add(ttl).Unix(),
+	})
+	return claims.SignedString([]byte(env.GetString("SECRET_KEY", "TOKEN")))
+}
+
+// Returns the user id of a valid invite token issued for the given password hash
+func ParseInviteToken(token string, currentHashedPassword func(userId int64) (string, error)) (int64, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidInviteToken
+		}
+		return []byte(env.GetString("SECRET_KEY", "TOKEN")), nil
+	})
+	if err != nil {
+		return 0, ErrInvalidInviteToken
+	}
+
+	purpose, _ := claims["purpose"].(string)
+	userId, okId := claims["id"].(float64)
+	fingerprint, _ := claims["pwv"].(string)
+	if purpose != "invite" || !okId {
+		return 0, ErrInvalidInviteToken
+	}
+
+	hashedPassword, err := currentHashedPassword(int64(userId))
+	if err != nil {
+		return 0, err
+	}
+	if fingerprint != passwordFingerprint(hashedPassword) {
+		return 0, ErrInvalidInviteToken
+	}
+
+	return int64(userId), nil
+}
+
+var (
+	ErrInvalidMagicLinkToken = errors.New("invalid or expired login link")
+)
+
+// Login link tokens name a single-use record by its id, the record is what makes them usable only once
+func CreateMagicLinkToken(userId int64, tokenId string, ttl time.Duration) (string, error) {
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose": "magic_link",
+		"id":      userId,
+		"jti":     tokenId,
+		"exp":     time.Now().Add(ttl).Unix(),
+	})
+	return claims.SignedString([]byte(env.GetString("SECRET_KEY", "TOKEN")))
+}
+
+// Returns the user id and token id of a valid login link token
+func ParseMagicLinkToken(token string) (int64, string, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidMagicLinkToken
+		}
+		return []byte(env.GetString("SECRET_KEY", "TOKEN")), nil
+	})
+	if err != nil {
+		return 0, "", ErrInvalidMagicLinkToken
+	}
+
+	purpose, _ := claims["purpose"].(string)
+	userId, okId := claims["id"].(float64)
+	tokenId, _ := claims["jti"].(string)
+	// Only tokens carrying an expiry were issued as login links
+	_, okExp := claims["exp"].(float64)
+	if purpose != "magic_link" || !okId || tokenId == "" || !okExp {
+		return 0, "", ErrInvalidMagicLinkToken
+	}
+
+	return int64(userId), tokenId, nil
+}
+
+func passwordFingerprint(hashedPassword string) string {
+	sum := sha256.Sum256([]byte(hashedPassword))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Random hex string built from n bytes of crypto/rand
+func RandomToken(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
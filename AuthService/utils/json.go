@@ -38,9 +38,13 @@ func WriteSuccessResponse(w http.ResponseWriter, status int, message string, dat
 }
 
 func WriteErrorResponse(w http.ResponseWriter, status int, message string, err any) error {
+	return WriteJsonResponse(w, status, errorResponse(message, err))
+}
+
+func errorResponse(message string, err any) map[string]any {
 	response := map[string]any{}
 	response["status"] = "failed"
 	response["message"] = message
 	response["error"] = err
-	return WriteJsonResponse(w, status, response)
+	return response
 }
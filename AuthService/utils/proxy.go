@@ -1,14 +1,31 @@
 package utils
 
 import (
+	"AuthService/assertion"
+	"AuthService/cache"
 	"AuthService/dto"
+	"AuthService/metrics"
+	"AuthService/tracing"
+	"AuthService/upstream"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Custom context key types to avoid collisions
@@ -18,36 +35,202 @@ import (
 // 	UserIDKey contextKey = "userId"
 // )
 
-func ProxyToService(targetBaseUrl string, pathPrefix string) http.HandlerFunc {
-	target, err := url.Parse(targetBaseUrl)
-	if err != nil {
+// Identity headers only the gateway may set, whatever a client sends in them is dropped.
+// Any other X-User- header is dropped as well.
+var identityHeaders = []string{
+	"X-User-ID",
+	"X-User-Email",
+	"X-User-Roles",
+	"X-User-Permissions",
+	"X-Authz-Version",
+	"X-Org-ID",
+	"X-Org-Role",
+	"X-Impersonator-ID",
+	assertion.HeaderName,
+}
+
+func stripIdentityHeaders(header http.Header) {
+	for _, name := range identityHeaders {
+		header.Del(name)
+	}
+	for name := range header {
+		if strings.HasPrefix(name, "X-User-") {
+			header.Del(name)
+		}
+	}
+}
+
+type proxyTargetKey struct{}
+
+// Past the route timeout, the time left to write the error envelope
+const proxyWriteGrace = 2 * time.Second
+
+// Returned by ModifyResponse to hand a retryable answer to the ErrorHandler before anything is written
+var errRetryableStatus = errors.New("upstream answered with a retryable status")
+
+// One try at serving a request: the replica, whether it failed or the client cancelled it,
+// and whether another try should follow
+type proxyAttempt struct {
+	target    *upstream.Target
+	route     context.Context
+	cache     *proxyCache
+	canRetry  bool
+	assertion string
+	failed    atomic.Bool
+	cancelled atomic.Bool
+	retry     atomic.Bool
+}
+
+// Sends the try and reports its outcome to the replica. A client that goes away while the body is
+// copied aborts the handler with a panic, the try is still ended before the panic goes on.
+func (attempt *proxyAttempt) serve(proxy *httputil.ReverseProxy, w http.ResponseWriter, r *http.Request, audience string) {
+	attempt.target.Begin()
+	started := time.Now()
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			if errors.Is(attempt.route.Err(), context.Canceled) {
+				attempt.cancelled.Store(true)
+			} else {
+				attempt.failed.Store(true)
+			}
+		}
+
+		if attempt.cancelled.Load() {
+			attempt.target.Abandon()
+			metrics.ObserveUpstreamCancelled(audience, time.Since(started))
+		} else {
+			attempt.target.End(attempt.failed.Load())
+			metrics.ObserveUpstream(audience, attempt.failed.Load(), time.Since(started))
+		}
+
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
+	proxy.ServeHTTP(w, r)
+}
+
+// Messages for the gateway errors an upstream can answer with, in the gateway's own words
+var upstreamErrorMessages = map[int]string{
+	http.StatusBadGateway:         "Upstream service unavailable",
+	http.StatusServiceUnavailable: "Upstream service is temporarily unavailable",
+	http.StatusGatewayTimeout:     "Upstream service timed out",
+}
+
+// Replaces the body of an upstream's gateway error with the envelope WriteErrorResponse writes,
+// so clients see one error format whether the gateway or the upstream gave up. Retry-After is kept.
+func rewriteUpstreamError(res *http.Response) error {
+	var body bytes.Buffer
+	status := http.StatusText(res.StatusCode)
+	if err := json.NewEncoder(&body).Encode(errorResponse(status, upstreamErrorMessages[res.StatusCode])); err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	res.Body.Close()
+
+	for _, name := range []string{"Content-Encoding", "Content-Length", "Content-Range", "ETag", "Last-Modified", "Transfer-Encoding"} {
+		res.Header.Del(name)
+	}
+	res.Header.Set("Content-Type", "application/json")
+	res.Header.Set("Content-Length", strconv.Itoa(body.Len()))
+	res.ContentLength = int64(body.Len())
+	res.TransferEncoding = nil
+	res.Body = io.NopCloser(&body)
+	return nil
+}
+
+// Proxies to a healthy replica of the pool. The caller's identity travels in plain headers
+// and in a signed assertion for the pool's upstream, which is what upstreams should trust.
+// The whole request, retries included, must finish within the route's timeout.
+// Routes with a cache TTL answer GETs from store when they can, a nil store turns caching off.
+func ProxyToService(pool *upstream.Pool, config upstream.RouteConfig, pathPrefix string, signer *assertion.Signer, store *cache.Store) http.HandlerFunc {
+	audience := pool.Name()
+	// Every try gets a client span and carries the trace to the upstream in traceparent
+	proxy := &httputil.ReverseProxy{Transport: tracing.Transport(http.DefaultTransport)}
+
+	// Transport errors and 5xx answers count against the replica, enough in a row eject it for a while
+	proxy.ModifyResponse = func(res *http.Response) error {
+		attempt, ok := res.Request.Context().Value(proxyTargetKey{}).(*proxyAttempt)
+		if !ok {
+			return nil
+		}
+		attempt.failed.Store(res.StatusCode >= 500)
+		switch res.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			if attempt.canRetry {
+				return errRetryableStatus
+			}
+			return rewriteUpstreamError(res)
+		}
+		if attempt.cache != nil {
+			return attempt.cache.handleResponse(res)
+		}
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		attempt, ok := r.Context().Value(proxyTargetKey{}).(*proxyAttempt)
+		if !ok {
+			WriteErrorResponse(w, http.StatusBadGateway, http.StatusText(http.StatusBadGateway), "Upstream service unavailable")
+			return
+		}
+		if errors.Is(err, errRetryableStatus) {
+			attempt.retry.Store(true)
+			return
+		}
+		// The client went away, that says nothing about the replica
+		if errors.Is(attempt.route.Err(), context.Canceled) {
+			attempt.cancelled.Store(true)
+			return
+		}
+
+		attempt.failed.Store(true)
 		logrus.WithFields(logrus.Fields{
-			"err":  fmt.Sprintf("Error parsing target url: %s", targetBaseUrl),
-			"type": "proxy_error",
+			"err":            err,
+			"upstream":       audience,
+			"target":         r.URL.Host,
+			"correlation_id": GetRequestMeta(r.Context()).CorrelationId,
+			"trace_id":       tracing.TraceId(r.Context()),
+			"type":           "proxy_error",
 		}).Error("Proxy Error")
-		return func(w http.ResponseWriter, r *http.Request) {
-			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+
+		// A try that ran past its own timeout is retried, one that ran past the route's is not
+		if attempt.canRetry && attempt.route.Err() == nil {
+			attempt.retry.Store(true)
+			return
 		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			WriteErrorResponse(w, http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout), "Upstream service timed out")
+			return
+		}
+		WriteErrorResponse(w, http.StatusBadGateway, http.StatusText(http.StatusBadGateway), "Upstream service unavailable")
 	}
 
-	proxy := httputil.NewSingleHostReverseProxy(target)
-
 	proxy.Director = func(r *http.Request) {
+		target := r.Context().Value(proxyTargetKey{}).(*proxyAttempt).target.URL
+
 		// Get the original path
 		originalPath := r.URL.Path
 
-		logrus.WithFields(logrus.Fields{
-			"original_path": originalPath,
-			"path_prefix":   pathPrefix,
-			"target_host":   target.Host,
-		}).Info("Proxying request")
-
 		// Set the target
 		r.URL.Scheme = target.Scheme
 		r.URL.Host = target.Host
 		r.URL.Path = originalPath
 		r.Host = target.Host
 
+		stripIdentityHeaders(r.Header)
+
+		// Upstreams log under the same ids, so one request can be followed through every service
+		meta := GetRequestMeta(r.Context())
+		r.Header.Set("X-Request-ID", meta.RequestId)
+		r.Header.Set("X-Correlation-ID", meta.CorrelationId)
+
+		// A stored answer needs the full body, the gateway checks the client's validators itself
+		if attempt := r.Context().Value(proxyTargetKey{}).(*proxyAttempt); attempt.cache != nil && attempt.cache.key != "" {
+			r.Header.Del("If-None-Match")
+			r.Header.Del("If-Modified-Since")
+		}
+
 		// Add user ID header
 		// payload := r.Context().Value("payload")
 		// payloadValue, ok := payload.(dto.UserIdDTO)
@@ -63,7 +246,169 @@ func ProxyToService(targetBaseUrl string, pathPrefix string) http.HandlerFunc {
 		if ok {
 			r.Header.Set("X-User-ID", strconv.Itoa(userIdDTO.UserId))
 		}
+
+		// Forward the token claims so upstreams don't need to look up roles themselves
+		if tokenClaims, ok := r.Context().Value(ClaimsKey).(dto.TokenClaimsDTO); ok {
+			r.Header.Set("X-User-Email", tokenClaims.Email)
+			r.Header.Set("X-User-Roles", strings.Join(tokenClaims.Roles, ","))
+			r.Header.Set("X-User-Permissions", strings.Join(tokenClaims.Permissions, ","))
+			r.Header.Set("X-Authz-Version", strconv.Itoa(tokenClaims.AuthzVersion))
+
+			// Upstreams scope their data by the active organization
+			if tokenClaims.OrgId != 0 {
+				r.Header.Set("X-Org-ID", strconv.FormatInt(tokenClaims.OrgId, 10))
+				r.Header.Set("X-Org-Role", tokenClaims.OrgRole)
+			}
+
+			// Upstreams see the impersonated user as X-User-ID and the acting admin separately
+			if tokenClaims.ImpersonatorId != 0 {
+				r.Header.Set("X-Impersonator-ID", strconv.Itoa(tokenClaims.ImpersonatorId))
+			}
+
+			if attempt := r.Context().Value(proxyTargetKey{}).(*proxyAttempt); attempt.assertion != "" {
+				r.Header.Set(assertion.HeaderName, attempt.assertion)
+			}
+		}
 	}
 
-	return proxy.ServeHTTP
+	return func(w http.ResponseWriter, r *http.Request) {
+		spanCtx, span := tracing.Tracer().Start(r.Context(), "proxy "+audience, trace.WithAttributes(
+			attribute.String("gateway.upstream", audience),
+			attribute.String("gateway.route_prefix", pathPrefix),
+		))
+		defer span.End()
+		r = r.WithContext(spanCtx)
+
+		timeout := time.Duration(config.Timeout)
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		// The server's write timeout would cut off routes allowed to take longer
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + proxyWriteGrace))
+
+		accessLog := GetAccessLogFields(r.Context())
+		accessLog.Upstream = audience
+
+		cached, served := lookupProxyCache(w, r, store, config.Cache, pathPrefix)
+		if served {
+			span.SetAttributes(attribute.Bool("gateway.cache_hit", true))
+			return
+		}
+
+		retries := 0
+		if isRetryableRequest(r) {
+			retries = max(config.Retry.Attempts, 0)
+		}
+		for try := 0; ; try++ {
+			// Signed for every try, a retry late in a long route would otherwise carry an expired one
+			signed, err := signAssertion(r, signer, audience)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"err":            err,
+					"upstream":       audience,
+					"correlation_id": GetRequestMeta(r.Context()).CorrelationId,
+					"trace_id":       tracing.TraceId(r.Context()),
+					"type":           "proxy_error",
+				}).Error("Identity assertion could not be signed")
+				span.SetStatus(codes.Error, err.Error())
+				WriteErrorResponse(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), "Identity could not be forwarded")
+				return
+			}
+
+			target, err := pool.Pick()
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				writeUnavailable(w, pool, err)
+				return
+			}
+
+			accessLog.Target = target.URL.Host
+			attempt := &proxyAttempt{target: target, route: ctx, cache: cached, canRetry: try < retries, assertion: signed}
+			tryCtx, tryCancel := ctx, context.CancelFunc(func() {})
+			if config.Retry.PerTryTimeout > 0 {
+				tryCtx, tryCancel = context.WithTimeout(ctx, time.Duration(config.Retry.PerTryTimeout))
+			}
+			attempt.serve(proxy, w, r.WithContext(context.WithValue(tryCtx, proxyTargetKey{}, attempt)), audience)
+			tryCancel()
+			span.SetAttributes(attribute.Int("gateway.tries", try+1))
+			if attempt.failed.Load() {
+				span.SetStatus(codes.Error, "upstream failed")
+			}
+			if !attempt.retry.Load() {
+				return
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"upstream":       audience,
+				"target":         target.URL.Host,
+				"path":           r.URL.Path,
+				"try":            try + 1,
+				"correlation_id": GetRequestMeta(r.Context()).CorrelationId,
+				"trace_id":       tracing.TraceId(r.Context()),
+				"type":           "proxy_retry",
+			}).Warn("Retrying proxied request")
+
+			timer := time.NewTimer(retryBackoff(config.Retry, try))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					WriteErrorResponse(w, http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout), "Upstream service timed out")
+				}
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// The assertion of the caller's identity for the upstream, empty for a request without token claims.
+// Proxying a signed in caller without it would leave upstreams only the plain headers, so it is an error.
+func signAssertion(r *http.Request, signer *assertion.Signer, audience string) (string, error) {
+	tokenClaims, ok := r.Context().Value(ClaimsKey).(dto.TokenClaimsDTO)
+	if !ok {
+		return "", nil
+	}
+	return signer.Sign(assertion.Claims{
+		Audience:       audience,
+		Subject:        strconv.Itoa(tokenClaims.UserId),
+		Email:          tokenClaims.Email,
+		Roles:          tokenClaims.Roles,
+		Permissions:    tokenClaims.Permissions,
+		OrgId:          tokenClaims.OrgId,
+		OrgRole:        tokenClaims.OrgRole,
+		ImpersonatorId: tokenClaims.ImpersonatorId,
+		RequestId:      GetRequestMeta(r.Context()).RequestId,
+	})
+}
+
+// Only methods that are safe to repeat are retried, and only without a body, which could not be sent twice
+func isRetryableRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return r.Body == nil || r.Body == http.NoBody
+	}
+	return false
+}
+
+// Full jitter: a random wait up to Backoff * 2^try, capped at MaxBackoff
+func retryBackoff(config upstream.RetryConfig, try int) time.Duration {
+	ceiling := time.Duration(config.MaxBackoff)
+	if exp := float64(config.Backoff) * math.Pow(2, float64(try)); exp < float64(ceiling) {
+		ceiling = time.Duration(exp)
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
+
+func writeUnavailable(w http.ResponseWriter, pool *upstream.Pool, err error) {
+	if errors.Is(err, upstream.ErrCircuitOpen) {
+		if wait := time.Until(pool.RetryAt()); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		}
+		WriteErrorResponse(w, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable), "Upstream service is temporarily unavailable")
+		return
+	}
+	WriteErrorResponse(w, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable), "No healthy upstream available")
 }
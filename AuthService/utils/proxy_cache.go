@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"AuthService/cache"
+	"AuthService/upstream"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redis is a shortcut here, waiting long on it would be slower than asking the upstream
+const proxyCacheTimeout = 200 * time.Millisecond
+
+// Cache state of one proxied request. Key is set when a GET answer may be stored,
+// invalidate when a successful write should drop what it changed.
+type proxyCache struct {
+	store       *cache.Store
+	config      upstream.CacheConfig
+	prefix      string
+	key         string
+	ifNoneMatch string
+	invalidate  bool
+}
+
+// Answers a GET or HEAD from the cache when it can and reports whether it did. Otherwise returns how the
+// upstream's answer is to be handled, nil when caching is off for the route or Redis could not be reached.
+func lookupProxyCache(w http.ResponseWriter, r *http.Request, store *cache.Store, config upstream.CacheConfig, prefix string) (*proxyCache, bool) {
+	if store == nil || config.TTL == 0 {
+		return nil, false
+	}
+	state := &proxyCache{store: store, config: config, prefix: prefix}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		state.invalidate = true
+		return state, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), proxyCacheTimeout)
+	defer cancel()
+
+	key, err := store.Key(ctx, prefix, r.URL.Path, r.URL.RawQuery)
+	if err != nil {
+		logProxyCacheError(r, err, "Cache key could not be read")
+		w.Header().Set("X-Cache", "BYPASS")
+		return nil, false
+	}
+
+	// A client asking for a fresh answer gets one, and the fresh answer replaces the stored one
+	directives := cache.ParseCacheControl(r.Header)
+	if directives.Has("no-store") {
+		w.Header().Set("X-Cache", "BYPASS")
+		return nil, false
+	}
+	if !directives.Has("no-cache") {
+		entry, ok, err := store.Get(ctx, key)
+		if err != nil {
+			logProxyCacheError(r, err, "Cache entry could not be read")
+		}
+		if ok && entry.Matches(r) {
+			writeCachedResponse(w, r, entry)
+			return nil, true
+		}
+	}
+
+	w.Header().Set("X-Cache", "MISS")
+	if r.Method == http.MethodGet {
+		state.key = key
+		state.ifNoneMatch = r.Header.Get("If-None-Match")
+	}
+	return state, false
+}
+
+// Stores a cacheable answer, or drops what a successful write changed
+func (c *proxyCache) handleResponse(res *http.Response) error {
+	ctx, cancel := context.WithTimeout(res.Request.Context(), proxyCacheTimeout)
+	defer cancel()
+
+	if c.invalidate {
+		if res.StatusCode < 400 {
+			if err := c.store.Invalidate(ctx, c.prefix, res.Request.URL.Path, res.Request.Method); err != nil {
+				logProxyCacheError(res.Request, err, "Cache could not be invalidated")
+			}
+		}
+		return nil
+	}
+	if c.key == "" || res.StatusCode != http.StatusOK {
+		return nil
+	}
+	ttl, ok := cache.Lifetime(res.Header, time.Duration(c.config.TTL))
+	if !ok {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, int64(c.config.MaxBodyBytes)+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > c.config.MaxBodyBytes {
+		// Too large to keep, the client gets what was read followed by the rest
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), res.Body), res.Body}
+		return nil
+	}
+	res.Body.Close()
+
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		etag = cache.GenerateETag(body)
+		res.Header.Set("ETag", etag)
+	}
+	header := res.Header.Clone()
+	header.Del("X-Request-ID")
+	header.Del("X-Correlation-ID")
+	header.Del("Date")
+	now := time.Now()
+	entry := &cache.Entry{
+		Status:    res.StatusCode,
+		Header:    header,
+		Body:      body,
+		ETag:      etag,
+		Vary:      cache.VaryValues(res.Request, res.Header),
+		StoredAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := c.store.Set(ctx, c.key, entry); err != nil {
+		logProxyCacheError(res.Request, err, "Cache entry could not be stored")
+	}
+
+	if cache.NoneMatch(c.ifNoneMatch, etag) {
+		res.StatusCode = http.StatusNotModified
+		res.Status = http.StatusText(http.StatusNotModified)
+		res.Body = http.NoBody
+		res.ContentLength = 0
+		res.Header.Del("Content-Length")
+		return nil
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	res.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, entry *cache.Entry) {
+	for name, values := range entry.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(entry.StoredAt).Seconds())))
+	w.Header().Set("X-Cache", "HIT")
+
+	if cache.NoneMatch(r.Header.Get("If-None-Match"), entry.ETag) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(entry.Status)
+	if r.Method != http.MethodHead {
+		w.Write(entry.Body)
+	}
+}
+
+func logProxyCacheError(r *http.Request, err error, message string) {
+	logrus.WithFields(logrus.Fields{
+		"err":        err,
+		"path":       r.URL.Path,
+		"request_id": GetRequestMeta(r.Context()).RequestId,
+		"type":       "cache_error",
+	}).Error(message)
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"AuthService/assertion"
+	"AuthService/dto"
+	"AuthService/upstream"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// A pool of two replicas of server, whose breaker and outlier detection trip on the first failure
+func newTestPool(t *testing.T, server *httptest.Server) *upstream.Pool {
+	t.Helper()
+	pool, err := upstream.NewPool("submission", []string{server.URL, server.URL + "/"}, upstream.Config{
+		Outlier:        upstream.OutlierConfig{ConsecutiveFailures: 1},
+		CircuitBreaker: upstream.CircuitBreakerConfig{FailureThreshold: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	return pool
+}
+
+func newTestProxy(t *testing.T, pool *upstream.Pool, attempts int) http.HandlerFunc {
+	t.Helper()
+	config, err := upstream.RouteConfig{
+		Timeout: upstream.Duration(5 * time.Second),
+		Retry:   upstream.RetryConfig{Attempts: attempts},
+	}.WithDefaults()
+	if err != nil {
+		t.Fatalf("WithDefaults() error = %v", err)
+	}
+	return ProxyToService(pool, config, "/submission", assertion.NewSigner([]byte("gateway-test-secret"), 30*time.Second), nil)
+}
+
+func TestProxyClientCancelIsNotAFailure(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	pool := newTestPool(t, server)
+	proxy := newTestProxy(t, pool, -1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/submission/1", nil).WithContext(ctx))
+	}()
+	<-started
+	cancel()
+	<-done
+
+	status := pool.Status()
+	if status.Circuit != upstream.CircuitClosed {
+		t.Errorf("circuit = %s, want %s", status.Circuit, upstream.CircuitClosed)
+	}
+	for _, target := range status.Targets {
+		if target.Failures != 0 || target.ConsecutiveFailures != 0 || target.Ejected {
+			t.Errorf("target %s = %+v, want no failure recorded", target.Url, target)
+		}
+		if target.ActiveRequests != 0 {
+			t.Errorf("target %s has %d active requests, want 0", target.Url, target.ActiveRequests)
+		}
+	}
+}
+
+func TestProxyRewritesUpstreamGatewayErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		message string
+	}{
+		{status: http.StatusBadGateway, message: "Upstream service unavailable"},
+		{status: http.StatusServiceUnavailable, message: "Upstream service is temporarily unavailable"},
+		{status: http.StatusGatewayTimeout, message: "Upstream service timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(tt.status)
+				w.Write([]byte("<html>nginx</html>"))
+			}))
+			defer server.Close()
+
+			// Not retryable: a POST, and a GET on a route without retries
+			for _, method := range []string{http.MethodPost, http.MethodGet} {
+				attempts := 2
+				if method == http.MethodGet {
+					attempts = -1
+				}
+				pool := newTestPool(t, server)
+				recorder := httptest.NewRecorder()
+				newTestProxy(t, pool, attempts).ServeHTTP(recorder, httptest.NewRequest(method, "/submission", nil))
+
+				if recorder.Code != tt.status {
+					t.Errorf("%s status = %d, want %d", method, recorder.Code, tt.status)
+				}
+				if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("%s Content-Type = %q, want application/json", method, got)
+				}
+				if got := recorder.Header().Get("Retry-After"); got != "7" {
+					t.Errorf("%s Retry-After = %q, want the upstream's", method, got)
+				}
+				var body map[string]any
+				if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+					t.Fatalf("%s body %q is not JSON: %v", method, recorder.Body.String(), err)
+				}
+				if body["status"] != "failed" || body["message"] != http.StatusText(tt.status) || body["error"] != tt.message {
+					t.Errorf("%s body = %v", method, body)
+				}
+
+				// Still a failure of the replica
+				if circuit := pool.Status().Circuit; circuit != upstream.CircuitOpen {
+					t.Errorf("%s circuit = %s, want the failure recorded", method, circuit)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyPassesOtherErrorsThrough(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"success":false,"message":"boom"}`))
+	}))
+	defer server.Close()
+
+	recorder := httptest.NewRecorder()
+	newTestProxy(t, newTestPool(t, server), -1).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/submission", nil))
+
+	if recorder.Code != http.StatusInternalServerError || recorder.Body.String() != `{"success":false,"message":"boom"}` {
+		t.Errorf("response = %d %q, want the upstream's", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestProxyForwardsSignedIdentity(t *testing.T) {
+	verifier := assertion.NewVerifier("submission", []byte("gateway-test-secret"))
+	tests := []struct {
+		name        string
+		claims      *dto.TokenClaimsDTO
+		wantSubject string
+	}{
+		{name: "signed in caller", claims: &dto.TokenClaimsDTO{UserId: 42, Roles: []string{"user"}}, wantSubject: "42"},
+		{name: "guest without claims"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Get(assertion.HeaderName)
+			}))
+			defer server.Close()
+			proxy := newTestProxy(t, newTestPool(t, server), -1)
+
+			req := httptest.NewRequest(http.MethodGet, "/submission/1", nil)
+			// A client's own assertion never reaches the upstream
+			req.Header.Set(assertion.HeaderName, "forged")
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), ClaimsKey, *tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if tt.claims == nil {
+				if received != "" {
+					t.Errorf("assertion = %q, want none", received)
+				}
+				return
+			}
+			claims, err := verifier.Verify(received)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.Subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", claims.Subject, tt.wantSubject)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Keeps a token bucket per key, such as an email address or a client IP.
+// Buckets idle for longer than it takes them to refill are dropped.
+type KeyedLimiter struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	idle     time.Duration
+	limiters map[string]*keyedLimiterEntry
+	lastGC   time.Time
+}
+
+type keyedLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Allows burst events per key, refilled at one event every interval
+func NewKeyedLimiter(interval time.Duration, burst int) *KeyedLimiter {
+	return &KeyedLimiter{
+		limit:    rate.Every(interval),
+		burst:    burst,
+		idle:     interval * time.Duration(burst),
+		limiters: make(map[string]*keyedLimiterEntry),
+		lastGC:   time.Now(),
+	}
+}
+
+func (l *KeyedLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastGC) > l.idle {
+		for k, entry := range l.limiters {
+			if now.Sub(entry.lastSeen) > l.idle {
+				delete(l.limiters, k)
+			}
+		}
+		l.lastGC = now
+	}
+
+	entry, ok := l.limiters[key]
+	if !ok {
+		entry = &keyedLimiterEntry{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[key] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter.Allow()
+}
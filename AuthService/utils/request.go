@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// The gateway is the edge of the system, so the peer address is the client.
+// Forwarded headers are ignored since any client can set them.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Groups addresses by network, /24 for IPv4 and /48 for IPv6, so a changing address on the same network is not a new location
+func IPRange(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+var ErrInvalidPagination = errors.New("page and limit must be positive numbers")
+
+// Reads the page and limit query parameters, the limit is capped at 100
+func ParsePagination(r *http.Request) (int, int, error) {
+	page := 1
+	if value := r.URL.Query().Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, ErrInvalidPagination
+		}
+		page = parsed
+	}
+	limit, err := ParseLimit(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return page, limit, nil
+}
+
+// Reads the limit query parameter on its own, for cursor pagination. It defaults to 20 and is capped at 100.
+func ParseLimit(r *http.Request) (int, error) {
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, ErrInvalidPagination
+		}
+		limit = min(parsed, 100)
+	}
+	return limit, nil
+}
+
+// Request details the service layer needs without depending on net/http.
+// The correlation id follows the work a request started, into upstreams and websocket messages.
+type RequestMeta struct {
+	RequestId     string
+	CorrelationId string
+	IpAddress     string
+}
+
+func GetRequestMeta(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(RequestMetaKey).(RequestMeta)
+	return meta
+}
+
+// Details of a request only known deeper in the handler chain, filled in for its access log line
+type AccessLogFields struct {
+	UserId         int
+	ImpersonatorId int
+	Upstream       string
+	Target         string
+}
+
+func GetAccessLogFields(ctx context.Context) *AccessLogFields {
+	fields, ok := ctx.Value(AccessLogKey).(*AccessLogFields)
+	if !ok {
+		// Requests outside the access log middleware write to a throwaway
+		return &AccessLogFields{}
+	}
+	return fields
+}
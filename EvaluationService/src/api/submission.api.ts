@@ -3,7 +3,7 @@ import { serverConfig } from "../config";
 import { InternalServerError } from "../utils/errors/app.error";
 import logger from "../config/logger.config";
 
-export async function updateSubmission(submissionId: string, status: string, userId: string, problemId: string) {
+export async function updateSubmission(submissionId: string, status: string, userId: string, problemId: string, correlationId?: string) {
     try {
         // TODO: Improve the axios api error handling
         console.log("sent", {
@@ -17,6 +17,9 @@ export async function updateSubmission(submissionId: string, status: string, use
             userId,
             problemId,
             submissionId
+        }, {
+            // Keeps the update under the correlation id of the request that created the submission
+            headers: correlationId ? { "X-Correlation-Id": correlationId } : {}
         });
         if (response.status !== 200) {
             throw new InternalServerError("Failed to update submission");
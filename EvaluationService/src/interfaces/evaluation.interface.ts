@@ -21,6 +21,7 @@ export interface EvaluationJob {
     language: "python" | "cpp";
     problem: Problem;
     userId: string;
+    correlationId?: string;
 }
 
 export interface EvaluationResult {
@@ -61,10 +61,10 @@ async function setupEvaluationWorker() {
             const testcasesRunnerPromiseResults = await Promise.all(testcasesRunnerPromise);
             console.log("testcasesRunnerPromiseResult", testcasesRunnerPromiseResults);
             const output = matchTestCasesWithResults(data.problem.testcases, testcasesRunnerPromiseResults);
-            await updateSubmission(data.submissionId, output, data.userId, data.problem.id);
+            await updateSubmission(data.submissionId, output, data.userId, data.problem.id, data.correlationId);
         } catch (error) {
             logger.error(`Evaluation job failed: ${job.id}`, error);
-            await updateSubmission(data.submissionId, "failed", data.userId, data.problem.id)
+            await updateSubmission(data.submissionId, "failed", data.userId, data.problem.id, data.correlationId)
             return;
         }
     }, {
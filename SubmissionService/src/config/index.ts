@@ -5,6 +5,8 @@ type ServerConfig = {
     PORT: number;
     DB_URL: string;
     PROBLEM_SERVICE: string;
+    GATEWAY_ASSERTION_SECRETS: string[];
+    IDENTITY_AUDIENCE: string;
 }
 
 function loadEnv() {
@@ -17,5 +19,9 @@ loadEnv();
 export const serverConfig: ServerConfig = {
     PORT: Number(process.env.PORT) || 3001,
     DB_URL: process.env.DB_URL || "mongodb://localhost:27017/leetcode_submission_db",
-    PROBLEM_SERVICE: process.env.PROBLEM_SERVICE || "http://localhost:3000/api/v1"
+    PROBLEM_SERVICE: process.env.PROBLEM_SERVICE || "http://localhost:3000/api/v1",
+    // Comma separated, the first is the gateway's current secret and the rest are still accepted while rotating
+    GATEWAY_ASSERTION_SECRETS: (process.env.GATEWAY_ASSERTION_SECRET || "").split(",").map((secret) => secret.trim()).filter(Boolean),
+    // The upstream name the gateway signs assertions for
+    IDENTITY_AUDIENCE: process.env.IDENTITY_AUDIENCE || "submission"
 };
\ No newline at end of file
@@ -2,11 +2,16 @@ import { NextFunction, Request, Response } from 'express';
 import { v4 as uuidV4 } from 'uuid';
 import { asyncLocalStorage } from '../utils/helpers/request.helpers';
 
+// Same rule as the gateway applies, anything else is replaced rather than written to the logs
+const CORRELATION_ID_PATTERN = /^[A-Za-z0-9\-_.:]{1,64}$/;
+
 export const attachCorrelationIdMiddleware = (req: Request, res: Response, next: NextFunction) => {
-    // Generate a unique correlation ID
-    const correlationId = uuidV4();
+    // Keep the correlation ID the gateway forwards, generate one for requests that come without
+    const incoming = req.headers['x-correlation-id'];
+    const correlationId = typeof incoming === 'string' && CORRELATION_ID_PATTERN.test(incoming) ? incoming : uuidV4();
     
     req.headers['x-correlation-id'] = correlationId;
+    res.setHeader('X-Correlation-ID', correlationId);
 
     // Call the next middleware or route handler
 
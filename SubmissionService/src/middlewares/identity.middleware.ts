@@ -0,0 +1,19 @@
+import { NextFunction, Request, Response } from 'express';
+import { serverConfig } from '../config';
+import { UnauthorizedError } from '../utils/errors/app.error';
+import { ASSERTION_HEADER, verifyAssertion } from '../utils/helpers/assertion.helpers';
+
+// Trusts only the identity the gateway signed, the plain X-User-* headers can be sent by anyone who reaches the service
+export const requireIdentity = (req: Request, res: Response, next: NextFunction) => {
+    const incoming = req.headers[ASSERTION_HEADER];
+    const token = typeof incoming === 'string' ? incoming : undefined;
+
+    const result = verifyAssertion(token, serverConfig.IDENTITY_AUDIENCE, serverConfig.GATEWAY_ASSERTION_SECRETS);
+    if (!result.valid) {
+        throw new UnauthorizedError(`Identity assertion ${result.reason}`);
+    }
+
+    req.headers['x-user-id'] = result.claims.sub;
+    res.locals.identity = result.claims;
+    next();
+}
@@ -28,6 +28,7 @@ export interface ISubmission extends Document {
     language: SubmissionLanguage;
     status: SubmissionStatus;
     submissionData: ISubmissionData;
+    correlationId?: string;
     createdAt: Date;
     updatedAt: Date;
 }
@@ -60,6 +61,10 @@ const submissionSchema = new Schema<ISubmission>({
         type: Object,
         required: true,
         default: {}
+    },
+    // Correlation id of the request that created the submission, carried through its evaluation
+    correlationId: {
+        type: String
     }
 }, {
     timestamps: true,
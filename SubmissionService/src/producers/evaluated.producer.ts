@@ -6,6 +6,8 @@ export interface IEvaluatedJob {
     userId: string;
     problemId: string;
     status: string;
+    // The one stored with the submission, the request that created it rather than the update
+    correlationId?: string;
 }
 
 export async function publishEvaluatedJob(data: IEvaluatedJob): Promise<string | null> {
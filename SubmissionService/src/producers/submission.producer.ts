@@ -9,6 +9,7 @@ export interface ISubmissionJob {
     code: string;
     language: SubmissionLanguage,
     userId: string;
+    correlationId: string;
 }
 
 export async function addSubmissionJob(data: ISubmissionJob): Promise<string | null> {
@@ -1,11 +1,13 @@
 import express from 'express';
+import { requireIdentity } from '../../middlewares/identity.middleware';
 import { submissionControllers } from '../../controllers/submission.controller';
 import { validateRequestParams, validateRequestBody } from '../../validators';
 import { createSubmissionSchema, findByIdSchema, findByUserAndProblemIdSchema, findByUserIdSchema, updateSubmissionSchema } from '../../validators/submission.validator';
 
 const submissionRouter = express.Router();
 
-submissionRouter.post('/', validateRequestBody(createSubmissionSchema), submissionControllers.create);
+// Created on behalf of the caller the gateway asserts, the other routes are also called service to service
+submissionRouter.post('/', requireIdentity, validateRequestBody(createSubmissionSchema), submissionControllers.create);
 submissionRouter.get('/:id', validateRequestParams(findByIdSchema), submissionControllers.findById);
 submissionRouter.get('/user/:userId', validateRequestParams(findByUserIdSchema), submissionControllers.findByUserId);
 submissionRouter.get('/user/:userId/:problemId', validateRequestParams(findByUserAndProblemIdSchema), submissionControllers.findByUserAndProblemId);
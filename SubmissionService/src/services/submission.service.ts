@@ -5,6 +5,7 @@ import { publishEvaluatedJob } from "../producers/evaluated.producer";
 import { addSubmissionJob } from "../producers/submission.producer";
 import { ISubmissionRepository } from "../repositories/submission.repository";
 import { BadRequestError, NotFoundError } from "../utils/errors/app.error";
+import { getCorrelationId } from "../utils/helpers/request.helpers";
 
 export interface ISubmissionService {
     create(submissionData: Partial<ISubmission>, userId: string): Promise<ISubmission>;
@@ -33,8 +34,9 @@ export class SubmissionService implements ISubmissionService {
             throw new NotFoundError("Problem not found or something went wrong");
         }
 
-        // Add the submission payload to db
-        const submission: any = await this.submissionRepository.create({...submissionData, userId});
+        // Add the submission payload to db, with the correlation id its evaluation is logged under
+        const correlationId = getCorrelationId();
+        const submission: any = await this.submissionRepository.create({...submissionData, userId, correlationId});
 
         // Add submission to redis queue
         // TODO - Store job id in submission collection to track the job
@@ -43,7 +45,8 @@ export class SubmissionService implements ISubmissionService {
             problem,
             userId,
             code: buildFinalCode(problem.driver_code, submissionData.code as string),
-            language: submissionData.language as SubmissionLanguage
+            language: submissionData.language as SubmissionLanguage,
+            correlationId
         });
         logger.info(`${jobId} added to evaluate-submission event`);
 
@@ -90,7 +93,8 @@ export class SubmissionService implements ISubmissionService {
             status: status,
             submissionId: submission.id as string,
             problemId: problemId,
-            userId: userId
+            userId: userId,
+            correlationId: submission.correlationId
         });
         return submission;
     }
@@ -0,0 +1,102 @@
+import { createHmac, timingSafeEqual } from "crypto";
+
+// Mirrors AuthService/assertion, AuthService/assertion/testdata/vectors.json holds the expected outcomes
+export const ASSERTION_HEADER = 'x-identity-assertion';
+const ISSUER = 'problem-battles-gateway';
+// Allowed clock difference between the gateway and this service
+const LEEWAY_SECONDS = 5;
+
+export type AssertionClaims = {
+    iss: string;
+    aud: string;
+    sub: string;
+    email?: string;
+    roles: string[];
+    perms?: string[];
+    org_id?: number;
+    org_role?: string;
+    imp?: number;
+    rid?: string;
+    iat: number;
+    exp: number;
+}
+
+export type AssertionFailure = 'missing' | 'malformed' | 'algorithm' | 'signature' | 'issuer' | 'audience' | 'expired' | 'not_yet_valid';
+
+export type AssertionResult =
+    | { valid: true; claims: AssertionClaims }
+    | { valid: false; reason: AssertionFailure };
+
+const fail = (reason: AssertionFailure): AssertionResult => ({ valid: false, reason });
+
+const decode = (part: string): Buffer | null => {
+    if (!/^[A-Za-z0-9_-]*$/.test(part)) {
+        return null;
+    }
+    return Buffer.from(part, 'base64url');
+}
+
+const parseJson = (part: string): any => {
+    const data = decode(part);
+    if (!data) {
+        return null;
+    }
+    try {
+        return JSON.parse(data.toString('utf8'));
+    } catch {
+        return null;
+    }
+}
+
+/**
+ * Verifies an assertion for audience signed with any of the secrets, listing more than one allows rotating the secret.
+ */
+export const verifyAssertion = (token: string | undefined, audience: string, secrets: string[], now: number = Math.floor(Date.now() / 1000)): AssertionResult => {
+    if (!token) {
+        return fail('missing');
+    }
+    const parts = token.split('.');
+    if (parts.length !== 3) {
+        return fail('malformed');
+    }
+
+    const header = parseJson(parts[0]);
+    if (!header || typeof header !== 'object') {
+        return fail('malformed');
+    }
+    // Only the algorithm the gateway signs with, never "none" or whatever the token asks for
+    if (header.alg !== 'HS256') {
+        return fail('algorithm');
+    }
+
+    const signature = decode(parts[2]);
+    if (!signature) {
+        return fail('malformed');
+    }
+    const signingInput = `${parts[0]}.${parts[1]}`;
+    const signed = secrets.some((secret) => {
+        const expected = createHmac('sha256', secret).update(signingInput).digest();
+        return expected.length === signature.length && timingSafeEqual(expected, signature);
+    });
+    if (!signed) {
+        return fail('signature');
+    }
+
+    const claims = parseJson(parts[1]);
+    if (!claims || typeof claims !== 'object') {
+        return fail('malformed');
+    }
+    if (claims.iss !== ISSUER) {
+        return fail('issuer');
+    }
+    if (claims.aud !== audience) {
+        return fail('audience');
+    }
+    if (now - LEEWAY_SECONDS >= Number(claims.exp)) {
+        return fail('expired');
+    }
+    if (now + LEEWAY_SECONDS < Number(claims.iat)) {
+        return fail('not_yet_valid');
+    }
+    return { valid: true, claims: claims as AssertionClaims };
+}